@@ -1,8 +1,10 @@
 package main
 
 import (
+	"context"
 	"os"
 	"os/signal"
+	"sync"
 
 	"log"
 
@@ -13,6 +15,14 @@ import (
 )
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := migrate(os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+
+		return
+	}
+
 	port := os.Getenv("PORT")
 	serv, err := server.New(port)
 	if err != nil {
@@ -25,15 +35,32 @@ func main() {
 		log.Fatal(err)
 	}
 
+	// the background workers stop on an interrupt.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		d.RenderPending(ctx)
+	}()
+	go func() {
+		defer wg.Done()
+		serv.Run(ctx)
+	}()
+
 	// start the server.
 	go serv.Start()
 
 	// Wait for an in interrupt.
-	c := make(chan os.Signal, 1)
-	signal.Notify(c, os.Interrupt)
-	<-c
+	<-ctx.Done()
 
 	// Attempt a graceful shutdown.
-	serv.Close()
+	if err := serv.Close(); err != nil {
+		log.Printf("shutdown: %v", err)
+	}
+
+	wg.Wait()
 	data.Close()
 }
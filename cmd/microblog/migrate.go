@@ -0,0 +1,139 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+
+	"github.com/orlmonteverde/go-postgres-microblog/database"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/data"
+)
+
+const migrateUsage = `usage: microblog migrate <command>
+
+commands:
+  up                apply every pending migration
+  down [n]          revert the last n migrations (default 1)
+  status            list the migrations and whether they are applied
+  create [-dir d] name
+                    create the up and down files of a new migration`
+
+var migrationName = regexp.MustCompile(`^\w+$`)
+
+// migrate runs the migrate subcommand.
+func migrate(args []string) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+
+	if args[0] == "create" {
+		return createMigration(args[1:])
+	}
+
+	db, err := data.Connect()
+	if err != nil {
+		return err
+	}
+
+	defer db.Close()
+
+	m := &data.Migrator{
+		DB:         db,
+		Migrations: database.Migrations(),
+	}
+
+	ctx := context.Background()
+	switch args[0] {
+	case "up":
+		applied, err := m.Up(ctx)
+		for _, mig := range applied {
+			fmt.Printf("applied  %04d_%s\n", mig.Version, mig.Name)
+		}
+
+		return err
+	case "down":
+		n := 1
+		if len(args) > 1 {
+			n, err = strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				return fmt.Errorf("invalid number of migrations: %s", args[1])
+			}
+		}
+
+		reverted, err := m.Down(ctx, n)
+		for _, mig := range reverted {
+			fmt.Printf("reverted %04d_%s\n", mig.Version, mig.Name)
+		}
+
+		return err
+	case "status":
+		migrations, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range migrations {
+			status := "pending"
+			if mig.AppliedAt != nil {
+				status = "applied at " + mig.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+
+			fmt.Printf("%04d_%-30s %s\n", mig.Version, mig.Name, status)
+		}
+
+		return nil
+	}
+
+	return errors.New(migrateUsage)
+}
+
+// createMigration writes empty up and down files for the next version.
+// The files are embedded in the binary, so it must be rebuilt to apply them.
+func createMigration(args []string) error {
+	fs := flag.NewFlagSet("migrate create", flag.ContinueOnError)
+	dir := fs.String("dir", "database/migrations", "directory of the migration files")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 1 || !migrationName.MatchString(fs.Arg(0)) {
+		return errors.New(migrateUsage)
+	}
+
+	files, err := filepath.Glob(filepath.Join(*dir, "*.up.sql"))
+	if err != nil {
+		return err
+	}
+
+	var last uint64
+	for _, f := range files {
+		var version uint64
+		_, err := fmt.Sscanf(filepath.Base(f), "%d_", &version)
+		if err == nil && version > last {
+			last = version
+		}
+	}
+
+	base := fmt.Sprintf("%04d_%s", last+1, fs.Arg(0))
+	for _, direction := range []string{"up", "down"} {
+		path := filepath.Join(*dir, base+"."+direction+".sql")
+		if _, err := os.Stat(path); err == nil {
+			return fmt.Errorf("%s already exists", path)
+		}
+
+		err := ioutil.WriteFile(path, nil, 0644)
+		if err != nil {
+			return err
+		}
+
+		fmt.Println("created", path)
+	}
+
+	return nil
+}
@@ -0,0 +1,23 @@
+// Package database contains the versioned migrations of the schema.
+package database
+
+import (
+	"embed"
+	"io/fs"
+)
+
+//go:embed migrations/*.sql
+var files embed.FS
+
+// Migrations returns the migration files embedded in the binary.
+//
+// Each version has an up and a down file named like
+// 0001_create_users.up.sql and 0001_create_users.down.sql.
+func Migrations() fs.FS {
+	sub, err := fs.Sub(files, "migrations")
+	if err != nil {
+		panic(err)
+	}
+
+	return sub
+}
@@ -0,0 +1,4 @@
+DROP TABLE IF EXISTS replies;
+DROP TABLE IF EXISTS posts;
+DROP TABLE IF EXISTS subjects;
+DROP TABLE IF EXISTS users;
@@ -0,0 +1,11 @@
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id serial NOT NULL,
+    user_id int NOT NULL,
+    family_id VARCHAR(64) NOT NULL,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    expires_at timestamp NOT NULL,
+    revoked_at timestamp,
+    created_at timestamp DEFAULT now(),
+    CONSTRAINT pk_refresh_tokens PRIMARY KEY(id),
+    CONSTRAINT fk_refresh_tokens_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
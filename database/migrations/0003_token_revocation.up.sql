@@ -0,0 +1,9 @@
+ALTER TABLE users ADD COLUMN IF NOT EXISTS token_version INT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti VARCHAR(64) NOT NULL,
+    user_id int NOT NULL,
+    expires_at timestamp NOT NULL,
+    CONSTRAINT pk_revoked_tokens PRIMARY KEY(jti),
+    CONSTRAINT fk_revoked_tokens_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_replies_search;
+
+ALTER TABLE replies DROP COLUMN IF EXISTS search;
+
+DROP INDEX IF EXISTS idx_posts_search;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS search;
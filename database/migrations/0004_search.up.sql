@@ -0,0 +1,13 @@
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS search tsvector
+    GENERATED ALWAYS AS (
+        setweight(to_tsvector('spanish', coalesce(title, '')), 'A') ||
+        setweight(to_tsvector('spanish', coalesce(category, '')), 'B') ||
+        setweight(to_tsvector('spanish', coalesce(body, '')), 'C')
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_posts_search ON posts USING GIN (search);
+
+ALTER TABLE replies ADD COLUMN IF NOT EXISTS search tsvector
+    GENERATED ALWAYS AS (to_tsvector('spanish', coalesce(body, ''))) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_replies_search ON replies USING GIN (search);
@@ -0,0 +1,3 @@
+DROP INDEX IF EXISTS idx_replies_post;
+
+DROP TABLE IF EXISTS enrollments;
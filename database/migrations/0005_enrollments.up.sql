@@ -0,0 +1,12 @@
+CREATE TABLE IF NOT EXISTS enrollments (
+    user_id int NOT NULL,
+    subject_id int NOT NULL,
+    created_at timestamp DEFAULT now(),
+    CONSTRAINT pk_enrollments PRIMARY KEY(user_id, subject_id),
+    CONSTRAINT fk_enrollments_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_enrollments_subjects FOREIGN KEY(subject_id) REFERENCES subjects(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_enrollments_subject ON enrollments (subject_id);
+
+CREATE INDEX IF NOT EXISTS idx_replies_post ON replies (post_id, created_at);
@@ -0,0 +1,32 @@
+CREATE TABLE IF NOT EXISTS notifications (
+    id serial NOT NULL,
+    user_id int NOT NULL,
+    type VARCHAR(20) NOT NULL,
+    actor_id int NOT NULL,
+    subject_id int NOT NULL,
+    post_id int NOT NULL,
+    reply_id int,
+    read_at timestamp,
+    created_at timestamp DEFAULT now(),
+    CONSTRAINT pk_notifications PRIMARY KEY(id),
+    CONSTRAINT fk_notifications_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_notifications_actors FOREIGN KEY(actor_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_notifications_subjects FOREIGN KEY(subject_id) REFERENCES subjects(id) ON DELETE CASCADE,
+    CONSTRAINT fk_notifications_posts FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_notifications_replies FOREIGN KEY(reply_id) REFERENCES replies(id) ON DELETE CASCADE,
+    CONSTRAINT ck_notifications_type CHECK (type IN ('reply', 'mention', 'post'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_user ON notifications (user_id, created_at DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_notifications_unread ON notifications (user_id) WHERE read_at IS NULL;
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id int NOT NULL,
+    reply boolean NOT NULL DEFAULT true,
+    mention boolean NOT NULL DEFAULT true,
+    post boolean NOT NULL DEFAULT true,
+    updated_at timestamp DEFAULT now(),
+    CONSTRAINT pk_notification_preferences PRIMARY KEY(user_id),
+    CONSTRAINT fk_notification_preferences_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
@@ -0,0 +1,11 @@
+DROP TRIGGER IF EXISTS tg_notifications_events ON notifications;
+
+DROP FUNCTION IF EXISTS notify_notification_event();
+
+DROP TRIGGER IF EXISTS tg_replies_events ON replies;
+
+DROP FUNCTION IF EXISTS notify_reply_event();
+
+DROP TRIGGER IF EXISTS tg_posts_events ON posts;
+
+DROP FUNCTION IF EXISTS notify_post_event();
@@ -0,0 +1,76 @@
+-- The events are sent with NOTIFY on the events channel, so every
+-- instance of the API receives them and forwards them to its clients.
+-- The payload only has the ids, the clients fetch the resources.
+
+CREATE OR REPLACE FUNCTION notify_post_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('events', json_build_object(
+        'type', 'post.created',
+        'channels', json_build_array('subject:' || NEW.subject_id),
+        'data', json_build_object(
+            'id', NEW.id,
+            'subject_id', NEW.subject_id,
+            'user_id', NEW.user_id,
+            'title', NEW.title
+        )
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tg_posts_events AFTER INSERT ON posts
+    FOR EACH ROW EXECUTE FUNCTION notify_post_event();
+
+CREATE OR REPLACE FUNCTION notify_reply_event() RETURNS trigger AS $$
+DECLARE
+    r replies;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        r := OLD;
+    ELSE
+        r := NEW;
+    END IF;
+
+    PERFORM pg_notify('events', json_build_object(
+        'type', 'reply.' || CASE TG_OP
+            WHEN 'INSERT' THEN 'created'
+            WHEN 'UPDATE' THEN 'updated'
+            ELSE 'deleted'
+        END,
+        'channels', json_build_array('post:' || r.post_id),
+        'data', json_build_object(
+            'id', r.id,
+            'post_id', r.post_id,
+            'user_id', r.user_id
+        )
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tg_replies_events AFTER INSERT OR UPDATE OR DELETE ON replies
+    FOR EACH ROW EXECUTE FUNCTION notify_reply_event();
+
+CREATE OR REPLACE FUNCTION notify_notification_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('events', json_build_object(
+        'type', 'notification.created',
+        'channels', json_build_array('user:' || NEW.user_id),
+        'data', json_build_object(
+            'id', NEW.id,
+            'type', NEW.type,
+            'actor_id', NEW.actor_id,
+            'subject_id', NEW.subject_id,
+            'post_id', NEW.post_id,
+            'reply_id', NEW.reply_id
+        )
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tg_notifications_events AFTER INSERT ON notifications
+    FOR EACH ROW EXECUTE FUNCTION notify_notification_event();
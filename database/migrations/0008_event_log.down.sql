@@ -0,0 +1,48 @@
+-- restore the functions of 0007_events, without the event log.
+
+CREATE OR REPLACE FUNCTION notify_post_event() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('events', json_build_object(
+        'type', 'post.created',
+        'channels', json_build_array('subject:' || NEW.subject_id),
+        'data', json_build_object(
+            'id', NEW.id,
+            'subject_id', NEW.subject_id,
+            'user_id', NEW.user_id,
+            'title', NEW.title
+        )
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION notify_reply_event() RETURNS trigger AS $$
+DECLARE
+    r replies;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        r := OLD;
+    ELSE
+        r := NEW;
+    END IF;
+
+    PERFORM pg_notify('events', json_build_object(
+        'type', 'reply.' || CASE TG_OP
+            WHEN 'INSERT' THEN 'created'
+            WHEN 'UPDATE' THEN 'updated'
+            ELSE 'deleted'
+        END,
+        'channels', json_build_array('post:' || r.post_id),
+        'data', json_build_object(
+            'id', r.id,
+            'post_id', r.post_id,
+            'user_id', r.user_id
+        )
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE IF EXISTS events;
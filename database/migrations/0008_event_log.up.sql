@@ -0,0 +1,94 @@
+-- The events of the subjects are kept in a log, so the SSE clients can
+-- resume the stream from the id of the last event they received.
+
+CREATE TABLE IF NOT EXISTS events (
+    id bigserial NOT NULL,
+    subject_id int NOT NULL,
+    type VARCHAR(50) NOT NULL,
+    data jsonb NOT NULL,
+    created_at timestamp DEFAULT now(),
+    CONSTRAINT pk_events PRIMARY KEY(id),
+    CONSTRAINT fk_events_subjects FOREIGN KEY(subject_id) REFERENCES subjects(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_events_subject ON events (subject_id, id);
+
+CREATE INDEX IF NOT EXISTS idx_events_created ON events (created_at);
+
+CREATE OR REPLACE FUNCTION notify_post_event() RETURNS trigger AS $$
+DECLARE
+    payload jsonb;
+    event_id bigint;
+BEGIN
+    payload := jsonb_build_object(
+        'id', NEW.id,
+        'subject_id', NEW.subject_id,
+        'user_id', NEW.user_id,
+        'title', NEW.title
+    );
+
+    INSERT INTO events (subject_id, type, data)
+        VALUES (NEW.subject_id, 'post.created', payload)
+        RETURNING id INTO event_id;
+
+    PERFORM pg_notify('events', jsonb_build_object(
+        'id', event_id,
+        'type', 'post.created',
+        'channels', jsonb_build_array('subject:' || NEW.subject_id),
+        'data', payload
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE FUNCTION notify_reply_event() RETURNS trigger AS $$
+DECLARE
+    r replies;
+    kind text;
+    subject int;
+    payload jsonb;
+    channels jsonb;
+    event_id bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        r := OLD;
+    ELSE
+        r := NEW;
+    END IF;
+
+    kind := 'reply.' || CASE TG_OP
+        WHEN 'INSERT' THEN 'created'
+        WHEN 'UPDATE' THEN 'updated'
+        ELSE 'deleted'
+    END;
+
+    SELECT subject_id INTO subject FROM posts WHERE id = r.post_id;
+
+    payload := jsonb_build_object(
+        'id', r.id,
+        'post_id', r.post_id,
+        'subject_id', subject,
+        'user_id', r.user_id
+    );
+    channels := jsonb_build_array('post:' || r.post_id);
+
+    -- the post is already gone when its replies are deleted in cascade.
+    IF subject IS NOT NULL THEN
+        INSERT INTO events (subject_id, type, data)
+            VALUES (subject, kind, payload)
+            RETURNING id INTO event_id;
+
+        channels := channels || jsonb_build_array('subject:' || subject);
+    END IF;
+
+    PERFORM pg_notify('events', jsonb_build_object(
+        'id', event_id,
+        'type', kind,
+        'channels', channels,
+        'data', payload
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,30 @@
+DROP TRIGGER IF EXISTS tg_replies_events ON replies;
+
+CREATE TRIGGER tg_replies_events AFTER INSERT OR UPDATE OR DELETE ON replies
+    FOR EACH ROW EXECUTE FUNCTION notify_reply_event();
+
+DROP TABLE IF EXISTS reply_votes;
+
+DROP TABLE IF EXISTS post_votes;
+
+DROP INDEX IF EXISTS idx_replies_post_hot;
+
+DROP INDEX IF EXISTS idx_replies_post_score;
+
+DROP INDEX IF EXISTS idx_posts_subject_hot;
+
+DROP INDEX IF EXISTS idx_posts_subject_score;
+
+ALTER TABLE replies
+    DROP COLUMN IF EXISTS hot,
+    DROP COLUMN IF EXISTS score,
+    DROP COLUMN IF EXISTS downvotes,
+    DROP COLUMN IF EXISTS upvotes;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS hot,
+    DROP COLUMN IF EXISTS score,
+    DROP COLUMN IF EXISTS downvotes,
+    DROP COLUMN IF EXISTS upvotes;
+
+DROP FUNCTION IF EXISTS hot_rank(int, timestamp);
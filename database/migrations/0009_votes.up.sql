@@ -0,0 +1,62 @@
+-- hot_rank sorts by score, with the newer items first at the same
+-- score. Every 12.5 hours are worth 10 times the score.
+CREATE OR REPLACE FUNCTION hot_rank(score int, created_at timestamp) RETURNS double precision AS $$
+    SELECT sign(score::double precision) * log(greatest(abs(score), 1)::double precision)
+        + extract(epoch FROM created_at)::double precision / 45000;
+$$ LANGUAGE sql IMMUTABLE;
+
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS upvotes int NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS downvotes int NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS score int NOT NULL DEFAULT 0;
+
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS hot double precision GENERATED ALWAYS AS (hot_rank(score, created_at)) STORED;
+
+ALTER TABLE replies
+    ADD COLUMN IF NOT EXISTS upvotes int NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS downvotes int NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS score int NOT NULL DEFAULT 0;
+
+ALTER TABLE replies
+    ADD COLUMN IF NOT EXISTS hot double precision GENERATED ALWAYS AS (hot_rank(score, created_at)) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_posts_subject_score ON posts (subject_id, score DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_posts_subject_hot ON posts (subject_id, hot DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_replies_post_score ON replies (post_id, score DESC, id DESC);
+
+CREATE INDEX IF NOT EXISTS idx_replies_post_hot ON replies (post_id, hot DESC, id DESC);
+
+CREATE TABLE IF NOT EXISTS post_votes (
+    user_id int NOT NULL,
+    post_id int NOT NULL,
+    value smallint NOT NULL,
+    created_at timestamp DEFAULT now(),
+    CONSTRAINT pk_post_votes PRIMARY KEY(user_id, post_id),
+    CONSTRAINT fk_post_votes_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_post_votes_posts FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    CONSTRAINT ck_post_votes_value CHECK (value IN (-1, 1))
+);
+
+CREATE INDEX IF NOT EXISTS idx_post_votes_post ON post_votes (post_id);
+
+CREATE TABLE IF NOT EXISTS reply_votes (
+    user_id int NOT NULL,
+    reply_id int NOT NULL,
+    value smallint NOT NULL,
+    created_at timestamp DEFAULT now(),
+    CONSTRAINT pk_reply_votes PRIMARY KEY(user_id, reply_id),
+    CONSTRAINT fk_reply_votes_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_reply_votes_replies FOREIGN KEY(reply_id) REFERENCES replies(id) ON DELETE CASCADE,
+    CONSTRAINT ck_reply_votes_value CHECK (value IN (-1, 1))
+);
+
+CREATE INDEX IF NOT EXISTS idx_reply_votes_reply ON reply_votes (reply_id);
+
+-- the votes update the totals of the replies, which are not edits.
+DROP TRIGGER IF EXISTS tg_replies_events ON replies;
+
+CREATE TRIGGER tg_replies_events AFTER INSERT OR DELETE OR UPDATE OF body ON replies
+    FOR EACH ROW EXECUTE FUNCTION notify_reply_event();
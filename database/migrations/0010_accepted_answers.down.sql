@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_posts_subject_solved;
+
+ALTER TABLE posts
+    DROP COLUMN IF EXISTS solved,
+    DROP CONSTRAINT IF EXISTS fk_posts_accepted_replies,
+    DROP COLUMN IF EXISTS accepted_reply_id;
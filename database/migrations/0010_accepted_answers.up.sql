@@ -0,0 +1,8 @@
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS accepted_reply_id int,
+    ADD CONSTRAINT fk_posts_accepted_replies FOREIGN KEY(accepted_reply_id) REFERENCES replies(id) ON DELETE SET NULL;
+
+ALTER TABLE posts
+    ADD COLUMN IF NOT EXISTS solved boolean GENERATED ALWAYS AS (accepted_reply_id IS NOT NULL) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_posts_subject_solved ON posts (subject_id, solved);
@@ -0,0 +1,71 @@
+DROP TRIGGER IF EXISTS tg_replies_events ON replies;
+
+CREATE TRIGGER tg_replies_events AFTER INSERT OR DELETE OR UPDATE OF body ON replies
+    FOR EACH ROW EXECUTE FUNCTION notify_reply_event();
+
+-- restore the function of 0008_event_log.
+CREATE OR REPLACE FUNCTION notify_reply_event() RETURNS trigger AS $$
+DECLARE
+    r replies;
+    kind text;
+    subject int;
+    payload jsonb;
+    channels jsonb;
+    event_id bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        r := OLD;
+    ELSE
+        r := NEW;
+    END IF;
+
+    kind := 'reply.' || CASE TG_OP
+        WHEN 'INSERT' THEN 'created'
+        WHEN 'UPDATE' THEN 'updated'
+        ELSE 'deleted'
+    END;
+
+    SELECT subject_id INTO subject FROM posts WHERE id = r.post_id;
+
+    payload := jsonb_build_object(
+        'id', r.id,
+        'post_id', r.post_id,
+        'subject_id', subject,
+        'user_id', r.user_id
+    );
+    channels := jsonb_build_array('post:' || r.post_id);
+
+    -- the post is already gone when its replies are deleted in cascade.
+    IF subject IS NOT NULL THEN
+        INSERT INTO events (subject_id, type, data)
+            VALUES (subject, kind, payload)
+            RETURNING id INTO event_id;
+
+        channels := channels || jsonb_build_array('subject:' || subject);
+    END IF;
+
+    PERFORM pg_notify('events', jsonb_build_object(
+        'id', event_id,
+        'type', kind,
+        'channels', channels,
+        'data', payload
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_replies_path;
+
+DROP INDEX IF EXISTS idx_replies_parent;
+
+-- the tombstones have no body left to show, they are removed and their
+-- children, by fk_replies_parents, are kept as replies of the post.
+DELETE FROM replies WHERE deleted_at IS NOT NULL AND body = '';
+
+ALTER TABLE replies
+    DROP CONSTRAINT IF EXISTS fk_replies_parents,
+    DROP COLUMN IF EXISTS deleted_at,
+    DROP COLUMN IF EXISTS path,
+    DROP COLUMN IF EXISTS depth,
+    DROP COLUMN IF EXISTS parent_id;
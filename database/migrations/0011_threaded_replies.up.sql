@@ -0,0 +1,75 @@
+-- path has the ids from the top-level reply to the reply, so sorting by
+-- path returns the replies in thread order.
+ALTER TABLE replies
+    ADD COLUMN IF NOT EXISTS parent_id int,
+    ADD COLUMN IF NOT EXISTS depth int NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS path int[],
+    ADD COLUMN IF NOT EXISTS deleted_at timestamp,
+    ADD CONSTRAINT fk_replies_parents FOREIGN KEY(parent_id) REFERENCES replies(id) ON DELETE SET NULL;
+
+UPDATE replies SET path = ARRAY[id] WHERE path IS NULL;
+
+ALTER TABLE replies ALTER COLUMN path SET NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_replies_parent ON replies (parent_id);
+
+CREATE INDEX IF NOT EXISTS idx_replies_path ON replies USING gin (path);
+
+-- the deleted replies with children are kept as tombstones, the update
+-- that clears them is sent as reply.deleted.
+CREATE OR REPLACE FUNCTION notify_reply_event() RETURNS trigger AS $$
+DECLARE
+    r replies;
+    kind text;
+    subject int;
+    payload jsonb;
+    channels jsonb;
+    event_id bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        r := OLD;
+    ELSE
+        r := NEW;
+    END IF;
+
+    kind := 'reply.' || CASE
+        WHEN TG_OP = 'INSERT' THEN 'created'
+        WHEN TG_OP = 'UPDATE' AND r.deleted_at IS NULL THEN 'updated'
+        ELSE 'deleted'
+    END;
+
+    SELECT subject_id INTO subject FROM posts WHERE id = r.post_id;
+
+    payload := jsonb_build_object(
+        'id', r.id,
+        'post_id', r.post_id,
+        'parent_id', r.parent_id,
+        'subject_id', subject,
+        'user_id', r.user_id
+    );
+    channels := jsonb_build_array('post:' || r.post_id);
+
+    -- the post is already gone when its replies are deleted in cascade.
+    IF subject IS NOT NULL THEN
+        INSERT INTO events (subject_id, type, data)
+            VALUES (subject, kind, payload)
+            RETURNING id INTO event_id;
+
+        channels := channels || jsonb_build_array('subject:' || subject);
+    END IF;
+
+    PERFORM pg_notify('events', jsonb_build_object(
+        'id', event_id,
+        'type', kind,
+        'channels', channels,
+        'data', payload
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tg_replies_events ON replies;
+
+CREATE TRIGGER tg_replies_events AFTER INSERT OR DELETE OR UPDATE OF body, deleted_at ON replies
+    FOR EACH ROW EXECUTE FUNCTION notify_reply_event();
@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS tg_attachments_deletions ON attachments;
+
+DROP FUNCTION IF EXISTS queue_attachment_deletion();
+
+DROP TABLE IF EXISTS attachment_deletions;
+
+DROP TABLE IF EXISTS attachments;
@@ -0,0 +1,45 @@
+-- The files are kept in the storage, the table only has their metadata.
+-- An attachment belongs to a post or to a reply, never both.
+CREATE TABLE IF NOT EXISTS attachments (
+    id serial NOT NULL,
+    user_id int NOT NULL,
+    post_id int,
+    reply_id int,
+    filename VARCHAR(255) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    size bigint NOT NULL,
+    storage_key VARCHAR(100) NOT NULL UNIQUE,
+    created_at timestamp DEFAULT now(),
+    CONSTRAINT pk_attachments PRIMARY KEY(id),
+    CONSTRAINT fk_attachments_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_attachments_posts FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_attachments_replies FOREIGN KEY(reply_id) REFERENCES replies(id) ON DELETE CASCADE,
+    CONSTRAINT ck_attachments_target CHECK ((post_id IS NULL) <> (reply_id IS NULL))
+);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_user ON attachments (user_id);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_post ON attachments (post_id);
+
+CREATE INDEX IF NOT EXISTS idx_attachments_reply ON attachments (reply_id);
+
+-- The files of the deleted attachments, also the ones deleted in
+-- cascade, wait here until they are removed from the storage.
+CREATE TABLE IF NOT EXISTS attachment_deletions (
+    storage_key VARCHAR(100) NOT NULL,
+    created_at timestamp DEFAULT now(),
+    CONSTRAINT pk_attachment_deletions PRIMARY KEY(storage_key)
+);
+
+CREATE OR REPLACE FUNCTION queue_attachment_deletion() RETURNS trigger AS $$
+BEGIN
+    INSERT INTO attachment_deletions (storage_key)
+        VALUES (OLD.storage_key)
+        ON CONFLICT DO NOTHING;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tg_attachments_deletions AFTER DELETE ON attachments
+    FOR EACH ROW EXECUTE FUNCTION queue_attachment_deletion();
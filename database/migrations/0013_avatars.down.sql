@@ -0,0 +1,7 @@
+DROP TRIGGER IF EXISTS tg_users_avatars ON users;
+
+DROP FUNCTION IF EXISTS queue_avatar_deletion();
+
+ALTER TABLE users
+    DROP COLUMN IF EXISTS avatar,
+    ADD COLUMN IF NOT EXISTS picture VARCHAR(256) DEFAULT 'https://placekitten.com/g/300/300';
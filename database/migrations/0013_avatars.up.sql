@@ -0,0 +1,25 @@
+-- The pictures of the users are uploaded and served by the API, avatar
+-- is the prefix of their files in the storage, NULL for the users
+-- without one, who get an identicon.
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS avatar VARCHAR(100),
+    DROP COLUMN IF EXISTS picture;
+
+-- the replaced and deleted avatars are queued to be removed from the
+-- storage with the files of the attachments, a file for each of the
+-- sizes of avatar.Sizes.
+CREATE OR REPLACE FUNCTION queue_avatar_deletion() RETURNS trigger AS $$
+BEGIN
+    IF OLD.avatar IS NOT NULL AND (TG_OP = 'DELETE' OR OLD.avatar IS DISTINCT FROM NEW.avatar) THEN
+        INSERT INTO attachment_deletions (storage_key)
+            SELECT OLD.avatar || '-' || size || '.jpg'
+            FROM unnest(ARRAY[64, 128, 300]) size
+            ON CONFLICT DO NOTHING;
+    END IF;
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER tg_users_avatars AFTER DELETE OR UPDATE OF avatar ON users
+    FOR EACH ROW EXECUTE FUNCTION queue_avatar_deletion();
@@ -0,0 +1,3 @@
+ALTER TABLE replies DROP COLUMN IF EXISTS body_html;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS body_html;
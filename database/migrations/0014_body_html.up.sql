@@ -0,0 +1,6 @@
+-- body_html is the Markdown of the body rendered as sanitized HTML when
+-- the post or reply is written. The rows written before this migration
+-- have NULL and are rendered in the background by the server.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS body_html text;
+
+ALTER TABLE replies ADD COLUMN IF NOT EXISTS body_html text;
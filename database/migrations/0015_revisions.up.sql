@@ -0,0 +1,33 @@
+-- Every version of the content of a post or reply, numbered from 1 for
+-- each one. The last revision is the current content.
+CREATE TABLE IF NOT EXISTS revisions (
+    id serial NOT NULL,
+    post_id int,
+    reply_id int,
+    number int NOT NULL,
+    user_id int,
+    title VARCHAR(150),
+    category VARCHAR(150),
+    body text NOT NULL,
+    created_at timestamp DEFAULT now(),
+    CONSTRAINT pk_revisions PRIMARY KEY(id),
+    CONSTRAINT fk_revisions_posts FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_revisions_replies FOREIGN KEY(reply_id) REFERENCES replies(id) ON DELETE CASCADE,
+    CONSTRAINT fk_revisions_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT ck_revisions_target CHECK ((post_id IS NULL) <> (reply_id IS NULL)),
+    CONSTRAINT uq_revisions_post UNIQUE(post_id, number),
+    CONSTRAINT uq_revisions_reply UNIQUE(reply_id, number)
+);
+
+-- the current content of the existing posts and replies is their first
+-- revision.
+INSERT INTO revisions (post_id, number, user_id, title, category, body, created_at)
+    SELECT id, 1, user_id, title, category, body, updated_at
+    FROM posts
+    WHERE NOT EXISTS (SELECT 1 FROM revisions r WHERE r.post_id = posts.id);
+
+INSERT INTO revisions (reply_id, number, user_id, body, created_at)
+    SELECT id, 1, user_id, body, updated_at
+    FROM replies
+    WHERE deleted_at IS NULL
+        AND NOT EXISTS (SELECT 1 FROM revisions r WHERE r.reply_id = replies.id);
@@ -0,0 +1,30 @@
+DROP INDEX IF EXISTS idx_replies_deleted;
+
+DROP INDEX IF EXISTS idx_posts_deleted;
+
+DROP INDEX IF EXISTS idx_subjects_deleted;
+
+DROP INDEX IF EXISTS idx_users_deleted;
+
+-- without the column the deleted rows would be visible again, and
+-- removing them would remove what depends on them too. They must be
+-- restored or purged first. The purged users and the tombstones of the
+-- replies are kept as they are.
+DO $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM users WHERE deleted_at IS NOT NULL AND purged_at IS NULL)
+		OR EXISTS (SELECT 1 FROM subjects WHERE deleted_at IS NOT NULL)
+		OR EXISTS (SELECT 1 FROM posts WHERE deleted_at IS NOT NULL)
+		OR EXISTS (SELECT 1 FROM replies WHERE deleted_at IS NOT NULL AND body <> '') THEN
+		RAISE EXCEPTION 'there are deleted users, subjects, posts or replies, restore or purge them first';
+	END IF;
+END
+$$;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE subjects DROP COLUMN IF EXISTS deleted_at;
+
+ALTER TABLE users DROP COLUMN IF EXISTS purged_at;
+
+ALTER TABLE users DROP COLUMN IF EXISTS deleted_at;
@@ -0,0 +1,20 @@
+-- The users, subjects and posts are marked as deleted instead of being
+-- removed, so they can be restored, and are purged after the retention
+-- period. The replies already have deleted_at for the tombstones.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deleted_at timestamp;
+
+-- the purged users are kept without their personal data, so the posts
+-- and replies they wrote are not lost.
+ALTER TABLE users ADD COLUMN IF NOT EXISTS purged_at timestamp;
+
+ALTER TABLE subjects ADD COLUMN IF NOT EXISTS deleted_at timestamp;
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS deleted_at timestamp;
+
+CREATE INDEX IF NOT EXISTS idx_users_deleted ON users (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_subjects_deleted ON subjects (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_posts_deleted ON posts (deleted_at) WHERE deleted_at IS NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_replies_deleted ON replies (deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,102 @@
+DROP TABLE IF EXISTS warnings;
+
+DROP TABLE IF EXISTS moderation_actions;
+
+DROP TABLE IF EXISTS reports;
+
+DROP TABLE IF EXISTS moderation_cases;
+
+DROP TRIGGER IF EXISTS tg_replies_events ON replies;
+
+CREATE TRIGGER tg_replies_events AFTER INSERT OR DELETE OR UPDATE OF body, deleted_at ON replies
+    FOR EACH ROW EXECUTE FUNCTION notify_reply_event();
+
+-- restore the function of 0011_threaded_replies.
+CREATE OR REPLACE FUNCTION notify_reply_event() RETURNS trigger AS $$
+DECLARE
+    r replies;
+    kind text;
+    subject int;
+    payload jsonb;
+    channels jsonb;
+    event_id bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        r := OLD;
+    ELSE
+        r := NEW;
+    END IF;
+
+    kind := 'reply.' || CASE
+        WHEN TG_OP = 'INSERT' THEN 'created'
+        WHEN TG_OP = 'UPDATE' AND r.deleted_at IS NULL THEN 'updated'
+        ELSE 'deleted'
+    END;
+
+    SELECT subject_id INTO subject FROM posts WHERE id = r.post_id;
+
+    payload := jsonb_build_object(
+        'id', r.id,
+        'post_id', r.post_id,
+        'parent_id', r.parent_id,
+        'subject_id', subject,
+        'user_id', r.user_id
+    );
+    channels := jsonb_build_array('post:' || r.post_id);
+
+    -- the post is already gone when its replies are deleted in cascade.
+    IF subject IS NOT NULL THEN
+        INSERT INTO events (subject_id, type, data)
+            VALUES (subject, kind, payload)
+            RETURNING id INTO event_id;
+
+        channels := channels || jsonb_build_array('subject:' || subject);
+    END IF;
+
+    PERFORM pg_notify('events', jsonb_build_object(
+        'id', event_id,
+        'type', kind,
+        'channels', channels,
+        'data', payload
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tg_posts_events ON posts;
+
+CREATE TRIGGER tg_posts_events AFTER INSERT ON posts
+    FOR EACH ROW EXECUTE FUNCTION notify_post_event();
+
+-- restore the function of 0008_event_log.
+CREATE OR REPLACE FUNCTION notify_post_event() RETURNS trigger AS $$
+DECLARE
+    payload jsonb;
+    event_id bigint;
+BEGIN
+    payload := jsonb_build_object(
+        'id', NEW.id,
+        'subject_id', NEW.subject_id,
+        'user_id', NEW.user_id,
+        'title', NEW.title
+    );
+
+    INSERT INTO events (subject_id, type, data)
+        VALUES (NEW.subject_id, 'post.created', payload)
+        RETURNING id INTO event_id;
+
+    PERFORM pg_notify('events', jsonb_build_object(
+        'id', event_id,
+        'type', 'post.created',
+        'channels', jsonb_build_array('subject:' || NEW.subject_id),
+        'data', payload
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+ALTER TABLE replies DROP COLUMN IF EXISTS hidden_at;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS hidden_at;
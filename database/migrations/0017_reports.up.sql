@@ -0,0 +1,181 @@
+-- The posts and replies hidden by the moderators, or automatically when
+-- they are reported too many times, are not shown until the case is
+-- dismissed.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS hidden_at timestamp;
+
+ALTER TABLE replies ADD COLUMN IF NOT EXISTS hidden_at timestamp;
+
+-- hiding a post or a reply is sent as post.deleted or reply.deleted,
+-- like the tombstones, and showing it again as post.created or
+-- reply.updated.
+CREATE OR REPLACE FUNCTION notify_post_event() RETURNS trigger AS $$
+DECLARE
+    kind text;
+    payload jsonb;
+    event_id bigint;
+BEGIN
+    payload := jsonb_build_object(
+        'id', NEW.id,
+        'subject_id', NEW.subject_id,
+        'user_id', NEW.user_id
+    );
+
+    IF TG_OP = 'UPDATE' AND NEW.hidden_at IS NOT NULL THEN
+        kind := 'post.deleted';
+    ELSE
+        kind := 'post.created';
+        payload := payload || jsonb_build_object('title', NEW.title);
+    END IF;
+
+    INSERT INTO events (subject_id, type, data)
+        VALUES (NEW.subject_id, kind, payload)
+        RETURNING id INTO event_id;
+
+    PERFORM pg_notify('events', jsonb_build_object(
+        'id', event_id,
+        'type', kind,
+        'channels', jsonb_build_array('subject:' || NEW.subject_id),
+        'data', payload
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tg_posts_events ON posts;
+
+CREATE TRIGGER tg_posts_events AFTER INSERT OR UPDATE OF hidden_at ON posts
+    FOR EACH ROW EXECUTE FUNCTION notify_post_event();
+
+CREATE OR REPLACE FUNCTION notify_reply_event() RETURNS trigger AS $$
+DECLARE
+    r replies;
+    kind text;
+    subject int;
+    payload jsonb;
+    channels jsonb;
+    event_id bigint;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        r := OLD;
+    ELSE
+        r := NEW;
+    END IF;
+
+    kind := 'reply.' || CASE
+        WHEN TG_OP = 'INSERT' THEN 'created'
+        WHEN TG_OP = 'UPDATE' AND r.deleted_at IS NULL AND r.hidden_at IS NULL THEN 'updated'
+        ELSE 'deleted'
+    END;
+
+    SELECT subject_id INTO subject FROM posts WHERE id = r.post_id;
+
+    payload := jsonb_build_object(
+        'id', r.id,
+        'post_id', r.post_id,
+        'parent_id', r.parent_id,
+        'subject_id', subject,
+        'user_id', r.user_id
+    );
+    channels := jsonb_build_array('post:' || r.post_id);
+
+    -- the post is already gone when its replies are deleted in cascade.
+    IF subject IS NOT NULL THEN
+        INSERT INTO events (subject_id, type, data)
+            VALUES (subject, kind, payload)
+            RETURNING id INTO event_id;
+
+        channels := channels || jsonb_build_array('subject:' || subject);
+    END IF;
+
+    PERFORM pg_notify('events', jsonb_build_object(
+        'id', event_id,
+        'type', kind,
+        'channels', channels,
+        'data', payload
+    )::text);
+
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tg_replies_events ON replies;
+
+CREATE TRIGGER tg_replies_events AFTER INSERT OR DELETE OR UPDATE OF body, deleted_at, hidden_at ON replies
+    FOR EACH ROW EXECUTE FUNCTION notify_reply_event();
+
+-- A case groups the reports of a post, reply or user while it is open.
+-- user_id is the user reported or the author of the content.
+CREATE TABLE IF NOT EXISTS moderation_cases (
+    id serial NOT NULL,
+    target VARCHAR(10) NOT NULL,
+    post_id int,
+    reply_id int,
+    user_id int NOT NULL,
+    status VARCHAR(10) NOT NULL DEFAULT 'open',
+    assignee_id int,
+    created_at timestamp DEFAULT now(),
+    updated_at timestamp NOT NULL DEFAULT now(),
+    resolved_at timestamp,
+    CONSTRAINT pk_moderation_cases PRIMARY KEY(id),
+    CONSTRAINT fk_moderation_cases_posts FOREIGN KEY(post_id) REFERENCES posts(id) ON DELETE CASCADE,
+    CONSTRAINT fk_moderation_cases_replies FOREIGN KEY(reply_id) REFERENCES replies(id) ON DELETE CASCADE,
+    CONSTRAINT fk_moderation_cases_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_moderation_cases_assignees FOREIGN KEY(assignee_id) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT ck_moderation_cases_target CHECK (
+        (target = 'post' AND post_id IS NOT NULL AND reply_id IS NULL)
+        OR (target = 'reply' AND reply_id IS NOT NULL AND post_id IS NULL)
+        OR (target = 'user' AND post_id IS NULL AND reply_id IS NULL)),
+    CONSTRAINT ck_moderation_cases_status CHECK (status IN ('open', 'resolved', 'dismissed'))
+);
+
+-- only one open case for each post, reply or user.
+CREATE UNIQUE INDEX IF NOT EXISTS uq_moderation_cases_open
+    ON moderation_cases (target, COALESCE(post_id, reply_id, user_id)) WHERE status = 'open';
+
+CREATE INDEX IF NOT EXISTS idx_moderation_cases_status ON moderation_cases (status, created_at DESC, id DESC);
+
+CREATE TABLE IF NOT EXISTS reports (
+    id serial NOT NULL,
+    case_id int NOT NULL,
+    reporter_id int NOT NULL,
+    reason VARCHAR(20) NOT NULL,
+    details VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at timestamp DEFAULT now(),
+    CONSTRAINT pk_reports PRIMARY KEY(id),
+    CONSTRAINT fk_reports_cases FOREIGN KEY(case_id) REFERENCES moderation_cases(id) ON DELETE CASCADE,
+    CONSTRAINT fk_reports_users FOREIGN KEY(reporter_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT uq_reports_reporter UNIQUE(case_id, reporter_id)
+);
+
+-- What the moderators did in each case. moderator_id is NULL for the
+-- automatic actions.
+CREATE TABLE IF NOT EXISTS moderation_actions (
+    id serial NOT NULL,
+    case_id int NOT NULL,
+    moderator_id int,
+    action VARCHAR(10) NOT NULL,
+    note VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at timestamp DEFAULT now(),
+    CONSTRAINT pk_moderation_actions PRIMARY KEY(id),
+    CONSTRAINT fk_moderation_actions_cases FOREIGN KEY(case_id) REFERENCES moderation_cases(id) ON DELETE CASCADE,
+    CONSTRAINT fk_moderation_actions_users FOREIGN KEY(moderator_id) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT ck_moderation_actions_action CHECK (action IN ('hide', 'warn', 'delete', 'dismiss'))
+);
+
+CREATE INDEX IF NOT EXISTS idx_moderation_actions_case ON moderation_actions (case_id, id);
+
+CREATE TABLE IF NOT EXISTS warnings (
+    id serial NOT NULL,
+    user_id int NOT NULL,
+    case_id int,
+    moderator_id int,
+    note VARCHAR(1000) NOT NULL DEFAULT '',
+    created_at timestamp DEFAULT now(),
+    CONSTRAINT pk_warnings PRIMARY KEY(id),
+    CONSTRAINT fk_warnings_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_warnings_cases FOREIGN KEY(case_id) REFERENCES moderation_cases(id) ON DELETE SET NULL,
+    CONSTRAINT fk_warnings_moderators FOREIGN KEY(moderator_id) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_warnings_user ON warnings (user_id, created_at DESC, id DESC);
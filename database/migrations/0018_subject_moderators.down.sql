@@ -0,0 +1,7 @@
+DROP INDEX IF EXISTS idx_posts_pinned;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS locked_at;
+
+ALTER TABLE posts DROP COLUMN IF EXISTS pinned_at;
+
+DROP TABLE IF EXISTS subject_moderators;
@@ -0,0 +1,20 @@
+-- The moderators of a subject can edit, delete, pin and lock the posts
+-- and replies of the subject, like the admins.
+CREATE TABLE IF NOT EXISTS subject_moderators (
+    subject_id int NOT NULL,
+    user_id int NOT NULL,
+    created_at timestamp DEFAULT now(),
+    CONSTRAINT pk_subject_moderators PRIMARY KEY(subject_id, user_id),
+    CONSTRAINT fk_subject_moderators_subjects FOREIGN KEY(subject_id) REFERENCES subjects(id) ON DELETE CASCADE,
+    CONSTRAINT fk_subject_moderators_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+
+CREATE INDEX IF NOT EXISTS idx_subject_moderators_user ON subject_moderators (user_id);
+
+-- the pinned posts go first in their subject and the locked posts don't
+-- accept new replies.
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS pinned_at timestamp;
+
+ALTER TABLE posts ADD COLUMN IF NOT EXISTS locked_at timestamp;
+
+CREATE INDEX IF NOT EXISTS idx_posts_pinned ON posts (subject_id, pinned_at) WHERE pinned_at IS NOT NULL;
@@ -0,0 +1,22 @@
+-- Suspensions of the users by the admins. A sanction without expiry is
+-- permanent, a ban, and the read-only ones let the user log in and read
+-- but not write. It is active until it expires or is lifted.
+CREATE TABLE IF NOT EXISTS sanctions (
+    id serial NOT NULL,
+    user_id int NOT NULL,
+    read_only boolean NOT NULL DEFAULT false,
+    reason VARCHAR(1000) NOT NULL,
+    expires_at timestamp,
+    created_by int,
+    created_at timestamp DEFAULT now(),
+    lifted_at timestamp,
+    lifted_by int,
+    appeal VARCHAR(2000),
+    appealed_at timestamp,
+    CONSTRAINT pk_sanctions PRIMARY KEY(id),
+    CONSTRAINT fk_sanctions_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE,
+    CONSTRAINT fk_sanctions_creators FOREIGN KEY(created_by) REFERENCES users(id) ON DELETE SET NULL,
+    CONSTRAINT fk_sanctions_lifters FOREIGN KEY(lifted_by) REFERENCES users(id) ON DELETE SET NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_sanctions_active ON sanctions (user_id) WHERE lifted_at IS NULL;
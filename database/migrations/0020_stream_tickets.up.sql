@@ -0,0 +1,11 @@
+-- Single-use tickets of the WebSocket and Server-Sent Events streams,
+-- so the browsers don't send the access token in the URL. Each ticket
+-- is exchanged once for the token it was issued with.
+CREATE TABLE IF NOT EXISTS stream_tickets (
+    ticket_hash VARCHAR(64) NOT NULL,
+    user_id int NOT NULL,
+    token text NOT NULL,
+    expires_at timestamp NOT NULL,
+    CONSTRAINT pk_stream_tickets PRIMARY KEY(ticket_hash),
+    CONSTRAINT fk_stream_tickets_users FOREIGN KEY(user_id) REFERENCES users(id) ON DELETE CASCADE
+);
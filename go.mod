@@ -1,12 +1,16 @@
 module github.com/orlmonteverde/go-postgres-microblog
 
-go 1.14
+go 1.20
 
 require (
 	github.com/dgrijalva/jwt-go v3.2.0+incompatible
 	github.com/go-chi/chi v4.0.4+incompatible
 	github.com/go-chi/cors v1.2.0
+	github.com/gorilla/websocket v1.4.2
 	github.com/joho/godotenv v1.3.0
 	github.com/lib/pq v1.5.2
-	golang.org/x/crypto v0.0.0-20200510223506-06a226fb4e37
+	github.com/yuin/goldmark v1.7.8
+	golang.org/x/crypto v0.14.0
+	golang.org/x/image v0.18.0
+	golang.org/x/net v0.17.0
 )
@@ -0,0 +1,217 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/attachment"
+)
+
+// AttachmentRepository manages the operations with the database that
+// correspond to the attachment model.
+type AttachmentRepository struct {
+	Data *Data
+}
+
+// GetOne returns one attachment by id. The attachments of the deleted
+// posts and replies are hidden, as the ones of GetByPost and GetByReply.
+func (ar *AttachmentRepository) GetOne(ctx context.Context, id uint) (attachment.Attachment, error) {
+	q := `
+	SELECT id, user_id, COALESCE(post_id, 0), COALESCE(reply_id, 0), filename, content_type,
+		size, storage_key, created_at
+		FROM attachments a
+		WHERE a.id = $1
+			AND NOT EXISTS (SELECT 1 FROM posts p WHERE p.id = a.post_id AND (p.deleted_at IS NOT NULL OR p.hidden_at IS NOT NULL))
+			AND NOT EXISTS (SELECT 1 FROM replies r JOIN posts p ON p.id = r.post_id
+				WHERE r.id = a.reply_id AND (r.deleted_at IS NOT NULL OR r.hidden_at IS NOT NULL
+					OR p.deleted_at IS NOT NULL OR p.hidden_at IS NOT NULL));
+	`
+
+	var a attachment.Attachment
+	err := ar.Data.DB.QueryRowContext(ctx, q, id).Scan(&a.ID, &a.UserID, &a.PostID, &a.ReplyID,
+		&a.Filename, &a.ContentType, &a.Size, &a.StorageKey, &a.CreatedAt)
+	if err != nil {
+		return attachment.Attachment{}, translate(err, "attachment")
+	}
+
+	return a, nil
+}
+
+// GetByPost returns the attachments of the post, the oldest first.
+func (ar *AttachmentRepository) GetByPost(ctx context.Context, postID uint) ([]attachment.Attachment, error) {
+	q := `
+	SELECT id, user_id, COALESCE(post_id, 0), COALESCE(reply_id, 0), filename, content_type,
+		size, storage_key, created_at
+		FROM attachments a
+		WHERE a.post_id = $1
+			AND NOT EXISTS (SELECT 1 FROM posts p WHERE p.id = a.post_id AND (p.deleted_at IS NOT NULL OR p.hidden_at IS NOT NULL))
+		ORDER BY id;
+	`
+
+	return ar.query(ctx, q, postID)
+}
+
+// GetByReply returns the attachments of the reply, the oldest first.
+func (ar *AttachmentRepository) GetByReply(ctx context.Context, replyID uint) ([]attachment.Attachment, error) {
+	q := `
+	SELECT id, user_id, COALESCE(post_id, 0), COALESCE(reply_id, 0), filename, content_type,
+		size, storage_key, created_at
+		FROM attachments a
+		WHERE a.reply_id = $1
+			AND NOT EXISTS (SELECT 1 FROM replies r JOIN posts p ON p.id = r.post_id
+				WHERE r.id = a.reply_id AND (r.deleted_at IS NOT NULL OR r.hidden_at IS NOT NULL
+					OR p.deleted_at IS NOT NULL OR p.hidden_at IS NOT NULL))
+		ORDER BY id;
+	`
+
+	return ar.query(ctx, q, replyID)
+}
+
+// query returns the attachments of the query.
+func (ar *AttachmentRepository) query(ctx context.Context, q string, args ...interface{}) ([]attachment.Attachment, error) {
+	rows, err := ar.Data.DB.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, translate(err, "attachment")
+	}
+
+	defer rows.Close()
+
+	attachments := []attachment.Attachment{}
+	for rows.Next() {
+		var a attachment.Attachment
+		err := rows.Scan(&a.ID, &a.UserID, &a.PostID, &a.ReplyID, &a.Filename, &a.ContentType,
+			&a.Size, &a.StorageKey, &a.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		attachments = append(attachments, a)
+	}
+
+	return attachments, rows.Err()
+}
+
+// Usage returns the space used by the files of the user.
+func (ar *AttachmentRepository) Usage(ctx context.Context, userID uint) (attachment.Usage, error) {
+	q := `SELECT COALESCE(sum(size), 0) FROM attachments WHERE user_id = $1;`
+
+	u := attachment.Usage{Quota: attachment.Quota}
+	err := ar.Data.DB.QueryRowContext(ctx, q, userID).Scan(&u.Used)
+	if err != nil {
+		return attachment.Usage{}, err
+	}
+
+	return u, nil
+}
+
+// Create adds a new attachment if the user has not exceeded the quota
+// and the post or reply has room for it. The user is locked, so
+// concurrent uploads are checked one after the other.
+func (ar *AttachmentRepository) Create(ctx context.Context, a *attachment.Attachment) error {
+	qLock := `SELECT id FROM users WHERE id = $1 FOR UPDATE;`
+	qUsed := `SELECT COALESCE(sum(size), 0) FROM attachments WHERE user_id = $1;`
+	qCount := `
+	SELECT count(*) FROM attachments
+		WHERE post_id IS NOT DISTINCT FROM $1 AND reply_id IS NOT DISTINCT FROM $2;
+	`
+	q := `
+	INSERT INTO attachments (user_id, post_id, reply_id, filename, content_type, size, storage_key, created_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id;
+	`
+
+	tx, err := ar.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var locked uint
+	err = tx.QueryRowContext(ctx, qLock, a.UserID).Scan(&locked)
+	if err != nil {
+		return translate(err, "user")
+	}
+
+	var used int64
+	err = tx.QueryRowContext(ctx, qUsed, a.UserID).Scan(&used)
+	if err != nil {
+		return err
+	}
+
+	if used+a.Size > attachment.Quota {
+		return attachment.ErrQuotaExceeded
+	}
+
+	var count int
+	err = tx.QueryRowContext(ctx, qCount, nullID(a.PostID), nullID(a.ReplyID)).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if count >= attachment.MaxPerItem {
+		return attachment.ErrTooMany
+	}
+
+	a.CreatedAt = time.Now()
+	err = tx.QueryRowContext(ctx, q, a.UserID, nullID(a.PostID), nullID(a.ReplyID), a.Filename,
+		a.ContentType, a.Size, a.StorageKey, a.CreatedAt).Scan(&a.ID)
+	if err != nil {
+		return translate(err, "attachment")
+	}
+
+	return tx.Commit()
+}
+
+// Delete removes an attachment by id. Its file is queued to be removed
+// from the storage.
+func (ar *AttachmentRepository) Delete(ctx context.Context, id uint) error {
+	q := `DELETE FROM attachments WHERE id=$1;`
+
+	res, err := ar.Data.DB.ExecContext(ctx, q, id)
+	if err != nil {
+		return translate(err, "attachment")
+	}
+
+	return affected(res, "attachment")
+}
+
+// Deletions returns the keys of the files waiting to be removed from
+// the storage, the oldest first.
+func (ar *AttachmentRepository) Deletions(ctx context.Context, limit int) ([]string, error) {
+	q := `
+	SELECT storage_key FROM attachment_deletions
+		ORDER BY created_at
+		LIMIT $1;
+	`
+
+	rows, err := ar.Data.DB.QueryContext(ctx, q, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var key string
+		err := rows.Scan(&key)
+		if err != nil {
+			return nil, err
+		}
+
+		keys = append(keys, key)
+	}
+
+	return keys, rows.Err()
+}
+
+// Forget removes the keys of the files already removed from the
+// storage.
+func (ar *AttachmentRepository) Forget(ctx context.Context, keys []string) error {
+	q := `DELETE FROM attachment_deletions WHERE storage_key = ANY($1);`
+
+	_, err := ar.Data.DB.ExecContext(ctx, q, pq.Array(keys))
+	return err
+}
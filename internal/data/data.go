@@ -25,7 +25,7 @@ func New() *Data {
 
 // initialize the data variable with the connection to the database.
 func initDB() {
-	db, err := getConnection()
+	db, err := Connect()
 	if err != nil {
 		log.Panic(err)
 	}
@@ -0,0 +1,105 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/enrollment"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/subject"
+)
+
+// EnrollmentRepository manages the operations with the database that
+// correspond to the enrollment model.
+type EnrollmentRepository struct {
+	Data *Data
+}
+
+// GetSubjects returns the subjects the user is enrolled in.
+func (er *EnrollmentRepository) GetSubjects(ctx context.Context, userID uint) ([]subject.Subject, error) {
+	q := `
+	SELECT s.id, s.name, s.year
+		FROM subjects s
+		JOIN enrollments e ON e.subject_id = s.id
+		WHERE e.user_id = $1 AND s.deleted_at IS NULL
+		ORDER BY s.year, s.name;
+	`
+
+	return er.querySubjects(ctx, q, userID)
+}
+
+// GetSuggested returns the subjects of the year of the user that
+// the user is not enrolled in.
+func (er *EnrollmentRepository) GetSuggested(ctx context.Context, userID uint) ([]subject.Subject, error) {
+	q := `
+	SELECT s.id, s.name, s.year
+		FROM subjects s
+		JOIN users u ON u.year = s.year
+		WHERE u.id = $1 AND s.deleted_at IS NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM enrollments e
+				WHERE e.user_id = u.id AND e.subject_id = s.id
+			)
+		ORDER BY s.name;
+	`
+
+	return er.querySubjects(ctx, q, userID)
+}
+
+func (er *EnrollmentRepository) querySubjects(ctx context.Context, q string, userID uint) ([]subject.Subject, error) {
+	rows, err := er.Data.DB.QueryContext(ctx, q, userID)
+	if err != nil {
+		return nil, translate(err, "subject")
+	}
+
+	defer rows.Close()
+
+	var subjects []subject.Subject
+	for rows.Next() {
+		var s subject.Subject
+		rows.Scan(&s.ID, &s.Name, &s.Year)
+		subjects = append(subjects, s)
+	}
+
+	return subjects, nil
+}
+
+// Enroll adds a new enrollment. Enrolling twice in a subject is not
+// an error, but the subject must not be deleted.
+func (er *EnrollmentRepository) Enroll(ctx context.Context, e *enrollment.Enrollment) error {
+	qSubject := `SELECT EXISTS (SELECT 1 FROM subjects WHERE id = $1 AND deleted_at IS NULL);`
+	q := `
+	INSERT INTO enrollments (user_id, subject_id, created_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, subject_id) DO NOTHING;
+	`
+
+	var exists bool
+	err := er.Data.DB.QueryRowContext(ctx, qSubject, e.SubjectID).Scan(&exists)
+	if err != nil {
+		return err
+	}
+
+	if !exists {
+		return errNoReference("enrollment")
+	}
+
+	e.CreatedAt = time.Now()
+	_, err = er.Data.DB.ExecContext(ctx, q, e.UserID, e.SubjectID, e.CreatedAt)
+	if err != nil {
+		return translate(err, "enrollment")
+	}
+
+	return nil
+}
+
+// Unenroll removes the enrollment of a user in a subject.
+func (er *EnrollmentRepository) Unenroll(ctx context.Context, userID, subjectID uint) error {
+	q := `DELETE FROM enrollments WHERE user_id=$1 AND subject_id=$2;`
+
+	res, err := er.Data.DB.ExecContext(ctx, q, userID, subjectID)
+	if err != nil {
+		return translate(err, "enrollment")
+	}
+
+	return affected(res, "enrollment")
+}
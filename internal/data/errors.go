@@ -0,0 +1,70 @@
+package data
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+)
+
+// fields of the unique constraints, so the clients know which field
+// is duplicated.
+var uniqueFields = map[string]apperror.FieldError{
+	"users_username_key": {Field: "username", Code: "taken", Message: "username already exists"},
+	"users_email_key":    {Field: "email", Code: "taken", Message: "email already exists"},
+}
+
+// translate converts the database errors into domain errors.
+// Other errors are returned as they are.
+func translate(err error, resource string) error {
+	if err == nil {
+		return nil
+	}
+
+	if errors.Is(err, sql.ErrNoRows) {
+		return apperror.NotFound(resource + " not found")
+	}
+
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+
+	switch pqErr.Code.Name() {
+	case "unique_violation":
+		if f, ok := uniqueFields[pqErr.Constraint]; ok {
+			return apperror.New(apperror.ErrConflict, f.Field+"_"+f.Code, f.Message, f)
+		}
+
+		return apperror.Conflict(resource + " already exists")
+	case "foreign_key_violation":
+		return errNoReference(resource)
+	case "not_null_violation", "check_violation", "string_data_right_truncation",
+		"invalid_text_representation", "numeric_value_out_of_range":
+		return apperror.Validation("invalid " + resource)
+	}
+
+	return err
+}
+
+// affected returns a not found error if the statement didn't change any row.
+func affected(res sql.Result, resource string) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return apperror.NotFound(resource + " not found")
+	}
+
+	return nil
+}
+
+// errNoReference returns the error of a resource that references
+// another one that does not exist or is deleted.
+func errNoReference(resource string) error {
+	return apperror.Validation(fmt.Sprintf("%s references a resource that does not exist", resource))
+}
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/event"
+)
+
+// EventRepository manages the log of the events of the subjects.
+type EventRepository struct {
+	Data *Data
+}
+
+// GetBySubject returns the events of the subject after the event
+// afterID, oldest first.
+func (er *EventRepository) GetBySubject(ctx context.Context, subjectID uint, afterID int64, limit int) ([]event.Event, error) {
+	q := `
+	SELECT id, type, data
+		FROM events
+		WHERE subject_id = $1 AND id > $2
+		ORDER BY id
+		LIMIT $3;
+	`
+
+	rows, err := er.Data.DB.QueryContext(ctx, q, subjectID, afterID, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	channel := event.Channel(event.KindSubject, subjectID)
+
+	var events []event.Event
+	for rows.Next() {
+		e := event.Event{Channels: []string{channel}}
+		err := rows.Scan(&e.ID, &e.Type, &e.Data)
+		if err != nil {
+			return nil, err
+		}
+
+		events = append(events, e)
+	}
+
+	return events, rows.Err()
+}
+
+// Purge removes the events created before the time and returns how
+// many were removed.
+func (er *EventRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	q := `DELETE FROM events WHERE created_at < $1;`
+
+	res, err := er.Data.DB.ExecContext(ctx, q, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
+}
@@ -0,0 +1,48 @@
+package data
+
+import (
+	"context"
+	"log"
+	"os"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// EventChannel is the channel where the database notifies the events
+// of the posts, replies and notifications.
+const EventChannel = "events"
+
+// Listen sends the payload of every notification of the channel to fn
+// until the context is done. The listener has its own connection and
+// reconnects by itself if the connection is lost.
+func Listen(ctx context.Context, channel string, fn func(payload string)) error {
+	uri := os.Getenv("DATABASE_URI")
+	l := pq.NewListener(uri, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("listen %s: %v", channel, err)
+		}
+	})
+
+	defer l.Close()
+
+	err := l.Listen(channel)
+	if err != nil {
+		return err
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n := <-l.Notify:
+			// nil is sent after a reconnection, the events notified
+			// while disconnected are lost.
+			if n != nil {
+				fn(n.Extra)
+			}
+		case <-time.After(90 * time.Second):
+			go l.Ping()
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"html"
+	"log"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/markdown"
+)
+
+// renderBatch is the number of bodies rendered by RenderPending in each
+// query.
+const renderBatch = 100
+
+// bodyHTML returns the cached HTML of a body, or renders it if the row
+// has not been rendered yet. If the Markdown can't be rendered the body
+// is returned as escaped text.
+func bodyHTML(cached sql.NullString, body string) string {
+	if cached.Valid {
+		return cached.String
+	}
+
+	rendered, err := markdown.Render(body)
+	if err != nil {
+		return "<p>" + html.EscapeString(body) + "</p>"
+	}
+
+	return rendered
+}
+
+// RenderPending renders the bodies of the posts and replies written
+// before body_html existed, in batches, until there are none left or
+// ctx is done.
+func (d *Data) RenderPending(ctx context.Context) {
+	for _, table := range []string{"posts", "replies"} {
+		for ctx.Err() == nil {
+			n, err := renderBatchOf(ctx, d.DB, table)
+			if err != nil {
+				log.Printf("render %s: %v", table, err)
+				break
+			}
+
+			if n < renderBatch {
+				break
+			}
+		}
+	}
+}
+
+// renderBatchOf renders one batch of the rows of the table without
+// body_html and returns how many rows were read.
+func renderBatchOf(ctx context.Context, db *sql.DB, table string) (int, error) {
+	q := `
+	SELECT id, body FROM ` + table + `
+		WHERE body_html IS NULL
+		ORDER BY id
+		LIMIT $1;
+	`
+	qUpdate := `
+	UPDATE ` + table + ` set body_html=$1
+		WHERE id=$2 AND body=$3;
+	`
+
+	rows, err := db.QueryContext(ctx, q, renderBatch)
+	if err != nil {
+		return 0, err
+	}
+
+	defer rows.Close()
+
+	type pending struct {
+		id   uint
+		body string
+	}
+
+	var batch []pending
+	for rows.Next() {
+		var p pending
+		err := rows.Scan(&p.id, &p.body)
+		if err != nil {
+			return 0, err
+		}
+
+		batch = append(batch, p)
+	}
+
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, p := range batch {
+		// a body that can't be rendered is kept as escaped text, so the
+		// row is not read again.
+		_, err := db.ExecContext(ctx, qUpdate, bodyHTML(sql.NullString{}, p.body), p.id, p.body)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	return len(batch), nil
+}
@@ -0,0 +1,259 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// migrationLockID is the key of the advisory lock held while migrating,
+// so two instances don't apply the same migrations concurrently.
+const migrationLockID = 30245
+
+var migrationFile = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+// Migration is a version of the database schema.
+type Migration struct {
+	Version   uint
+	Name      string
+	Up        string
+	Down      string
+	AppliedAt *time.Time
+}
+
+// Migrator applies the versioned migrations to the database and keeps
+// track of them in the schema_migrations table.
+type Migrator struct {
+	DB         *sql.DB
+	Migrations fs.FS
+}
+
+// Up applies every pending migration and returns the applied ones.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		migrations, err := m.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for _, mig := range migrations {
+			if mig.AppliedAt != nil {
+				continue
+			}
+
+			q := `INSERT INTO schema_migrations (version, name, applied_at) VALUES ($1, $2, $3);`
+			err := m.exec(ctx, conn, mig.Up, q, mig.Version, mig.Name, time.Now())
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+
+			applied = append(applied, mig)
+		}
+
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last n applied migrations and returns the reverted ones.
+func (m *Migrator) Down(ctx context.Context, n int) ([]Migration, error) {
+	var reverted []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		migrations, err := m.status(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		for i := len(migrations) - 1; i >= 0 && len(reverted) < n; i-- {
+			mig := migrations[i]
+			if mig.AppliedAt == nil {
+				continue
+			}
+
+			if mig.Down == "" {
+				return fmt.Errorf("migration %04d_%s has no down file", mig.Version, mig.Name)
+			}
+
+			q := `DELETE FROM schema_migrations WHERE version = $1;`
+			err := m.exec(ctx, conn, mig.Down, q, mig.Version)
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s: %w", mig.Version, mig.Name, err)
+			}
+
+			reverted = append(reverted, mig)
+		}
+
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status returns every known migration, with AppliedAt set for the
+// ones already applied.
+func (m *Migrator) Status(ctx context.Context) ([]Migration, error) {
+	var migrations []Migration
+	err := m.locked(ctx, func(conn *sql.Conn) error {
+		var err error
+		migrations, err = m.status(ctx, conn)
+		return err
+	})
+
+	return migrations, err
+}
+
+// locked runs fn on a single connection holding the migration lock.
+func (m *Migrator) locked(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.DB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+
+	defer conn.Close()
+
+	_, err = conn.ExecContext(ctx, `SELECT pg_advisory_lock($1);`, migrationLockID)
+	if err != nil {
+		return err
+	}
+
+	defer conn.ExecContext(context.Background(), `SELECT pg_advisory_unlock($1);`, migrationLockID)
+
+	q := `
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version BIGINT NOT NULL,
+		name VARCHAR(150) NOT NULL,
+		applied_at timestamp NOT NULL,
+		CONSTRAINT pk_schema_migrations PRIMARY KEY(version)
+	);
+	`
+	_, err = conn.ExecContext(ctx, q)
+	if err != nil {
+		return err
+	}
+
+	return fn(conn)
+}
+
+// exec runs a migration script and the bookkeeping query in a transaction.
+func (m *Migrator) exec(ctx context.Context, conn *sql.Conn, script, q string, args ...interface{}) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, script)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, q, args...)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// status merges the migration files with the applied versions.
+func (m *Migrator) status(ctx context.Context, conn *sql.Conn) ([]Migration, error) {
+	migrations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := conn.QueryContext(ctx, `SELECT version, applied_at FROM schema_migrations;`)
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	applied := make(map[uint]time.Time)
+	for rows.Next() {
+		var version uint
+		var appliedAt time.Time
+		if err := rows.Scan(&version, &appliedAt); err != nil {
+			return nil, err
+		}
+
+		applied[version] = appliedAt
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for i := range migrations {
+		if t, ok := applied[migrations[i].Version]; ok {
+			migrations[i].AppliedAt = &t
+		}
+	}
+
+	return migrations, nil
+}
+
+// load reads the migration files sorted by version.
+func (m *Migrator) load() ([]Migration, error) {
+	entries, err := fs.ReadDir(m.Migrations, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[uint]*Migration)
+	for _, e := range entries {
+		match := migrationFile.FindStringSubmatch(e.Name())
+		if match == nil {
+			continue
+		}
+
+		version, err := strconv.ParseUint(match[1], 10, 32)
+		if err != nil {
+			return nil, err
+		}
+
+		b, err := fs.ReadFile(m.Migrations, e.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byVersion[uint(version)]
+		if !ok {
+			mig = &Migration{Version: uint(version), Name: match[2]}
+			byVersion[uint(version)] = mig
+		}
+
+		if mig.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two different names", version)
+		}
+
+		if match[3] == "up" {
+			mig.Up = string(b)
+		} else {
+			mig.Down = string(b)
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, mig := range byVersion {
+		if mig.Up == "" {
+			return nil, fmt.Errorf("migration %04d_%s has no up file", mig.Version, mig.Name)
+		}
+
+		migrations = append(migrations, *mig)
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+
+	return migrations, nil
+}
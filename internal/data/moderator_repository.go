@@ -0,0 +1,99 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/moderator"
+)
+
+// ModeratorRepository manages the operations with the database that
+// correspond to the moderator model.
+type ModeratorRepository struct {
+	Data *Data
+}
+
+// GetBySubject returns the moderators of the subject, the oldest first.
+// The deleted users are not moderators.
+func (mr *ModeratorRepository) GetBySubject(ctx context.Context, subjectID uint) ([]moderator.Moderator, error) {
+	q := `
+	SELECT m.user_id, m.created_at
+		FROM subject_moderators m
+			JOIN users u ON u.id = m.user_id
+		WHERE m.subject_id = $1 AND u.deleted_at IS NULL
+		ORDER BY m.created_at, m.user_id;
+	`
+
+	rows, err := mr.Data.DB.QueryContext(ctx, q, subjectID)
+	if err != nil {
+		return nil, translate(err, "moderator")
+	}
+
+	defer rows.Close()
+
+	moderators := []moderator.Moderator{}
+	for rows.Next() {
+		m := moderator.Moderator{SubjectID: subjectID}
+		err := rows.Scan(&m.UserID, &m.CreatedAt)
+		if err != nil {
+			return nil, err
+		}
+
+		moderators = append(moderators, m)
+	}
+
+	return moderators, rows.Err()
+}
+
+// IsModerator reports whether the user moderates the subject.
+func (mr *ModeratorRepository) IsModerator(ctx context.Context, subjectID, userID uint) (bool, error) {
+	q := `
+	SELECT EXISTS (
+		SELECT 1 FROM subject_moderators m
+			JOIN users u ON u.id = m.user_id
+		WHERE m.subject_id = $1 AND m.user_id = $2 AND u.deleted_at IS NULL
+	);
+	`
+
+	var ok bool
+	err := mr.Data.DB.QueryRowContext(ctx, q, subjectID, userID).Scan(&ok)
+	return ok, err
+}
+
+// Add makes the user a moderator of the subject. Adding a moderator
+// twice is not an error, but the subject and the user must not be
+// deleted.
+func (mr *ModeratorRepository) Add(ctx context.Context, m *moderator.Moderator) error {
+	q := `
+	INSERT INTO subject_moderators (subject_id, user_id, created_at)
+		SELECT s.id, u.id, $3
+		FROM subjects s, users u
+		WHERE s.id = $1 AND s.deleted_at IS NULL AND u.id = $2 AND u.deleted_at IS NULL
+		ON CONFLICT (subject_id, user_id) DO UPDATE set created_at = subject_moderators.created_at
+		RETURNING created_at;
+	`
+
+	err := mr.Data.DB.QueryRowContext(ctx, q, m.SubjectID, m.UserID, time.Now()).Scan(&m.CreatedAt)
+	if err == sql.ErrNoRows {
+		return errNoReference("moderator")
+	}
+
+	if err != nil {
+		return translate(err, "moderator")
+	}
+
+	return nil
+}
+
+// Remove removes the user from the moderators of the subject.
+func (mr *ModeratorRepository) Remove(ctx context.Context, subjectID, userID uint) error {
+	q := `DELETE FROM subject_moderators WHERE subject_id = $1 AND user_id = $2;`
+
+	res, err := mr.Data.DB.ExecContext(ctx, q, subjectID, userID)
+	if err != nil {
+		return translate(err, "moderator")
+	}
+
+	return affected(res, "moderator")
+}
@@ -0,0 +1,259 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/notification"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/post"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/reply"
+)
+
+// NotificationRepository manages the operations with the database that
+// correspond to the notification model.
+type NotificationRepository struct {
+	Data *Data
+}
+
+// GetByUser returns a page of the notifications of the user, newest
+// first. If unread is true, only the unread ones are returned.
+func (nr *NotificationRepository) GetByUser(ctx context.Context, userID uint, unread bool, pg page.Request) ([]notification.Notification, string, error) {
+	q := `
+	SELECT id, user_id, type, actor_id, subject_id, post_id, reply_id, read_at, created_at
+		FROM notifications
+		WHERE user_id = $1
+			AND (NOT $2 OR read_at IS NULL)
+			AND ($4 = 0 OR (created_at, id) < ($3, $4))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $5;
+	`
+
+	rows, err := nr.Data.DB.QueryContext(ctx, q, userID, unread, pg.After.Time, pg.After.ID, pg.Limit+1)
+	if err != nil {
+		return nil, "", translate(err, "notification")
+	}
+
+	defer rows.Close()
+
+	var notifications []notification.Notification
+	for rows.Next() {
+		var n notification.Notification
+		var replyID sql.NullInt64
+		err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.ActorID, &n.SubjectID, &n.PostID,
+			&replyID, &n.ReadAt, &n.CreatedAt)
+		if err != nil {
+			return nil, "", err
+		}
+
+		n.ReplyID = uint(replyID.Int64)
+		notifications = append(notifications, n)
+	}
+
+	var next string
+	if len(notifications) > pg.Limit {
+		notifications = notifications[:pg.Limit]
+		last := notifications[pg.Limit-1]
+		next = page.Cursor{ID: last.ID, Time: last.CreatedAt}.Encode()
+	}
+
+	return notifications, next, nil
+}
+
+// CountUnread returns the number of unread notifications of the user.
+func (nr *NotificationRepository) CountUnread(ctx context.Context, userID uint) (int, error) {
+	q := `SELECT count(*) FROM notifications WHERE user_id = $1 AND read_at IS NULL;`
+
+	var count int
+	err := nr.Data.DB.QueryRowContext(ctx, q, userID).Scan(&count)
+	if err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
+
+// MarkRead marks a notification of the user as read.
+func (nr *NotificationRepository) MarkRead(ctx context.Context, userID, id uint) error {
+	q := `
+	UPDATE notifications set read_at=COALESCE(read_at, $1)
+		WHERE id=$2 AND user_id=$3;
+	`
+
+	res, err := nr.Data.DB.ExecContext(ctx, q, time.Now(), id, userID)
+	if err != nil {
+		return translate(err, "notification")
+	}
+
+	return affected(res, "notification")
+}
+
+// MarkAllRead marks every notification of the user as read.
+func (nr *NotificationRepository) MarkAllRead(ctx context.Context, userID uint) error {
+	q := `
+	UPDATE notifications set read_at=$1
+		WHERE user_id=$2 AND read_at IS NULL;
+	`
+
+	_, err := nr.Data.DB.ExecContext(ctx, q, time.Now(), userID)
+	if err != nil {
+		return translate(err, "notification")
+	}
+
+	return nil
+}
+
+// GetPreferences returns the notification preferences of the user.
+// Users that never changed them have every event enabled.
+func (nr *NotificationRepository) GetPreferences(ctx context.Context, userID uint) (notification.Preferences, error) {
+	q := `
+	SELECT reply, mention, post
+		FROM notification_preferences WHERE user_id = $1;
+	`
+
+	p := notification.Preferences{Reply: true, Mention: true, Post: true}
+	err := nr.Data.DB.QueryRowContext(ctx, q, userID).Scan(&p.Reply, &p.Mention, &p.Post)
+	if err != nil && err != sql.ErrNoRows {
+		return notification.Preferences{}, err
+	}
+
+	return p, nil
+}
+
+// UpdatePreferences stores the notification preferences of the user.
+func (nr *NotificationRepository) UpdatePreferences(ctx context.Context, userID uint, p notification.Preferences) error {
+	q := `
+	INSERT INTO notification_preferences (user_id, reply, mention, post, updated_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (user_id) DO UPDATE
+			SET reply=EXCLUDED.reply, mention=EXCLUDED.mention,
+				post=EXCLUDED.post, updated_at=EXCLUDED.updated_at;
+	`
+
+	_, err := nr.Data.DB.ExecContext(ctx, q, userID, p.Reply, p.Mention, p.Post, time.Now())
+	if err != nil {
+		return translate(err, "notification preferences")
+	}
+
+	return nil
+}
+
+// NotifyPost notifies a new post to the users enrolled in its subject
+// and to the users mentioned in its body.
+func (nr *NotificationRepository) NotifyPost(ctx context.Context, p post.Post) error {
+	q := `
+	INSERT INTO notifications (user_id, type, actor_id, subject_id, post_id, created_at)
+		SELECT e.user_id, 'post', $1::int, $2::int, $3::int, $4::timestamp
+		FROM enrollments e
+			LEFT JOIN notification_preferences np ON np.user_id = e.user_id
+		WHERE e.subject_id = $2
+			AND e.user_id <> $1
+			AND COALESCE(np.post, true);
+	`
+
+	tx, err := nr.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, q, p.UserID, p.SubjectId, p.ID, time.Now())
+	if err != nil {
+		return err
+	}
+
+	err = notifyMentions(ctx, tx, p.UserID, p.ID, 0, p.Body)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// NotifyReply notifies a new reply to the author of the post, to the
+// author of the reply answered, if any, and to the users mentioned in
+// its body.
+func (nr *NotificationRepository) NotifyReply(ctx context.Context, r reply.Reply) error {
+	qParent := `
+	INSERT INTO notifications (user_id, type, actor_id, subject_id, post_id, reply_id, created_at)
+		SELECT pr.user_id, 'reply', $1::int, p.subject_id, p.id, $2::int, $3::timestamp
+		FROM replies pr
+			JOIN posts p ON p.id = pr.post_id
+			LEFT JOIN notification_preferences np ON np.user_id = pr.user_id
+		WHERE pr.id = $4
+			AND pr.deleted_at IS NULL
+			AND pr.user_id NOT IN ($1, p.user_id)
+			AND COALESCE(np.reply, true);
+	`
+	q := `
+	INSERT INTO notifications (user_id, type, actor_id, subject_id, post_id, reply_id, created_at)
+		SELECT p.user_id, 'reply', $1::int, p.subject_id, p.id, $2::int, $3::timestamp
+		FROM posts p
+			LEFT JOIN notification_preferences np ON np.user_id = p.user_id
+		WHERE p.id = $4
+			AND p.user_id <> $1
+			AND COALESCE(np.reply, true);
+	`
+
+	tx, err := nr.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, q, r.UserID, r.ID, time.Now(), r.PostId)
+	if err != nil {
+		return err
+	}
+
+	if r.ParentID != 0 {
+		_, err = tx.ExecContext(ctx, qParent, r.UserID, r.ID, time.Now(), r.ParentID)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = notifyMentions(ctx, tx, r.UserID, r.PostId, r.ID, r.Body)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// notifyMentions notifies the users mentioned in the body of a post or,
+// if replyID is not 0, of a reply.
+func notifyMentions(ctx context.Context, tx *sql.Tx, actorID, postID, replyID uint, body string) error {
+	usernames := notification.Mentions(body)
+	if len(usernames) == 0 {
+		return nil
+	}
+
+	q := `
+	INSERT INTO notifications (user_id, type, actor_id, subject_id, post_id, reply_id, created_at)
+		SELECT u.id, 'mention', $1::int, p.subject_id, p.id, $3::int, $4::timestamp
+		FROM users u
+			JOIN posts p ON p.id = $2
+			LEFT JOIN notification_preferences np ON np.user_id = u.id
+		WHERE u.username = ANY($5)
+			AND u.id <> $1
+			AND u.deleted_at IS NULL
+			AND COALESCE(np.mention, true);
+	`
+
+	_, err := tx.ExecContext(ctx, q, actorID, postID, nullID(replyID), time.Now(), pq.Array(usernames))
+	return err
+}
+
+// nullID returns nil for the id 0, so it is stored as NULL.
+func nullID(id uint) interface{} {
+	if id == 0 {
+		return nil
+	}
+
+	return id
+}
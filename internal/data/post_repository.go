@@ -2,9 +2,13 @@ package data
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/markdown"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/post"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/revision"
 )
 
 // PostRepository manages the operations with the database that
@@ -13,16 +17,19 @@ type PostRepository struct {
 	Data *Data
 }
 
-// GetAll returns all posts.
-func (pr *PostRepository) GetAll(ctx context.Context) ([]post.Post, error) {
+// GetAll returns a page of posts.
+func (pr *PostRepository) GetAll(ctx context.Context, pg page.Request) ([]post.Post, string, error) {
 	q := `
-	SELECT id, title, category, body, user_id, subject_id, created_at, updated_at
-		FROM posts;
+	SELECT id, title, category, body, body_html, user_id, subject_id, created_at, updated_at, upvotes, downvotes, score, solved
+		FROM posts
+		WHERE id > $1 AND deleted_at IS NULL AND hidden_at IS NULL
+		ORDER BY id
+		LIMIT $2;
 	`
 
-	rows, err := pr.Data.DB.QueryContext(ctx, q)
+	rows, err := pr.Data.DB.QueryContext(ctx, q, pg.After.ID, pg.Limit+1)
 	if err != nil {
-		return nil, err
+		return nil, "", translate(err, "post")
 	}
 
 	defer rows.Close()
@@ -30,57 +37,112 @@ func (pr *PostRepository) GetAll(ctx context.Context) ([]post.Post, error) {
 	var posts []post.Post
 	for rows.Next() {
 		var p post.Post
-		rows.Scan(&p.ID, &p.Title, &p.Category, &p.Body, &p.UserID, &p.SubjectId,
-			&p.CreatedAt, &p.UpdatedAt)
+		var html sql.NullString
+		rows.Scan(&p.ID, &p.Title, &p.Category, &p.Body, &html, &p.UserID, &p.SubjectId,
+			&p.CreatedAt, &p.UpdatedAt, &p.Upvotes, &p.Downvotes, &p.Score, &p.Solved)
+		p.BodyHTML = bodyHTML(html, p.Body)
 		posts = append(posts, p)
 	}
 
-	return posts, nil
+	var next string
+	if len(posts) > pg.Limit {
+		posts = posts[:pg.Limit]
+		next = page.Cursor{ID: posts[pg.Limit-1].ID}.Encode()
+	}
+
+	return posts, next, nil
 }
 
 // GetOne returns one post by id.
 func (pr *PostRepository) GetOne(ctx context.Context, id uint) (post.Post, error) {
 	q := `
-	SELECT id, title, category, body, user_id, subject_id, created_at, updated_at
-		FROM posts WHERE id = $1;
+	SELECT id, title, category, body, body_html, user_id, subject_id, created_at, updated_at, upvotes, downvotes, score, solved,
+		accepted_reply_id, pinned_at IS NOT NULL, locked_at IS NOT NULL
+		FROM posts WHERE id = $1 AND deleted_at IS NULL AND hidden_at IS NULL;
 	`
 
 	row := pr.Data.DB.QueryRowContext(ctx, q, id)
 
 	var p post.Post
-	err := row.Scan(&p.ID, &p.Title, &p.Category, &p.Body, &p.UserID, &p.SubjectId,
-		&p.CreatedAt, &p.UpdatedAt)
+	var html sql.NullString
+	var acceptedReplyID sql.NullInt64
+	err := row.Scan(&p.ID, &p.Title, &p.Category, &p.Body, &html, &p.UserID, &p.SubjectId,
+		&p.CreatedAt, &p.UpdatedAt, &p.Upvotes, &p.Downvotes, &p.Score, &p.Solved, &acceptedReplyID,
+		&p.Pinned, &p.Locked)
 	if err != nil {
-		return post.Post{}, err
+		return post.Post{}, translate(err, "post")
 	}
 
+	p.BodyHTML = bodyHTML(html, p.Body)
+	p.AcceptedReplyID = uint(acceptedReplyID.Int64)
+
 	return p, nil
 }
 
-// GetBySubject returns all subject posts.
-func (pr *PostRepository) GetBySubject(ctx context.Context, subjectID uint, order string) ([]post.Post, error) {
-	q_created := `
-	SELECT id, user_id, title, category, created_at, updated_at
-		FROM posts
-		WHERE subject_id = $1
-		ORDER BY created_at DESC;
-	`
-	q_updated := `
-	SELECT id, user_id, title, category, created_at, updated_at
-		FROM posts
-		WHERE subject_id = $1
-		ORDER BY updated_at DESC;
-	`
+// GetBySubject returns a page of subject posts sorted by the order:
+// created, updated, top (score) or hot (score decayed by age). If
+// solved is not nil, only the solved or unsolved posts are returned.
+func (pr *PostRepository) GetBySubject(ctx context.Context, subjectID uint, order string, solved *bool, pg page.Request) ([]post.Post, string, error) {
 	var q string
-	if order == "created" {
-		q = q_created
-	} else { // order == "updated"
-		q = q_updated
+	var after interface{}
+	switch order {
+	case post.OrderCreated:
+		q = `
+		SELECT id, user_id, title, category, created_at, updated_at, upvotes, downvotes, score, hot, solved,
+			locked_at IS NOT NULL
+			FROM posts
+			WHERE subject_id = $1 AND deleted_at IS NULL AND hidden_at IS NULL
+				AND pinned_at IS NULL
+				AND ($5::boolean IS NULL OR solved = $5)
+				AND ($3 = 0 OR (created_at, id) < ($2, $3))
+			ORDER BY created_at DESC, id DESC
+			LIMIT $4;
+		`
+		after = pg.After.Time
+	case post.OrderTop:
+		q = `
+		SELECT id, user_id, title, category, created_at, updated_at, upvotes, downvotes, score, hot, solved,
+			locked_at IS NOT NULL
+			FROM posts
+			WHERE subject_id = $1 AND deleted_at IS NULL AND hidden_at IS NULL
+				AND pinned_at IS NULL
+				AND ($5::boolean IS NULL OR solved = $5)
+				AND ($3 = 0 OR (score, id) < ($2, $3))
+			ORDER BY score DESC, id DESC
+			LIMIT $4;
+		`
+		after = int(pg.After.Score)
+	case post.OrderHot:
+		q = `
+		SELECT id, user_id, title, category, created_at, updated_at, upvotes, downvotes, score, hot, solved,
+			locked_at IS NOT NULL
+			FROM posts
+			WHERE subject_id = $1 AND deleted_at IS NULL AND hidden_at IS NULL
+				AND pinned_at IS NULL
+				AND ($5::boolean IS NULL OR solved = $5)
+				AND ($3 = 0 OR (hot, id) < ($2, $3))
+			ORDER BY hot DESC, id DESC
+			LIMIT $4;
+		`
+		after = pg.After.Score
+	default: // post.OrderUpdated
+		q = `
+		SELECT id, user_id, title, category, created_at, updated_at, upvotes, downvotes, score, hot, solved,
+			locked_at IS NOT NULL
+			FROM posts
+			WHERE subject_id = $1 AND deleted_at IS NULL AND hidden_at IS NULL
+				AND pinned_at IS NULL
+				AND ($5::boolean IS NULL OR solved = $5)
+				AND ($3 = 0 OR (updated_at, id) < ($2, $3))
+			ORDER BY updated_at DESC, id DESC
+			LIMIT $4;
+		`
+		after = pg.After.Time
 	}
 
-	rows, err := pr.Data.DB.QueryContext(ctx, q, subjectID)
+	rows, err := pr.Data.DB.QueryContext(ctx, q, subjectID, after, pg.After.ID, pg.Limit+1, solved)
 	if err != nil {
-		return nil, err
+		return nil, "", translate(err, "post")
 	}
 
 	defer rows.Close()
@@ -89,49 +151,123 @@ func (pr *PostRepository) GetBySubject(ctx context.Context, subjectID uint, orde
 	for rows.Next() {
 		var p post.Post
 		rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Category,
-			&p.CreatedAt, &p.UpdatedAt)
+			&p.CreatedAt, &p.UpdatedAt, &p.Upvotes, &p.Downvotes, &p.Score, &p.Hot, &p.Solved, &p.Locked)
 		posts = append(posts, p)
 	}
 
-	return posts, nil
+	var next string
+	if len(posts) > pg.Limit {
+		posts = posts[:pg.Limit]
+		last := posts[pg.Limit-1]
+		c := page.Cursor{ID: last.ID}
+		switch order {
+		case post.OrderCreated:
+			c.Time = last.CreatedAt
+		case post.OrderTop:
+			c.Score = float64(last.Score)
+		case post.OrderHot:
+			c.Score = last.Hot
+		default:
+			c.Time = last.UpdatedAt
+		}
+
+		next = c.Encode()
+	}
+
+	// the pinned posts go first, only in the first page.
+	if pg.After.ID == 0 {
+		pinned, err := pr.getPinned(ctx, subjectID, solved)
+		if err != nil {
+			return nil, "", err
+		}
+
+		posts = append(pinned, posts...)
+	}
+
+	return posts, next, nil
 }
 
-// GetByUser returns all user posts.
-func (pr *PostRepository) GetByUser(ctx context.Context, userID uint) ([]post.Post, error) {
+// getPinned returns the pinned posts of the subject, the last pinned
+// first.
+func (pr *PostRepository) getPinned(ctx context.Context, subjectID uint, solved *bool) ([]post.Post, error) {
 	q := `
-	SELECT id, title, category, body, user_id, subject_id, created_at, updated_at
+	SELECT id, user_id, title, category, created_at, updated_at, upvotes, downvotes, score, solved,
+		locked_at IS NOT NULL
 		FROM posts
-		WHERE user_id = $1;
+		WHERE subject_id = $1 AND deleted_at IS NULL AND hidden_at IS NULL
+			AND pinned_at IS NOT NULL
+			AND ($2::boolean IS NULL OR solved = $2)
+		ORDER BY pinned_at DESC, id DESC;
 	`
 
-	rows, err := pr.Data.DB.QueryContext(ctx, q, userID)
+	rows, err := pr.Data.DB.QueryContext(ctx, q, subjectID, solved)
 	if err != nil {
 		return nil, err
 	}
 
 	defer rows.Close()
 
+	var posts []post.Post
+	for rows.Next() {
+		p := post.Post{SubjectId: subjectID, Pinned: true}
+		err := rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Category, &p.CreatedAt, &p.UpdatedAt,
+			&p.Upvotes, &p.Downvotes, &p.Score, &p.Solved, &p.Locked)
+		if err != nil {
+			return nil, err
+		}
+
+		posts = append(posts, p)
+	}
+
+	return posts, rows.Err()
+}
+
+// GetByUser returns a page of user posts.
+func (pr *PostRepository) GetByUser(ctx context.Context, userID uint, pg page.Request) ([]post.Post, string, error) {
+	q := `
+	SELECT id, title, category, body, body_html, user_id, subject_id, created_at, updated_at, upvotes, downvotes, score, solved
+		FROM posts
+		WHERE user_id = $1 AND deleted_at IS NULL AND hidden_at IS NULL
+			AND ($3 = 0 OR (created_at, id) < ($2, $3))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4;
+	`
+
+	rows, err := pr.Data.DB.QueryContext(ctx, q, userID, pg.After.Time, pg.After.ID, pg.Limit+1)
+	if err != nil {
+		return nil, "", translate(err, "post")
+	}
+
+	defer rows.Close()
+
 	var posts []post.Post
 	for rows.Next() {
 		var p post.Post
-		rows.Scan(&p.ID, &p.Title, &p.Category, &p.Body, &p.UserID, &p.SubjectId,
-			&p.CreatedAt, &p.UpdatedAt)
+		var html sql.NullString
+		rows.Scan(&p.ID, &p.Title, &p.Category, &p.Body, &html, &p.UserID, &p.SubjectId,
+			&p.CreatedAt, &p.UpdatedAt, &p.Upvotes, &p.Downvotes, &p.Score, &p.Solved)
+		p.BodyHTML = bodyHTML(html, p.Body)
 		posts = append(posts, p)
 	}
 
-	return posts, nil
+	return pagePosts(posts, pg)
 }
 
-func (pr *PostRepository) GetByCategory(ctx context.Context, subjectID uint, category string) ([]post.Post, error) {
+// GetByCategory returns a page of subject posts of a category.
+func (pr *PostRepository) GetByCategory(ctx context.Context, subjectID uint, category string, pg page.Request) ([]post.Post, string, error) {
 	q := `
-	SELECT id, user_id, title, category, created_at, updated_at
+	SELECT id, user_id, title, category, created_at, updated_at, upvotes, downvotes, score, solved
 	FROM posts
-	WHERE subject_id = $1 AND category LIKE $2;
+	WHERE subject_id = $1 AND category LIKE $2 AND deleted_at IS NULL AND hidden_at IS NULL
+		AND ($4 = 0 OR (created_at, id) < ($3, $4))
+	ORDER BY created_at DESC, id DESC
+	LIMIT $5;
 	`
 
-	rows, err := pr.Data.DB.QueryContext(ctx, q, subjectID, category)
+	rows, err := pr.Data.DB.QueryContext(ctx, q, subjectID, category,
+		pg.After.Time, pg.After.ID, pg.Limit+1)
 	if err != nil {
-		return nil, err
+		return nil, "", translate(err, "post")
 	}
 
 	defer rows.Close()
@@ -139,23 +275,28 @@ func (pr *PostRepository) GetByCategory(ctx context.Context, subjectID uint, cat
 	var posts []post.Post
 	for rows.Next() {
 		var p post.Post
-		rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Category, &p.CreatedAt, &p.UpdatedAt)
+		rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Category, &p.CreatedAt, &p.UpdatedAt, &p.Upvotes, &p.Downvotes, &p.Score, &p.Solved)
 		posts = append(posts, p)
 	}
 
-	return posts, nil
+	return pagePosts(posts, pg)
 }
 
-func (pr *PostRepository) GetByTitle(ctx context.Context, subjectID uint, title string) ([]post.Post, error) {
+// GetByTitle returns a page of subject posts whose title starts with title.
+func (pr *PostRepository) GetByTitle(ctx context.Context, subjectID uint, title string, pg page.Request) ([]post.Post, string, error) {
 	q := `
-	SELECT id, user_id, title, category, created_at, updated_at
+	SELECT id, user_id, title, category, created_at, updated_at, upvotes, downvotes, score, solved
 	FROM posts
-	WHERE subject_id = $1 AND title LIKE $2;
+	WHERE subject_id = $1 AND title LIKE $2 AND deleted_at IS NULL AND hidden_at IS NULL
+		AND ($4 = 0 OR (created_at, id) < ($3, $4))
+	ORDER BY created_at DESC, id DESC
+	LIMIT $5;
 	`
 	title = title + "%"
-	rows, err := pr.Data.DB.QueryContext(ctx, q, subjectID, title)
+	rows, err := pr.Data.DB.QueryContext(ctx, q, subjectID, title,
+		pg.After.Time, pg.After.ID, pg.Limit+1)
 	if err != nil {
-		return nil, err
+		return nil, "", translate(err, "post")
 	}
 
 	defer rows.Close()
@@ -163,78 +304,293 @@ func (pr *PostRepository) GetByTitle(ctx context.Context, subjectID uint, title
 	var posts []post.Post
 	for rows.Next() {
 		var p post.Post
-		rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Category, &p.CreatedAt, &p.UpdatedAt)
+		rows.Scan(&p.ID, &p.UserID, &p.Title, &p.Category, &p.CreatedAt, &p.UpdatedAt, &p.Upvotes, &p.Downvotes, &p.Score, &p.Solved)
 		posts = append(posts, p)
 	}
 
-	return posts, nil
+	return pagePosts(posts, pg)
 }
 
-// Create adds a new post.
+// GetFeed returns a page of the posts of the subjects the user is
+// enrolled in, ordered by their last activity: the last update of the
+// post or its last reply.
+func (pr *PostRepository) GetFeed(ctx context.Context, userID uint, pg page.Request) ([]post.Post, string, error) {
+	q := `
+	SELECT id, user_id, subject_id, title, category, created_at, updated_at,
+			upvotes, downvotes, score, solved, activity_at
+		FROM (
+			SELECT p.id, p.user_id, p.subject_id, p.title, p.category, p.created_at, p.updated_at,
+				p.upvotes, p.downvotes, p.score, p.solved,
+				GREATEST(p.updated_at, (SELECT max(r.created_at) FROM replies r WHERE r.post_id = p.id AND r.deleted_at IS NULL AND r.hidden_at IS NULL)) AS activity_at
+			FROM posts p
+				JOIN enrollments e ON e.subject_id = p.subject_id
+			WHERE e.user_id = $1 AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+		) feed
+		WHERE $3 = 0 OR (activity_at, id) < ($2, $3)
+		ORDER BY activity_at DESC, id DESC
+		LIMIT $4;
+	`
+
+	rows, err := pr.Data.DB.QueryContext(ctx, q, userID, pg.After.Time, pg.After.ID, pg.Limit+1)
+	if err != nil {
+		return nil, "", translate(err, "post")
+	}
+
+	defer rows.Close()
+
+	var posts []post.Post
+	for rows.Next() {
+		var p post.Post
+		var activityAt time.Time
+		rows.Scan(&p.ID, &p.UserID, &p.SubjectId, &p.Title, &p.Category,
+			&p.CreatedAt, &p.UpdatedAt, &p.Upvotes, &p.Downvotes, &p.Score, &p.Solved, &activityAt)
+		p.ActivityAt = &activityAt
+		posts = append(posts, p)
+	}
+
+	var next string
+	if len(posts) > pg.Limit {
+		posts = posts[:pg.Limit]
+		last := posts[pg.Limit-1]
+		next = page.Cursor{ID: last.ID, Time: *last.ActivityAt}.Encode()
+	}
+
+	return posts, next, nil
+}
+
+// pagePosts trims the extra post fetched to know if there is a next
+// page of posts ordered by creation date and returns its cursor.
+func pagePosts(posts []post.Post, pg page.Request) ([]post.Post, string, error) {
+	if len(posts) <= pg.Limit {
+		return posts, "", nil
+	}
+
+	posts = posts[:pg.Limit]
+	last := posts[pg.Limit-1]
+
+	return posts, page.Cursor{ID: last.ID, Time: last.CreatedAt}.Encode(), nil
+}
+
+// Accept marks the reply as the accepted answer of the post. The reply
+// must belong to the post.
+func (pr *PostRepository) Accept(ctx context.Context, id, replyID uint) error {
+	q := `
+	UPDATE posts set accepted_reply_id=$1
+		WHERE id=$2 AND deleted_at IS NULL AND hidden_at IS NULL
+			AND EXISTS (SELECT 1 FROM replies WHERE id=$1 AND post_id=$2 AND deleted_at IS NULL AND hidden_at IS NULL);
+	`
+
+	res, err := pr.Data.DB.ExecContext(ctx, q, replyID, id)
+	if err != nil {
+		return translate(err, "post")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+
+	if n == 0 {
+		return post.ErrReplyNotInPost
+	}
+
+	return nil
+}
+
+// Unaccept removes the accepted answer of the post.
+func (pr *PostRepository) Unaccept(ctx context.Context, id uint) error {
+	q := `UPDATE posts set accepted_reply_id=NULL WHERE id=$1 AND deleted_at IS NULL AND hidden_at IS NULL;`
+
+	res, err := pr.Data.DB.ExecContext(ctx, q, id)
+	if err != nil {
+		return translate(err, "post")
+	}
+
+	return affected(res, "post")
+}
+
+// Create adds a new post, with its content as the first revision. The
+// subject must not be deleted.
 func (pr *PostRepository) Create(ctx context.Context, p *post.Post) error {
 	q := `
-	INSERT INTO posts (user_id, subject_id, title, category, body, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	INSERT INTO posts (user_id, subject_id, title, category, body, body_html, created_at, updated_at)
+		SELECT $1, $2, $3, $4, $5, $6, $7, $8
+		WHERE EXISTS (SELECT 1 FROM subjects WHERE id = $2 AND deleted_at IS NULL)
 		RETURNING id;
 	`
 
-	stmt, err := pr.Data.DB.PrepareContext(ctx, q)
+	var err error
+	p.BodyHTML, err = markdown.Render(p.Body)
+	if err != nil {
+		return err
+	}
+
+	tx, err := pr.Data.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	row := stmt.QueryRowContext(ctx, p.UserID, p.SubjectId, p.Title, p.Category,
-		p.Body, time.Now(), time.Now())
+	now := time.Now()
+	err = tx.QueryRowContext(ctx, q, p.UserID, p.SubjectId, p.Title, p.Category,
+		p.Body, p.BodyHTML, now, now).Scan(&p.ID)
+	if err == sql.ErrNoRows {
+		return errNoReference("post")
+	}
 
-	err = row.Scan(&p.ID)
+	if err != nil {
+		return translate(err, "post")
+	}
+
+	err = addRevision(ctx, tx, revision.TargetPost, p.ID, p.UserID)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return tx.Commit()
 }
 
-// Update updates a post by id.
-func (pr *PostRepository) Update(ctx context.Context, id uint, p post.Post) error {
+// Update updates a post by id and adds the new content as a revision
+// written by the editor.
+func (pr *PostRepository) Update(ctx context.Context, id, editorID uint, p post.Post) error {
 	q := `
-	UPDATE posts set title=$1, category=$2, body=$3, updated_at=$4
-		WHERE id=$5;
+	UPDATE posts set title=$1, category=$2, body=$3, body_html=$4, updated_at=$5
+		WHERE id=$6 AND deleted_at IS NULL AND hidden_at IS NULL;
 	`
 
-	stmt, err := pr.Data.DB.PrepareContext(ctx, q)
+	html, err := markdown.Render(p.Body)
 	if err != nil {
 		return err
 	}
 
-	defer stmt.Close()
+	tx, err := pr.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
 
-	_, err = stmt.ExecContext(
-		ctx, p.Title, p.Category, p.Body, time.Now(), id,
+	res, err := tx.ExecContext(
+		ctx, q, p.Title, p.Category, p.Body, html, time.Now(), id,
 	)
+	if err != nil {
+		return translate(err, "post")
+	}
+
+	err = affected(res, "post")
 	if err != nil {
 		return err
 	}
 
-	return nil
+	err = addRevision(ctx, tx, revision.TargetPost, id, editorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// Delete removes a post by id.
-func (pr *PostRepository) Delete(ctx context.Context, id uint) error {
-	q := `DELETE FROM posts WHERE id=$1;`
+// Pin pins or unpins a post by id, the pinned posts go first in their
+// subject.
+func (pr *PostRepository) Pin(ctx context.Context, id uint, pinned bool) error {
+	q := `
+	UPDATE posts set pinned_at = CASE WHEN $1 THEN COALESCE(pinned_at, $2) END
+		WHERE id=$3 AND deleted_at IS NULL AND hidden_at IS NULL;
+	`
+
+	res, err := pr.Data.DB.ExecContext(ctx, q, pinned, time.Now(), id)
+	if err != nil {
+		return translate(err, "post")
+	}
+
+	return affected(res, "post")
+}
+
+// Lock locks or unlocks a post by id, the locked posts don't accept
+// new replies.
+func (pr *PostRepository) Lock(ctx context.Context, id uint, locked bool) error {
+	q := `
+	UPDATE posts set locked_at = CASE WHEN $1 THEN COALESCE(locked_at, $2) END
+		WHERE id=$3 AND deleted_at IS NULL AND hidden_at IS NULL;
+	`
+
+	res, err := pr.Data.DB.ExecContext(ctx, q, locked, time.Now(), id)
+	if err != nil {
+		return translate(err, "post")
+	}
+
+	return affected(res, "post")
+}
 
-	stmt, err := pr.Data.DB.PrepareContext(ctx, q)
+// Delete marks a post by id as deleted. The deleted posts are hidden
+// until they are restored or purged.
+func (pr *PostRepository) Delete(ctx context.Context, id uint) error {
+	tx, err := pr.Data.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	_, err = stmt.ExecContext(ctx, id)
+	err = deletePost(ctx, tx, id)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	return tx.Commit()
+}
+
+// deletePost marks a post by id as deleted in the transaction.
+func deletePost(ctx context.Context, tx *sql.Tx, id uint) error {
+	q := `UPDATE posts set deleted_at=$1 WHERE id=$2 AND deleted_at IS NULL;`
+
+	res, err := tx.ExecContext(ctx, q, time.Now(), id)
+	if err != nil {
+		return translate(err, "post")
+	}
+
+	return affected(res, "post")
+}
+
+// Restore restores a deleted post by id. The posts of a deleted subject
+// can't be restored before the subject.
+func (pr *PostRepository) Restore(ctx context.Context, id uint) error {
+	qSubject := `
+	SELECT s.deleted_at IS NOT NULL
+		FROM posts p
+			JOIN subjects s ON s.id = p.subject_id
+		WHERE p.id = $1 AND p.deleted_at IS NOT NULL;
+	`
+	q := `UPDATE posts set deleted_at=NULL WHERE id=$1 AND deleted_at IS NOT NULL;`
+
+	var subjectDeleted bool
+	err := pr.Data.DB.QueryRowContext(ctx, qSubject, id).Scan(&subjectDeleted)
+	if err != nil {
+		return translate(err, "post")
+	}
+
+	if subjectDeleted {
+		return post.ErrSubjectDeleted
+	}
+
+	res, err := pr.Data.DB.ExecContext(ctx, q, id)
+	if err != nil {
+		return translate(err, "post")
+	}
+
+	return affected(res, "post")
+}
+
+// Purge removes the posts deleted before the time, with their replies,
+// and returns how many were removed.
+func (pr *PostRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	q := `DELETE FROM posts WHERE deleted_at < $1;`
+
+	res, err := pr.Data.DB.ExecContext(ctx, q, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
 }
@@ -1,30 +1,29 @@
 package data
 
 import (
+	"context"
 	"database/sql"
-	"io/ioutil"
 	"os"
 
+	"github.com/orlmonteverde/go-postgres-microblog/database"
+
 	// registering database driver
 	_ "github.com/lib/pq"
 )
 
-func getConnection() (*sql.DB, error) {
+// Connect opens a new connection pool to the database in DATABASE_URI.
+func Connect() (*sql.DB, error) {
 	uri := os.Getenv("DATABASE_URI")
 	return sql.Open("postgres", uri)
 }
 
-// MakeMigration creates all the tables in the database
+// MakeMigration applies every pending migration to the database.
 func MakeMigration(db *sql.DB) error {
-	b, err := ioutil.ReadFile("./database/models.sql")
-	if err != nil {
-		return err
-	}
-
-	rows, err := db.Query(string(b))
-	if err != nil {
-		return err
+	m := &Migrator{
+		DB:         db,
+		Migrations: database.Migrations(),
 	}
 
-	return rows.Close()
+	_, err := m.Up(context.Background())
+	return err
 }
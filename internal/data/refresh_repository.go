@@ -0,0 +1,121 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/refresh"
+)
+
+// RefreshRepository manages the operations with the database that
+// correspond to the refresh token model.
+type RefreshRepository struct {
+	Data *Data
+}
+
+// GetByHash returns one refresh token by hash.
+func (rr *RefreshRepository) GetByHash(ctx context.Context, hash string) (refresh.Token, error) {
+	q := `
+	SELECT id, user_id, family_id, token_hash, expires_at, revoked_at, created_at
+		FROM refresh_tokens WHERE token_hash = $1;
+	`
+
+	row := rr.Data.DB.QueryRowContext(ctx, q, hash)
+
+	var t refresh.Token
+	err := row.Scan(&t.ID, &t.UserID, &t.FamilyID, &t.Hash, &t.ExpiresAt,
+		&t.RevokedAt, &t.CreatedAt)
+	if err != nil {
+		return refresh.Token{}, translate(err, "refresh token")
+	}
+
+	return t, nil
+}
+
+// Create adds a new refresh token.
+func (rr *RefreshRepository) Create(ctx context.Context, t *refresh.Token) error {
+	q := `
+	INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id;
+	`
+
+	t.CreatedAt = time.Now()
+	row := rr.Data.DB.QueryRowContext(ctx, q, t.UserID, t.FamilyID, t.Hash,
+		t.ExpiresAt, t.CreatedAt)
+
+	err := row.Scan(&t.ID)
+	if err != nil {
+		return translate(err, "refresh token")
+	}
+
+	return nil
+}
+
+// Rotate revokes the refresh token by id and adds its replacement.
+// It returns refresh.ErrAlreadyRevoked if the token was already used.
+func (rr *RefreshRepository) Rotate(ctx context.Context, id uint, t *refresh.Token) error {
+	qRevoke := `
+	UPDATE refresh_tokens set revoked_at=$1
+		WHERE id=$2 AND revoked_at IS NULL;
+	`
+	qCreate := `
+	INSERT INTO refresh_tokens (user_id, family_id, token_hash, expires_at, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id;
+	`
+
+	tx, err := rr.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return translate(err, "refresh token")
+	}
+
+	defer tx.Rollback()
+
+	res, err := tx.ExecContext(ctx, qRevoke, time.Now(), id)
+	if err != nil {
+		return translate(err, "refresh token")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return translate(err, "refresh token")
+	}
+
+	if n == 0 {
+		return refresh.ErrAlreadyRevoked
+	}
+
+	t.CreatedAt = time.Now()
+	row := tx.QueryRowContext(ctx, qCreate, t.UserID, t.FamilyID, t.Hash,
+		t.ExpiresAt, t.CreatedAt)
+
+	err = row.Scan(&t.ID)
+	if err != nil {
+		return translate(err, "refresh token")
+	}
+
+	return tx.Commit()
+}
+
+// RevokeFamily revokes every refresh token of a family.
+func (rr *RefreshRepository) RevokeFamily(ctx context.Context, familyID string) error {
+	q := `
+	UPDATE refresh_tokens set revoked_at=$1
+		WHERE family_id=$2 AND revoked_at IS NULL;
+	`
+
+	stmt, err := rr.Data.DB.PrepareContext(ctx, q)
+	if err != nil {
+		return translate(err, "refresh token")
+	}
+
+	defer stmt.Close()
+
+	_, err = stmt.ExecContext(ctx, time.Now(), familyID)
+	if err != nil {
+		return translate(err, "refresh token")
+	}
+
+	return nil
+}
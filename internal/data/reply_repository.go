@@ -2,7 +2,12 @@ package data
 
 import (
 	"context"
+	"database/sql"
+	"github.com/lib/pq"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/markdown"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/reply"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/revision"
 	"time"
 )
 
@@ -12,18 +17,167 @@ type ReplyRepository struct {
 	Data *Data
 }
 
-// GetByPost returns all post replies.
-func (rr *ReplyRepository) GetByPost(ctx context.Context, postID uint) ([]reply.Reply, error) {
+// GetOne returns one reply by id. The deleted replies keep their
+// author, so the policies can be checked, but the replies of the
+// deleted posts are not found.
+func (rr *ReplyRepository) GetOne(ctx context.Context, id uint) (reply.Reply, error) {
 	q := `
-	SELECT id, user_id, body, created_at, updated_at
-		FROM replies
-		WHERE post_id = $1
-		ORDER BY created_at;
+	SELECT r.id, r.user_id, r.post_id, p.subject_id, r.body, r.body_html, r.created_at, r.updated_at,
+		r.upvotes, r.downvotes, r.score, COALESCE(r.parent_id, 0), r.depth, r.path,
+		r.deleted_at IS NOT NULL, r.hidden_at IS NOT NULL
+		FROM replies r
+			JOIN posts p ON p.id = r.post_id
+		WHERE r.id = $1 AND p.deleted_at IS NULL AND p.hidden_at IS NULL;
 	`
 
-	rows, err := rr.Data.DB.QueryContext(ctx, q, postID)
+	row := rr.Data.DB.QueryRowContext(ctx, q, id)
+
+	var r reply.Reply
+	var html sql.NullString
+	err := row.Scan(&r.ID, &r.UserID, &r.PostId, &r.SubjectID, &r.Body, &html, &r.CreatedAt, &r.UpdatedAt,
+		&r.Upvotes, &r.Downvotes, &r.Score, &r.ParentID, &r.Depth, (*pq.Int64Array)(&r.Path), &r.Deleted, &r.Hidden)
 	if err != nil {
-		return nil, err
+		return reply.Reply{}, translate(err, "reply")
+	}
+
+	r.BodyHTML = bodyHTML(html, r.Body)
+
+	return r, nil
+}
+
+// GetByPost returns a page of the top-level replies of the post sorted
+// by the order: the oldest first, top (score) or hot (score decayed by
+// age). The first page starts with the accepted answer, if any, besides
+// the limit. The answers of each reply are returned by GetBranch.
+func (rr *ReplyRepository) GetByPost(ctx context.Context, postID uint, order string, pg page.Request) ([]reply.Reply, string, error) {
+	var q string
+	var after interface{}
+	switch order {
+	case reply.OrderTop:
+		q = `
+		SELECT r.id, r.user_id, r.body, r.body_html, r.created_at, r.updated_at, r.upvotes, r.downvotes, r.score, r.hot,
+			r.depth, r.path, r.deleted_at IS NOT NULL, r.hidden_at IS NOT NULL,
+			(SELECT count(*) FROM replies c WHERE c.parent_id = r.id)
+			FROM replies r
+				JOIN posts p ON p.id = r.post_id
+			WHERE r.post_id = $1 AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+				AND r.depth = 0
+				AND r.id IS DISTINCT FROM p.accepted_reply_id
+				AND ($3 = 0 OR (r.score, r.id) < ($2, $3))
+			ORDER BY r.score DESC, r.id DESC
+			LIMIT $4;
+		`
+		after = int(pg.After.Score)
+	case reply.OrderHot:
+		q = `
+		SELECT r.id, r.user_id, r.body, r.body_html, r.created_at, r.updated_at, r.upvotes, r.downvotes, r.score, r.hot,
+			r.depth, r.path, r.deleted_at IS NOT NULL, r.hidden_at IS NOT NULL,
+			(SELECT count(*) FROM replies c WHERE c.parent_id = r.id)
+			FROM replies r
+				JOIN posts p ON p.id = r.post_id
+			WHERE r.post_id = $1 AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+				AND r.depth = 0
+				AND r.id IS DISTINCT FROM p.accepted_reply_id
+				AND ($3 = 0 OR (r.hot, r.id) < ($2, $3))
+			ORDER BY r.hot DESC, r.id DESC
+			LIMIT $4;
+		`
+		after = pg.After.Score
+	default:
+		q = `
+		SELECT r.id, r.user_id, r.body, r.body_html, r.created_at, r.updated_at, r.upvotes, r.downvotes, r.score, r.hot,
+			r.depth, r.path, r.deleted_at IS NOT NULL, r.hidden_at IS NOT NULL,
+			(SELECT count(*) FROM replies c WHERE c.parent_id = r.id)
+			FROM replies r
+				JOIN posts p ON p.id = r.post_id
+			WHERE r.post_id = $1 AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+				AND r.depth = 0
+				AND r.id IS DISTINCT FROM p.accepted_reply_id
+				AND ($3 = 0 OR (r.created_at, r.id) > ($2, $3))
+			ORDER BY r.created_at, r.id
+			LIMIT $4;
+		`
+		after = pg.After.Time
+	}
+
+	rows, err := rr.Data.DB.QueryContext(ctx, q, postID, after, pg.After.ID, pg.Limit+1)
+	if err != nil {
+		return nil, "", translate(err, "reply")
+	}
+
+	defer rows.Close()
+
+	var replies []reply.Reply
+	for rows.Next() {
+		r := reply.Reply{PostId: postID}
+		var html sql.NullString
+		err := rows.Scan(&r.ID, &r.UserID, &r.Body, &html, &r.CreatedAt, &r.UpdatedAt,
+			&r.Upvotes, &r.Downvotes, &r.Score, &r.Hot,
+			&r.Depth, (*pq.Int64Array)(&r.Path), &r.Deleted, &r.Hidden, &r.ChildrenCount)
+		if err != nil {
+			return nil, "", err
+		}
+
+		r.BodyHTML = bodyHTML(html, r.Body)
+		if r.Deleted || r.Hidden {
+			r.Tombstone()
+		}
+
+		replies = append(replies, r)
+	}
+
+	var next string
+	if len(replies) > pg.Limit {
+		replies = replies[:pg.Limit]
+		last := replies[pg.Limit-1]
+		c := page.Cursor{ID: last.ID}
+		switch order {
+		case reply.OrderTop:
+			c.Score = float64(last.Score)
+		case reply.OrderHot:
+			c.Score = last.Hot
+		default:
+			c.Time = last.CreatedAt
+		}
+
+		next = c.Encode()
+	}
+
+	// the accepted answer goes first, only in the first page.
+	if pg.After.ID == 0 {
+		accepted, err := rr.getAccepted(ctx, postID)
+		if err != nil {
+			return nil, "", err
+		}
+
+		if accepted != nil {
+			replies = append([]reply.Reply{*accepted}, replies...)
+		}
+	}
+
+	return replies, next, nil
+}
+
+// GetBranch returns a page of the answers of the reply, at any depth,
+// in thread order: every reply is followed by its answers, the oldest
+// first.
+func (rr *ReplyRepository) GetBranch(ctx context.Context, id uint, pg page.Request) ([]reply.Reply, string, error) {
+	q := `
+	SELECT r.id, r.user_id, r.post_id, r.body, r.body_html, r.created_at, r.updated_at, r.upvotes, r.downvotes, r.score,
+		COALESCE(r.parent_id, 0), r.depth, r.path, r.deleted_at IS NOT NULL, r.hidden_at IS NOT NULL,
+		(SELECT count(*) FROM replies c WHERE c.parent_id = r.id)
+		FROM replies r
+			JOIN posts p ON p.id = r.post_id
+		WHERE r.path @> ARRAY[$1::int] AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+			AND r.id <> $1
+			AND ($2::int[] IS NULL OR r.path > $2::int[])
+		ORDER BY r.path
+		LIMIT $3;
+	`
+
+	rows, err := rr.Data.DB.QueryContext(ctx, q, id, pq.Int64Array(pg.After.Path), pg.Limit+1)
+	if err != nil {
+		return nil, "", translate(err, "reply")
 	}
 
 	defer rows.Close()
@@ -31,78 +185,308 @@ func (rr *ReplyRepository) GetByPost(ctx context.Context, postID uint) ([]reply.
 	var replies []reply.Reply
 	for rows.Next() {
 		var r reply.Reply
-		rows.Scan(&r.ID, &r.UserID, &r.Body, &r.CreatedAt, &r.UpdatedAt)
+		var html sql.NullString
+		err := rows.Scan(&r.ID, &r.UserID, &r.PostId, &r.Body, &html, &r.CreatedAt, &r.UpdatedAt,
+			&r.Upvotes, &r.Downvotes, &r.Score, &r.ParentID, &r.Depth, (*pq.Int64Array)(&r.Path),
+			&r.Deleted, &r.Hidden, &r.ChildrenCount)
+		if err != nil {
+			return nil, "", err
+		}
+
+		r.BodyHTML = bodyHTML(html, r.Body)
+		if r.Deleted || r.Hidden {
+			r.Tombstone()
+		}
+
 		replies = append(replies, r)
 	}
 
-	return replies, nil
+	var next string
+	if len(replies) > pg.Limit {
+		replies = replies[:pg.Limit]
+		last := replies[pg.Limit-1]
+		next = page.Cursor{ID: last.ID, Path: last.Path}.Encode()
+	}
+
+	return replies, next, nil
+}
+
+// getAccepted returns the accepted answer of the post, or nil if the
+// post has none.
+func (rr *ReplyRepository) getAccepted(ctx context.Context, postID uint) (*reply.Reply, error) {
+	q := `
+	SELECT r.id, r.user_id, r.body, r.body_html, r.created_at, r.updated_at, r.upvotes, r.downvotes, r.score,
+		COALESCE(r.parent_id, 0), r.depth, r.path,
+		(SELECT count(*) FROM replies c WHERE c.parent_id = r.id)
+		FROM replies r
+			JOIN posts p ON p.accepted_reply_id = r.id
+		WHERE p.id = $1 AND p.deleted_at IS NULL AND p.hidden_at IS NULL;
+	`
+
+	r := reply.Reply{PostId: postID, Accepted: true}
+	var html sql.NullString
+	err := rr.Data.DB.QueryRowContext(ctx, q, postID).Scan(&r.ID, &r.UserID, &r.Body, &html,
+		&r.CreatedAt, &r.UpdatedAt, &r.Upvotes, &r.Downvotes, &r.Score,
+		&r.ParentID, &r.Depth, (*pq.Int64Array)(&r.Path), &r.ChildrenCount)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	r.BodyHTML = bodyHTML(html, r.Body)
+	return &r, nil
 }
 
-// Create adds a new reply.
-func (rr *ReplyRepository) Create(ctx context.Context, reply *reply.Reply) error {
+// Create adds a new reply to a post that is not deleted nor locked. If
+// it answers another reply, the parent must be a reply of the same post
+// that is not deleted and the new reply can't be deeper than
+// reply.MaxDepth.
+func (rr *ReplyRepository) Create(ctx context.Context, r *reply.Reply) error {
+	qPost := `
+	SELECT id, locked_at IS NOT NULL
+		FROM posts
+		WHERE id = $1 AND deleted_at IS NULL AND hidden_at IS NULL
+		FOR SHARE;
+	`
+	qParent := `
+	SELECT post_id, depth, path, deleted_at IS NOT NULL OR hidden_at IS NOT NULL
+		FROM replies WHERE id = $1
+		FOR SHARE;
+	`
 	q := `
-	INSERT INTO replies (user_id, post_id, body, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5)
+	INSERT INTO replies (id, user_id, post_id, parent_id, depth, path, body, body_html, created_at, updated_at)
+		SELECT n.id, $1, $2, $3, $4, $5::int[] || n.id, $6, $7, $8, $9
+		FROM (SELECT nextval(pg_get_serial_sequence('replies', 'id'))::int AS id) n
 		RETURNING id;
 	`
 
-	stmt, err := rr.Data.DB.PrepareContext(ctx, q)
+	var err error
+	r.BodyHTML, err = markdown.Render(r.Body)
+	if err != nil {
+		return err
+	}
+
+	tx, err := rr.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var postID uint
+	var locked bool
+	err = tx.QueryRowContext(ctx, qPost, r.PostId).Scan(&postID, &locked)
+	if err == sql.ErrNoRows {
+		return errNoReference("reply")
+	}
+
 	if err != nil {
 		return err
 	}
 
-	defer stmt.Close()
+	if locked {
+		return reply.ErrPostLocked
+	}
+
+	path := []int64{}
+	if r.ParentID != 0 {
+		var deleted bool
+		err = tx.QueryRowContext(ctx, qParent, r.ParentID).Scan(&postID, &r.Depth, (*pq.Int64Array)(&path), &deleted)
+		if err == sql.ErrNoRows {
+			return reply.ErrParentNotFound
+		}
+
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case postID != r.PostId:
+			return reply.ErrParentNotInPost
+		case deleted:
+			return reply.ErrParentDeleted
+		case r.Depth >= reply.MaxDepth:
+			return reply.ErrTooDeep
+		}
 
-	row := stmt.QueryRowContext(ctx, reply.UserID, reply.PostId, reply.Body,
-		time.Now(), time.Now())
+		r.Depth++
+	}
+
+	now := time.Now()
+	err = tx.QueryRowContext(ctx, q, r.UserID, r.PostId, nullID(r.ParentID), r.Depth,
+		pq.Array(path), r.Body, r.BodyHTML, now, now).Scan(&r.ID)
+	if err != nil {
+		return translate(err, "reply")
+	}
 
-	err = row.Scan(&reply.ID)
+	err = addRevision(ctx, tx, revision.TargetReply, r.ID, r.UserID)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	r.Path = append(path, int64(r.ID))
+	r.CreatedAt = now
+	r.UpdatedAt = now
+
+	return tx.Commit()
 }
 
-// Update updates a reply by id.
-func (rr *ReplyRepository) Update(ctx context.Context, id uint, reply reply.Reply) error {
+// Update updates a reply by id and adds the new content as a revision
+// written by the editor. The deleted replies can't be updated.
+func (rr *ReplyRepository) Update(ctx context.Context, id, editorID uint, reply reply.Reply) error {
 	q := `
-	UPDATE replies set body=$1, updated_at=$2
-		WHERE id=$3;
+	UPDATE replies set body=$1, body_html=$2, updated_at=$3
+		WHERE id=$4 AND deleted_at IS NULL AND hidden_at IS NULL;
 	`
 
-	stmt, err := rr.Data.DB.PrepareContext(ctx, q)
+	html, err := markdown.Render(reply.Body)
+	if err != nil {
+		return err
+	}
+
+	tx, err := rr.Data.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	defer stmt.Close()
+	defer tx.Rollback()
 
-	_, err = stmt.ExecContext(
-		ctx, reply.Body, time.Now(), id,
+	res, err := tx.ExecContext(
+		ctx, q, reply.Body, html, time.Now(), id,
 	)
+	if err != nil {
+		return translate(err, "reply")
+	}
+
+	err = affected(res, "reply")
 	if err != nil {
 		return err
 	}
 
-	return nil
+	err = addRevision(ctx, tx, revision.TargetReply, id, editorID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
 }
 
-// Delete removes a reply by id.
+// Delete marks a reply by id as deleted. It is kept in the threads as
+// a tombstone, without body, until it is restored or purged, so its
+// answers are not lost.
 func (rr *ReplyRepository) Delete(ctx context.Context, id uint) error {
-	q := `DELETE FROM replies WHERE id=$1;`
+	tx, err := rr.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
 
-	stmt, err := rr.Data.DB.PrepareContext(ctx, q)
+	err = deleteReply(ctx, tx, id)
 	if err != nil {
 		return err
 	}
 
-	defer stmt.Close()
+	return tx.Commit()
+}
+
+// deleteReply marks a reply by id as deleted in the transaction.
+func deleteReply(ctx context.Context, tx *sql.Tx, id uint) error {
+	q := `UPDATE replies set deleted_at=$1 WHERE id=$2 AND deleted_at IS NULL;`
+	qUnaccept := `UPDATE posts set accepted_reply_id=NULL WHERE accepted_reply_id=$1;`
 
-	_, err = stmt.ExecContext(ctx, id)
+	res, err := tx.ExecContext(ctx, q, time.Now(), id)
+	if err != nil {
+		return translate(err, "reply")
+	}
+
+	err = affected(res, "reply")
 	if err != nil {
 		return err
 	}
 
-	return nil
+	// a tombstone can't be the accepted answer.
+	_, err = tx.ExecContext(ctx, qUnaccept, id)
+	return err
+}
+
+// Restore restores a deleted reply by id. The replies of a deleted post
+// can't be restored before the post, nor the ones already purged.
+func (rr *ReplyRepository) Restore(ctx context.Context, id uint) error {
+	qPost := `
+	SELECT p.deleted_at IS NOT NULL
+		FROM replies r
+			JOIN posts p ON p.id = r.post_id
+		WHERE r.id = $1 AND r.deleted_at IS NOT NULL AND r.body <> '';
+	`
+	q := `UPDATE replies set deleted_at=NULL WHERE id=$1 AND deleted_at IS NOT NULL AND body <> '';`
+
+	var postDeleted bool
+	err := rr.Data.DB.QueryRowContext(ctx, qPost, id).Scan(&postDeleted)
+	if err != nil {
+		return translate(err, "reply")
+	}
+
+	if postDeleted {
+		return reply.ErrPostDeleted
+	}
+
+	res, err := rr.Data.DB.ExecContext(ctx, q, id)
+	if err != nil {
+		return translate(err, "reply")
+	}
+
+	return affected(res, "reply")
+}
+
+// Purge removes the replies deleted before the time and returns how
+// many were removed. The ones with answers are kept as tombstones but
+// their body, files and revisions are removed.
+func (rr *ReplyRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	qAttachments := `
+	DELETE FROM attachments
+		WHERE reply_id IN (SELECT id FROM replies WHERE deleted_at < $1);
+	`
+	qRevisions := `
+	DELETE FROM revisions
+		WHERE reply_id IN (SELECT id FROM replies WHERE deleted_at < $1);
+	`
+	qClear := `
+	UPDATE replies set body='', body_html=''
+		WHERE deleted_at < $1 AND body <> '';
+	`
+	q := `
+	DELETE FROM replies r
+		WHERE r.deleted_at < $1
+			AND NOT EXISTS (SELECT 1 FROM replies c WHERE c.parent_id = r.id);
+	`
+
+	tx, err := rr.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	for _, q := range []string{qAttachments, qRevisions, qClear} {
+		_, err = tx.ExecContext(ctx, q, before)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, q, before)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
 }
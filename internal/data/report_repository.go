@@ -0,0 +1,500 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/report"
+)
+
+// reportAuthors are the queries of the author of each target that can
+// be reported, the user itself for the users.
+var reportAuthors = map[string]string{
+	report.TargetPost: `SELECT user_id FROM posts WHERE id = $1 AND deleted_at IS NULL;`,
+	report.TargetReply: `
+	SELECT r.user_id
+		FROM replies r
+			JOIN posts p ON p.id = r.post_id
+		WHERE r.id = $1 AND r.deleted_at IS NULL AND p.deleted_at IS NULL;
+	`,
+	report.TargetUser: `SELECT id FROM users WHERE id = $1 AND deleted_at IS NULL;`,
+}
+
+// hiddenTables are the tables of the targets that can be hidden.
+var hiddenTables = map[string]string{
+	report.TargetPost:  "posts",
+	report.TargetReply: "replies",
+}
+
+// caseColumns are the columns of the cases read by scanCase.
+const caseColumns = `c.id, c.target, COALESCE(c.post_id, c.reply_id, c.user_id), c.user_id, c.status,
+		COALESCE(c.assignee_id, 0), (SELECT count(*) FROM reports rp WHERE rp.case_id = c.id),
+		COALESCE(p.hidden_at, r.hidden_at) IS NOT NULL, c.created_at, c.updated_at, c.resolved_at
+		FROM moderation_cases c
+			LEFT JOIN posts p ON p.id = c.post_id
+			LEFT JOIN replies r ON r.id = c.reply_id`
+
+// ReportRepository manages the operations with the database that
+// correspond to the report model.
+type ReportRepository struct {
+	Data *Data
+}
+
+// Create adds the report to the open case of its target. The reports
+// of the deleted content or users are not allowed, nor the reports of
+// the own content.
+func (rr *ReportRepository) Create(ctx context.Context, rp *report.Report, threshold int) error {
+	qAuthor, ok := reportAuthors[rp.Target]
+	if !ok {
+		return apperror.Validation("invalid report target")
+	}
+
+	qCase := `
+	INSERT INTO moderation_cases (target, post_id, reply_id, user_id, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $5)
+		ON CONFLICT DO NOTHING;
+	`
+	qOpen := `
+	SELECT id FROM moderation_cases
+		WHERE target = $1 AND COALESCE(post_id, reply_id, user_id) = $2 AND status = 'open'
+		FOR UPDATE;
+	`
+	q := `
+	INSERT INTO reports (case_id, reporter_id, reason, details, created_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (case_id, reporter_id) DO NOTHING
+		RETURNING id;
+	`
+	qCount := `
+	UPDATE moderation_cases set updated_at=$1 WHERE id=$2
+		RETURNING (SELECT count(*) FROM reports WHERE case_id = $2);
+	`
+
+	tx, err := rr.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var authorID uint
+	err = tx.QueryRowContext(ctx, qAuthor, rp.TargetID).Scan(&authorID)
+	if err != nil {
+		return translate(err, rp.Target)
+	}
+
+	if authorID == rp.ReporterID {
+		return report.ErrOwnContent
+	}
+
+	var postID, replyID uint
+	switch rp.Target {
+	case report.TargetPost:
+		postID = rp.TargetID
+	case report.TargetReply:
+		replyID = rp.TargetID
+	}
+
+	rp.CreatedAt = time.Now()
+	_, err = tx.ExecContext(ctx, qCase, rp.Target, nullID(postID), nullID(replyID), authorID, rp.CreatedAt)
+	if err != nil {
+		return translate(err, "report")
+	}
+
+	err = tx.QueryRowContext(ctx, qOpen, rp.Target, rp.TargetID).Scan(&rp.CaseID)
+	if err != nil {
+		return err
+	}
+
+	err = tx.QueryRowContext(ctx, q, rp.CaseID, rp.ReporterID, rp.Reason, rp.Details, rp.CreatedAt).Scan(&rp.ID)
+	if err == sql.ErrNoRows {
+		return report.ErrAlreadyReported
+	}
+
+	if err != nil {
+		return translate(err, "report")
+	}
+
+	var count int
+	err = tx.QueryRowContext(ctx, qCount, rp.CreatedAt, rp.CaseID).Scan(&count)
+	if err != nil {
+		return err
+	}
+
+	if _, ok := hiddenTables[rp.Target]; ok && threshold > 0 && count >= threshold {
+		hidden, err := hide(ctx, tx, rp.Target, rp.TargetID)
+		if err != nil {
+			return err
+		}
+
+		if hidden {
+			note := fmt.Sprintf("hidden automatically after %d reports", count)
+			err = addAction(ctx, tx, rp.CaseID, 0, report.ActionHide, note)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	return tx.Commit()
+}
+
+// GetCases returns a page of the cases that match the filter, the
+// oldest first.
+func (rr *ReportRepository) GetCases(ctx context.Context, f report.Filter, pg page.Request) ([]report.Case, string, error) {
+	q := `
+	SELECT ` + caseColumns + `
+		WHERE ($1 = '' OR c.status = $1)
+			AND ($2 = '' OR c.target = $2)
+			AND ($3 = 0 OR c.assignee_id = $3)
+			AND ($5 = 0 OR (c.created_at, c.id) > ($4, $5))
+		ORDER BY c.created_at, c.id
+		LIMIT $6;
+	`
+
+	rows, err := rr.Data.DB.QueryContext(ctx, q, f.Status, f.Target, f.AssigneeID,
+		pg.After.Time, pg.After.ID, pg.Limit+1)
+	if err != nil {
+		return nil, "", translate(err, "case")
+	}
+
+	defer rows.Close()
+
+	cases := []report.Case{}
+	for rows.Next() {
+		c, err := scanCase(rows)
+		if err != nil {
+			return nil, "", err
+		}
+
+		cases = append(cases, c)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(cases) > pg.Limit {
+		cases = cases[:pg.Limit]
+		last := cases[pg.Limit-1]
+		next = page.Cursor{ID: last.ID, Time: last.CreatedAt}.Encode()
+	}
+
+	return cases, next, nil
+}
+
+// GetCase returns one case by id with its reports and the actions of
+// the moderators.
+func (rr *ReportRepository) GetCase(ctx context.Context, id uint) (report.Case, error) {
+	q := `SELECT ` + caseColumns + ` WHERE c.id = $1;`
+	qReports := `
+	SELECT id, reporter_id, reason, details, created_at
+		FROM reports
+		WHERE case_id = $1
+		ORDER BY id;
+	`
+	qActions := `
+	SELECT id, COALESCE(moderator_id, 0), action, note, created_at
+		FROM moderation_actions
+		WHERE case_id = $1
+		ORDER BY id;
+	`
+
+	c, err := scanCase(rr.Data.DB.QueryRowContext(ctx, q, id))
+	if err != nil {
+		return report.Case{}, translate(err, "case")
+	}
+
+	rows, err := rr.Data.DB.QueryContext(ctx, qReports, id)
+	if err != nil {
+		return report.Case{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		rp := report.Report{CaseID: c.ID, Target: c.Target, TargetID: c.TargetID}
+		err := rows.Scan(&rp.ID, &rp.ReporterID, &rp.Reason, &rp.Details, &rp.CreatedAt)
+		if err != nil {
+			return report.Case{}, err
+		}
+
+		c.Reports = append(c.Reports, rp)
+	}
+
+	if err := rows.Err(); err != nil {
+		return report.Case{}, err
+	}
+
+	rows, err = rr.Data.DB.QueryContext(ctx, qActions, id)
+	if err != nil {
+		return report.Case{}, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		a := report.Action{CaseID: c.ID}
+		err := rows.Scan(&a.ID, &a.ModeratorID, &a.Action, &a.Note, &a.CreatedAt)
+		if err != nil {
+			return report.Case{}, err
+		}
+
+		c.Actions = append(c.Actions, a)
+	}
+
+	return c, rows.Err()
+}
+
+// Assign assigns an open case to an administrator, or leaves it
+// unassigned if the assignee is 0.
+func (rr *ReportRepository) Assign(ctx context.Context, id, assigneeID uint) error {
+	qAdmin := `SELECT admin FROM users WHERE id = $1 AND deleted_at IS NULL;`
+	qStatus := `SELECT status FROM moderation_cases WHERE id = $1;`
+	q := `UPDATE moderation_cases set assignee_id=$1, updated_at=$2 WHERE id=$3 AND status='open';`
+
+	if assigneeID != 0 {
+		var admin bool
+		err := rr.Data.DB.QueryRowContext(ctx, qAdmin, assigneeID).Scan(&admin)
+		if err != nil && err != sql.ErrNoRows {
+			return err
+		}
+
+		if !admin {
+			return report.ErrAssignee
+		}
+	}
+
+	var status string
+	err := rr.Data.DB.QueryRowContext(ctx, qStatus, id).Scan(&status)
+	if err != nil {
+		return translate(err, "case")
+	}
+
+	if status != report.StatusOpen {
+		return report.ErrClosed
+	}
+
+	res, err := rr.Data.DB.ExecContext(ctx, q, nullID(assigneeID), time.Now(), id)
+	if err != nil {
+		return translate(err, "case")
+	}
+
+	return affected(res, "case")
+}
+
+// Act records the action of the moderator in an open case and closes
+// it. Hide hides the post or reply and warn adds a warning to the user
+// reported or the author of the content. Delete deletes the content or
+// user, in the same transaction. Dismiss shows the content again only
+// if it was hidden automatically in this case.
+func (rr *ReportRepository) Act(ctx context.Context, id, moderatorID uint, action, note string) error {
+	qCase := `
+	SELECT target, COALESCE(post_id, reply_id, user_id), user_id, status
+		FROM moderation_cases
+		WHERE id = $1
+		FOR UPDATE;
+	`
+	qWarn := `
+	INSERT INTO warnings (user_id, case_id, moderator_id, note, created_at)
+		VALUES ($1, $2, $3, $4, $5);
+	`
+	qAutoHidden := `
+	SELECT EXISTS (
+		SELECT 1 FROM moderation_actions
+			WHERE case_id = $1 AND action = 'hide' AND moderator_id IS NULL
+	);
+	`
+	qClose := `UPDATE moderation_cases set status=$1, resolved_at=$2, updated_at=$2 WHERE id=$3;`
+
+	tx, err := rr.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var target, status string
+	var targetID, userID uint
+	err = tx.QueryRowContext(ctx, qCase, id).Scan(&target, &targetID, &userID, &status)
+	if err != nil {
+		return translate(err, "case")
+	}
+
+	if status != report.StatusOpen {
+		return report.ErrClosed
+	}
+
+	_, hideable := hiddenTables[target]
+	now := time.Now()
+	status = report.StatusResolved
+	switch action {
+	case report.ActionHide:
+		if !hideable {
+			return report.ErrNotHideable
+		}
+
+		_, err = hide(ctx, tx, target, targetID)
+	case report.ActionWarn:
+		_, err = tx.ExecContext(ctx, qWarn, userID, id, nullID(moderatorID), note, now)
+	case report.ActionDismiss:
+		status = report.StatusDismissed
+		if !hideable {
+			break
+		}
+
+		var autoHidden bool
+		err = tx.QueryRowContext(ctx, qAutoHidden, id).Scan(&autoHidden)
+		if err == nil && autoHidden {
+			err = unhide(ctx, tx, target, targetID)
+		}
+	case report.ActionDelete:
+		err = deleteTarget(ctx, tx, target, targetID)
+	default:
+		return apperror.Validation("invalid moderation action")
+	}
+
+	if err != nil {
+		return err
+	}
+
+	err = addAction(ctx, tx, id, moderatorID, action, note)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, qClose, status, now, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Warnings returns a page of the warnings of the user, the newest first.
+func (rr *ReportRepository) Warnings(ctx context.Context, userID uint, pg page.Request) ([]report.Warning, string, error) {
+	q := `
+	SELECT id, user_id, COALESCE(case_id, 0), COALESCE(moderator_id, 0), note, created_at
+		FROM warnings
+		WHERE user_id = $1
+			AND ($3 = 0 OR (created_at, id) < ($2, $3))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4;
+	`
+
+	rows, err := rr.Data.DB.QueryContext(ctx, q, userID, pg.After.Time, pg.After.ID, pg.Limit+1)
+	if err != nil {
+		return nil, "", translate(err, "warning")
+	}
+
+	defer rows.Close()
+
+	warnings := []report.Warning{}
+	for rows.Next() {
+		var w report.Warning
+		err := rows.Scan(&w.ID, &w.UserID, &w.CaseID, &w.ModeratorID, &w.Note, &w.CreatedAt)
+		if err != nil {
+			return nil, "", err
+		}
+
+		warnings = append(warnings, w)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(warnings) > pg.Limit {
+		warnings = warnings[:pg.Limit]
+		last := warnings[pg.Limit-1]
+		next = page.Cursor{ID: last.ID, Time: last.CreatedAt}.Encode()
+	}
+
+	return warnings, next, nil
+}
+
+// scanner is implemented by sql.Row and sql.Rows.
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanCase reads a case selected with caseColumns.
+func scanCase(s scanner) (report.Case, error) {
+	var c report.Case
+	err := s.Scan(&c.ID, &c.Target, &c.TargetID, &c.UserID, &c.Status, &c.AssigneeID,
+		&c.ReportsCount, &c.Hidden, &c.CreatedAt, &c.UpdatedAt, &c.ResolvedAt)
+	return c, err
+}
+
+// hide hides the post or reply and reports whether it was shown. A
+// hidden reply can't be the accepted answer, like the deleted ones.
+func hide(ctx context.Context, tx *sql.Tx, target string, id uint) (bool, error) {
+	q := fmt.Sprintf(`UPDATE %s set hidden_at=$1 WHERE id=$2 AND hidden_at IS NULL;`, hiddenTables[target])
+	qUnaccept := `UPDATE posts set accepted_reply_id=NULL WHERE accepted_reply_id=$1;`
+
+	res, err := tx.ExecContext(ctx, q, time.Now(), id)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil || n == 0 {
+		return false, err
+	}
+
+	if target == report.TargetReply {
+		_, err = tx.ExecContext(ctx, qUnaccept, id)
+		if err != nil {
+			return false, err
+		}
+	}
+
+	return true, nil
+}
+
+// deleteTarget deletes the post, reply or user of a case, unless it is
+// already deleted.
+func deleteTarget(ctx context.Context, tx *sql.Tx, target string, id uint) error {
+	var err error
+	switch target {
+	case report.TargetPost:
+		err = deletePost(ctx, tx, id)
+	case report.TargetReply:
+		err = deleteReply(ctx, tx, id)
+	case report.TargetUser:
+		err = deleteUser(ctx, tx, id)
+	}
+
+	if errors.Is(err, apperror.ErrNotFound) {
+		return nil
+	}
+
+	return err
+}
+
+// unhide shows the hidden post or reply again.
+func unhide(ctx context.Context, tx *sql.Tx, target string, id uint) error {
+	q := fmt.Sprintf(`UPDATE %s set hidden_at=NULL WHERE id=$1 AND hidden_at IS NOT NULL;`, hiddenTables[target])
+
+	_, err := tx.ExecContext(ctx, q, id)
+	return err
+}
+
+// addAction records the action of a moderator in a case, moderatorID
+// is 0 for the automatic actions.
+func addAction(ctx context.Context, tx *sql.Tx, caseID, moderatorID uint, action, note string) error {
+	q := `
+	INSERT INTO moderation_actions (case_id, moderator_id, action, note, created_at)
+		VALUES ($1, $2, $3, $4, $5);
+	`
+
+	_, err := tx.ExecContext(ctx, q, caseID, nullID(moderatorID), action, note, time.Now())
+	return err
+}
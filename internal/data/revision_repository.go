@@ -0,0 +1,137 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/revision"
+)
+
+// revisionColumns are the columns of the revisions of each target.
+var revisionColumns = map[string]string{
+	revision.TargetPost:  "post_id",
+	revision.TargetReply: "reply_id",
+}
+
+// RevisionRepository manages the operations with the database that
+// correspond to the revision model.
+type RevisionRepository struct {
+	Data *Data
+}
+
+// GetByTarget returns a page of the revisions of the post or reply,
+// the oldest first.
+func (rr *RevisionRepository) GetByTarget(ctx context.Context, target string, id uint, pg page.Request) ([]revision.Revision, string, error) {
+	column, err := revisionColumnOf(target)
+	if err != nil {
+		return nil, "", err
+	}
+
+	q := fmt.Sprintf(`
+	SELECT number, COALESCE(post_id, 0), COALESCE(reply_id, 0), COALESCE(user_id, 0),
+		COALESCE(title, ''), COALESCE(category, ''), body, created_at
+		FROM revisions
+		WHERE %s = $1 AND number > $2
+		ORDER BY number
+		LIMIT $3;
+	`, column)
+
+	rows, err := rr.Data.DB.QueryContext(ctx, q, id, pg.After.ID, pg.Limit+1)
+	if err != nil {
+		return nil, "", translate(err, "revision")
+	}
+
+	defer rows.Close()
+
+	revisions := []revision.Revision{}
+	for rows.Next() {
+		var r revision.Revision
+		err := rows.Scan(&r.Number, &r.PostID, &r.ReplyID, &r.UserID,
+			&r.Title, &r.Category, &r.Body, &r.CreatedAt)
+		if err != nil {
+			return nil, "", err
+		}
+
+		revisions = append(revisions, r)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(revisions) > pg.Limit {
+		revisions = revisions[:pg.Limit]
+		next = page.Cursor{ID: uint(revisions[pg.Limit-1].Number)}.Encode()
+	}
+
+	return revisions, next, nil
+}
+
+// GetOne returns the revision of the post or reply by number.
+func (rr *RevisionRepository) GetOne(ctx context.Context, target string, id uint, number int) (revision.Revision, error) {
+	column, err := revisionColumnOf(target)
+	if err != nil {
+		return revision.Revision{}, err
+	}
+
+	q := fmt.Sprintf(`
+	SELECT number, COALESCE(post_id, 0), COALESCE(reply_id, 0), COALESCE(user_id, 0),
+		COALESCE(title, ''), COALESCE(category, ''), body, created_at
+		FROM revisions
+		WHERE %s = $1 AND number = $2;
+	`, column)
+
+	var r revision.Revision
+	err = rr.Data.DB.QueryRowContext(ctx, q, id, number).Scan(&r.Number, &r.PostID, &r.ReplyID,
+		&r.UserID, &r.Title, &r.Category, &r.Body, &r.CreatedAt)
+	if err != nil {
+		return revision.Revision{}, translate(err, "revision")
+	}
+
+	return r, nil
+}
+
+// addRevision adds the current content of the post or reply as its
+// next revision, written by the user. It must run in the transaction
+// that changed the content, after the row is locked by the change, so
+// the numbers are not repeated.
+func addRevision(ctx context.Context, tx *sql.Tx, target string, id, userID uint) error {
+	var q string
+	switch target {
+	case revision.TargetPost:
+		q = `
+		INSERT INTO revisions (post_id, number, user_id, title, category, body, created_at)
+			SELECT id, (SELECT COALESCE(max(number), 0) + 1 FROM revisions WHERE post_id = $1),
+				$2, title, category, body, updated_at
+			FROM posts WHERE id = $1;
+		`
+	case revision.TargetReply:
+		q = `
+		INSERT INTO revisions (reply_id, number, user_id, body, created_at)
+			SELECT id, (SELECT COALESCE(max(number), 0) + 1 FROM revisions WHERE reply_id = $1),
+				$2, body, updated_at
+			FROM replies WHERE id = $1;
+		`
+	default:
+		return fmt.Errorf("unknown revision target %q", target)
+	}
+
+	_, err := tx.ExecContext(ctx, q, id, userID)
+	if err != nil {
+		return translate(err, "revision")
+	}
+
+	return nil
+}
+
+func revisionColumnOf(target string) (string, error) {
+	column, ok := revisionColumns[target]
+	if !ok {
+		return "", fmt.Errorf("unknown revision target %q", target)
+	}
+
+	return column, nil
+}
@@ -0,0 +1,111 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/sanction"
+)
+
+// RevocationRepository manages the operations with the database that
+// correspond to the revoked access tokens.
+type RevocationRepository struct {
+	Data *Data
+}
+
+// Revoke adds a token to the revocation list until it expires.
+// Tokens that have already expired are purged from the list.
+func (rr *RevocationRepository) Revoke(ctx context.Context, jti string, userID uint, expiresAt time.Time) error {
+	qPurge := `DELETE FROM revoked_tokens WHERE expires_at < $1;`
+	qRevoke := `
+	INSERT INTO revoked_tokens (jti, user_id, expires_at)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING;
+	`
+
+	_, err := rr.Data.DB.ExecContext(ctx, qPurge, time.Now())
+	if err != nil {
+		return err
+	}
+
+	_, err = rr.Data.DB.ExecContext(ctx, qRevoke, jti, userID, expiresAt)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// RevokeAll revokes every access and refresh token of a user.
+func (rr *RevocationRepository) RevokeAll(ctx context.Context, userID uint) error {
+	qVersion := `
+	UPDATE users set token_version=token_version + 1
+		WHERE id=$1;
+	`
+	qRefresh := `
+	UPDATE refresh_tokens set revoked_at=$1
+		WHERE user_id=$2 AND revoked_at IS NULL;
+	`
+
+	tx, err := rr.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	_, err = tx.ExecContext(ctx, qVersion, userID)
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, qRefresh, time.Now(), userID)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Check reports whether a token has been revoked, either by its jti or
+// because the token version of the user has changed, and returns the
+// active sanction of the user like SanctionRepository.Active, with its
+// id, read_only, expires_at and reason only, in the same query. The tokens of
+// the deleted users are revoked.
+func (rr *RevocationRepository) Check(ctx context.Context, jti string, userID uint, version int) (bool, *sanction.Sanction, error) {
+	q := `
+	SELECT u.token_version <> $3
+			OR EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1),
+		s.id, COALESCE(s.read_only, false), s.expires_at, COALESCE(s.reason, '')
+		FROM users u
+			LEFT JOIN LATERAL (
+				SELECT id, read_only, expires_at, reason
+					FROM sanctions
+					WHERE user_id = u.id AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > $4)
+					ORDER BY read_only, expires_at DESC NULLS FIRST, id DESC
+					LIMIT 1
+			) s ON true
+		WHERE u.id = $2 AND u.deleted_at IS NULL;
+	`
+
+	row := rr.Data.DB.QueryRowContext(ctx, q, jti, userID, version, time.Now())
+
+	var revoked bool
+	var sanctionID sql.NullInt64
+	s := sanction.Sanction{UserID: userID}
+	err := row.Scan(&revoked, &sanctionID, &s.ReadOnly, &s.ExpiresAt, &s.Reason)
+	if err == sql.ErrNoRows {
+		return true, nil, nil
+	}
+	if err != nil {
+		return false, nil, err
+	}
+
+	if !sanctionID.Valid {
+		return revoked, nil, nil
+	}
+
+	s.ID = uint(sanctionID.Int64)
+	return revoked, &s, nil
+}
@@ -0,0 +1,198 @@
+package data
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/sanction"
+)
+
+// sanctionColumns are the columns of the sanctions read by scanSanction.
+const sanctionColumns = `id, user_id, read_only, reason, expires_at, COALESCE(created_by, 0), created_at,
+		lifted_at, COALESCE(lifted_by, 0), COALESCE(appeal, ''), appealed_at`
+
+// SanctionRepository manages the operations with the database that
+// correspond to the sanction model.
+type SanctionRepository struct {
+	Data *Data
+}
+
+// Active returns the most restrictive active sanction of the user: the
+// suspensions before the read-only ones and, of those, the one that
+// lasts longer.
+func (sr *SanctionRepository) Active(ctx context.Context, userID uint) (*sanction.Sanction, error) {
+	q := `
+	SELECT ` + sanctionColumns + `
+		FROM sanctions
+		WHERE user_id = $1 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > $2)
+		ORDER BY read_only, expires_at DESC NULLS FIRST, id DESC
+		LIMIT 1;
+	`
+
+	s, err := scanSanction(sr.Data.DB.QueryRowContext(ctx, q, userID, time.Now()))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &s, nil
+}
+
+// GetActive returns a page of the active sanctions of every user, the
+// newest first.
+func (sr *SanctionRepository) GetActive(ctx context.Context, pg page.Request) ([]sanction.Sanction, string, error) {
+	q := `
+	SELECT ` + sanctionColumns + `
+		FROM sanctions
+		WHERE lifted_at IS NULL AND (expires_at IS NULL OR expires_at > $1)
+			AND ($3 = 0 OR (created_at, id) < ($2, $3))
+		ORDER BY created_at DESC, id DESC
+		LIMIT $4;
+	`
+
+	rows, err := sr.Data.DB.QueryContext(ctx, q, time.Now(), pg.After.Time, pg.After.ID, pg.Limit+1)
+	if err != nil {
+		return nil, "", translate(err, "sanction")
+	}
+
+	defer rows.Close()
+
+	sanctions := []sanction.Sanction{}
+	for rows.Next() {
+		s, err := scanSanction(rows)
+		if err != nil {
+			return nil, "", err
+		}
+
+		sanctions = append(sanctions, s)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, "", err
+	}
+
+	var next string
+	if len(sanctions) > pg.Limit {
+		sanctions = sanctions[:pg.Limit]
+		last := sanctions[pg.Limit-1]
+		next = page.Cursor{ID: last.ID, Time: last.CreatedAt}.Encode()
+	}
+
+	return sanctions, next, nil
+}
+
+// Create adds a sanction to a user that is not deleted. The suspensions
+// also revoke the refresh tokens of the user, the access tokens are
+// rejected by the Authorizator while the sanction is active.
+func (sr *SanctionRepository) Create(ctx context.Context, s *sanction.Sanction) error {
+	q := `
+	INSERT INTO sanctions (user_id, read_only, reason, expires_at, created_by, created_at)
+		SELECT id, $2, $3, $4, $5, $6
+		FROM users
+		WHERE id = $1 AND deleted_at IS NULL
+		RETURNING id;
+	`
+	qRefresh := `
+	UPDATE refresh_tokens set revoked_at=$1
+		WHERE user_id=$2 AND revoked_at IS NULL;
+	`
+
+	tx, err := sr.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	// expires_at is a timestamp without time zone, like the other
+	// times, which are kept in local time, so the offset of the request
+	// would be lost.
+	if s.ExpiresAt != nil {
+		expiresAt := s.ExpiresAt.Local()
+		s.ExpiresAt = &expiresAt
+	}
+
+	s.CreatedAt = time.Now()
+	err = tx.QueryRowContext(ctx, q, s.UserID, s.ReadOnly, s.Reason, s.ExpiresAt,
+		nullID(s.CreatedBy), s.CreatedAt).Scan(&s.ID)
+	if err != nil {
+		return translate(err, "user")
+	}
+
+	if !s.ReadOnly {
+		_, err = tx.ExecContext(ctx, qRefresh, s.CreatedAt, s.UserID)
+		if err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
+
+// Lift ends an active sanction by id before it expires.
+func (sr *SanctionRepository) Lift(ctx context.Context, id, adminID uint) error {
+	q := `
+	UPDATE sanctions set lifted_at=$1, lifted_by=$2
+		WHERE id=$3 AND lifted_at IS NULL AND (expires_at IS NULL OR expires_at > $1);
+	`
+
+	res, err := sr.Data.DB.ExecContext(ctx, q, time.Now(), nullID(adminID), id)
+	if err != nil {
+		return translate(err, "sanction")
+	}
+
+	return affected(res, "sanction")
+}
+
+// Appeal adds the note of the user to the most restrictive active
+// sanction. A sanction can be appealed only once.
+func (sr *SanctionRepository) Appeal(ctx context.Context, userID uint, appeal string) (sanction.Sanction, error) {
+	q := `UPDATE sanctions set appeal=$1, appealed_at=$2 WHERE id=$3 AND appealed_at IS NULL;`
+
+	s, err := sr.Active(ctx, userID)
+	if err != nil {
+		return sanction.Sanction{}, err
+	}
+
+	if s == nil {
+		return sanction.Sanction{}, apperror.NotFound("you have no active sanction")
+	}
+
+	if s.AppealedAt != nil {
+		return sanction.Sanction{}, sanction.ErrAlreadyAppealed
+	}
+
+	now := time.Now()
+	res, err := sr.Data.DB.ExecContext(ctx, q, appeal, now, s.ID)
+	if err != nil {
+		return sanction.Sanction{}, translate(err, "sanction")
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return sanction.Sanction{}, err
+	}
+
+	if n == 0 {
+		return sanction.Sanction{}, sanction.ErrAlreadyAppealed
+	}
+
+	s.Appeal = appeal
+	s.AppealedAt = &now
+
+	return *s, nil
+}
+
+// scanSanction reads a sanction selected with sanctionColumns.
+func scanSanction(s scanner) (sanction.Sanction, error) {
+	var sn sanction.Sanction
+	err := s.Scan(&sn.ID, &sn.UserID, &sn.ReadOnly, &sn.Reason, &sn.ExpiresAt, &sn.CreatedBy,
+		&sn.CreatedAt, &sn.LiftedAt, &sn.LiftedBy, &sn.Appeal, &sn.AppealedAt)
+	return sn, err
+}
@@ -0,0 +1,103 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/search"
+)
+
+// SearchRepository manages the full-text search over posts and replies
+// using the spanish text search configuration.
+type SearchRepository struct {
+	Data *Data
+}
+
+// Search returns a page of posts and replies matching the query,
+// ordered by rank. The snippets highlight the matches with <mark>;
+// the body is escaped before, so they are safe to render as HTML.
+func (sr *SearchRepository) Search(ctx context.Context, sq search.Query, pg page.Request) ([]search.Result, string, error) {
+	q := `
+	WITH q AS (SELECT websearch_to_tsquery('spanish', $1) AS query)
+	SELECT type, id, post_id, subject_id, user_id, title, category,
+		ts_headline('spanish',
+			replace(replace(replace(body, '&', '&amp;'), '<', '&lt;'), '>', '&gt;'),
+			q.query, 'StartSel=<mark>, StopSel=</mark>, MaxFragments=2, MaxWords=30, MinWords=10'),
+		rank, created_at
+	FROM q, (
+		SELECT type, id, post_id, subject_id, user_id, title, category, body, rank, created_at
+		FROM (
+			SELECT 'post' AS type, p.id, p.id AS post_id, p.subject_id, p.user_id,
+				p.title, p.category, p.body, ts_rank(p.search, q.query) AS rank, p.created_at
+			FROM q, posts p
+				JOIN subjects s ON s.id = p.subject_id
+			WHERE p.search @@ q.query AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+				AND $2 <> 'reply'
+				AND ($3 = 0 OR p.subject_id = $3)
+				AND ($4 = 0 OR s.year = $4)
+				AND ($5 = 0 OR p.user_id = $5)
+				AND ($6 = '' OR p.category = $6)
+				AND ($7::timestamp IS NULL OR p.created_at >= $7)
+				AND ($8::timestamp IS NULL OR p.created_at < $8)
+			UNION ALL
+			SELECT 'reply', r.id, r.post_id, p.subject_id, r.user_id,
+				p.title, p.category, r.body, ts_rank(r.search, q.query), r.created_at
+			FROM q, replies r
+				JOIN posts p ON p.id = r.post_id
+				JOIN subjects s ON s.id = p.subject_id
+			WHERE r.search @@ q.query AND r.deleted_at IS NULL AND r.hidden_at IS NULL AND p.deleted_at IS NULL AND p.hidden_at IS NULL
+				AND $2 <> 'post'
+				AND ($3 = 0 OR p.subject_id = $3)
+				AND ($4 = 0 OR s.year = $4)
+				AND ($5 = 0 OR r.user_id = $5)
+				AND ($6 = '' OR p.category = $6)
+				AND ($7::timestamp IS NULL OR r.created_at >= $7)
+				AND ($8::timestamp IS NULL OR r.created_at < $8)
+		) results
+		WHERE $11 = 0 OR (rank, created_at, id) < ($9::real, $10, $11)
+		ORDER BY rank DESC, created_at DESC, id DESC
+		LIMIT $12
+	) matches
+	ORDER BY rank DESC, created_at DESC, id DESC;
+	`
+
+	rows, err := sr.Data.DB.QueryContext(ctx, q, sq.Text, sq.Type, sq.SubjectID,
+		sq.Year, sq.UserID, sq.Category, nullTime(sq.From), nullTime(sq.To),
+		pg.After.Rank, pg.After.Time, pg.After.ID, pg.Limit+1)
+	if err != nil {
+		return nil, "", err
+	}
+
+	defer rows.Close()
+
+	var results []search.Result
+	for rows.Next() {
+		var r search.Result
+		err := rows.Scan(&r.Type, &r.ID, &r.PostID, &r.SubjectID, &r.UserID, &r.Title,
+			&r.Category, &r.Snippet, &r.Rank, &r.CreatedAt)
+		if err != nil {
+			return nil, "", err
+		}
+
+		results = append(results, r)
+	}
+
+	var next string
+	if len(results) > pg.Limit {
+		results = results[:pg.Limit]
+		last := results[pg.Limit-1]
+		next = page.Cursor{ID: last.ID, Time: last.CreatedAt, Rank: last.Rank}.Encode()
+	}
+
+	return results, next, nil
+}
+
+// nullTime returns nil for the zero time, so it is stored as NULL.
+func nullTime(t time.Time) interface{} {
+	if t.IsZero() {
+		return nil
+	}
+
+	return t
+}
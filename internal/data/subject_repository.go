@@ -2,6 +2,9 @@ package data
 
 import (
 	"context"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/subject"
 )
 
@@ -11,16 +14,19 @@ type SubjectRepository struct {
 	Data *Data
 }
 
-// GetAll returns all subjects.
-func (sr *SubjectRepository) GetAll(ctx context.Context) ([]subject.Subject, error) {
+// GetAll returns a page of subjects.
+func (sr *SubjectRepository) GetAll(ctx context.Context, pg page.Request) ([]subject.Subject, string, error) {
 	q := `
 	SELECT id, name, year
-		FROM subjects;
+		FROM subjects
+		WHERE id > $1 AND deleted_at IS NULL
+		ORDER BY id
+		LIMIT $2;
 	`
 
-	rows, err := sr.Data.DB.QueryContext(ctx, q)
+	rows, err := sr.Data.DB.QueryContext(ctx, q, pg.After.ID, pg.Limit+1)
 	if err != nil {
-		return nil, err
+		return nil, "", translate(err, "subject")
 	}
 
 	defer rows.Close()
@@ -32,14 +38,26 @@ func (sr *SubjectRepository) GetAll(ctx context.Context) ([]subject.Subject, err
 		subjects = append(subjects, s)
 	}
 
-	return subjects, nil
+	return pageSubjects(subjects, pg)
+}
+
+// pageSubjects trims the extra subject fetched to know if there is a
+// next page and returns its cursor.
+func pageSubjects(subjects []subject.Subject, pg page.Request) ([]subject.Subject, string, error) {
+	if len(subjects) <= pg.Limit {
+		return subjects, "", nil
+	}
+
+	subjects = subjects[:pg.Limit]
+
+	return subjects, page.Cursor{ID: subjects[pg.Limit-1].ID}.Encode(), nil
 }
 
 // GetOne returns one subject by id.
 func (sr *SubjectRepository) GetOne(ctx context.Context, id uint) (subject.Subject, error) {
 	q := `
 	SELECT id, name, year
-		FROM subjects WHERE id = $1;
+		FROM subjects WHERE id = $1 AND deleted_at IS NULL;
 	`
 
 	row := sr.Data.DB.QueryRowContext(ctx, q, id)
@@ -47,23 +65,25 @@ func (sr *SubjectRepository) GetOne(ctx context.Context, id uint) (subject.Subje
 	var s subject.Subject
 	err := row.Scan(&s.ID, &s.Name, &s.Year)
 	if err != nil {
-		return subject.Subject{}, err
+		return subject.Subject{}, translate(err, "subject")
 	}
 
 	return s, nil
 }
 
-// GetByYear returns all years subjects.
-func (sr *SubjectRepository) GetByYear(ctx context.Context, year int) ([]subject.Subject, error) {
+// GetByYear returns a page of years subjects.
+func (sr *SubjectRepository) GetByYear(ctx context.Context, year int, pg page.Request) ([]subject.Subject, string, error) {
 	q := `
 	SELECT id, name, year
 		FROM subjects
-		WHERE year = $1;
+		WHERE year = $1 AND id > $2 AND deleted_at IS NULL
+		ORDER BY id
+		LIMIT $3;
 	`
 
-	rows, err := sr.Data.DB.QueryContext(ctx, q, year)
+	rows, err := sr.Data.DB.QueryContext(ctx, q, year, pg.After.ID, pg.Limit+1)
 	if err != nil {
-		return nil, err
+		return nil, "", translate(err, "subject")
 	}
 
 	defer rows.Close()
@@ -75,7 +95,7 @@ func (sr *SubjectRepository) GetByYear(ctx context.Context, year int) ([]subject
 		subjects = append(subjects, s)
 	}
 
-	return subjects, nil
+	return pageSubjects(subjects, pg)
 }
 
 // Create adds a new subject.
@@ -88,7 +108,7 @@ func (sr *SubjectRepository) Create(ctx context.Context, s *subject.Subject) err
 
 	stmt, err := sr.Data.DB.PrepareContext(ctx, q)
 	if err != nil {
-		return err
+		return translate(err, "subject")
 	}
 
 	defer stmt.Close()
@@ -97,7 +117,7 @@ func (sr *SubjectRepository) Create(ctx context.Context, s *subject.Subject) err
 
 	err = row.Scan(&s.ID)
 	if err != nil {
-		return err
+		return translate(err, "subject")
 	}
 
 	return nil
@@ -107,41 +127,99 @@ func (sr *SubjectRepository) Create(ctx context.Context, s *subject.Subject) err
 func (sr *SubjectRepository) Update(ctx context.Context, id uint, s subject.Subject) error {
 	q := `
 	UPDATE subjects set name=$1, year=$2
-		WHERE id=$3;
+		WHERE id=$3 AND deleted_at IS NULL;
 	`
 
 	stmt, err := sr.Data.DB.PrepareContext(ctx, q)
 	if err != nil {
-		return err
+		return translate(err, "subject")
 	}
 
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(
+	res, err := stmt.ExecContext(
 		ctx, s.Name, s.Year, id,
 	)
 	if err != nil {
-		return err
+		return translate(err, "subject")
 	}
 
-	return nil
+	return affected(res, "subject")
 }
 
-// Delete removes a subject by id.
+// Delete marks a subject by id as deleted, with its posts. The deleted
+// subjects are hidden until they are restored or purged.
 func (sr *SubjectRepository) Delete(ctx context.Context, id uint) error {
-	q := `DELETE FROM subjects WHERE id=$1;`
+	q := `UPDATE subjects set deleted_at=$1 WHERE id=$2 AND deleted_at IS NULL;`
+	qPosts := `UPDATE posts set deleted_at=$1 WHERE subject_id=$2 AND deleted_at IS NULL;`
 
-	stmt, err := sr.Data.DB.PrepareContext(ctx, q)
+	tx, err := sr.Data.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	defer stmt.Close()
+	defer tx.Rollback()
+
+	now := time.Now()
+	res, err := tx.ExecContext(ctx, q, now, id)
+	if err != nil {
+		return translate(err, "subject")
+	}
 
-	_, err = stmt.ExecContext(ctx, id)
+	err = affected(res, "subject")
 	if err != nil {
 		return err
 	}
 
-	return nil
+	_, err = tx.ExecContext(ctx, qPosts, now, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Restore restores a deleted subject by id, with the posts deleted with
+// it. The posts deleted before the subject stay deleted.
+func (sr *SubjectRepository) Restore(ctx context.Context, id uint) error {
+	q := `
+	UPDATE subjects s set deleted_at=NULL
+		FROM subjects old
+		WHERE s.id=$1 AND old.id=s.id AND old.deleted_at IS NOT NULL
+		RETURNING old.deleted_at;
+	`
+	qPosts := `UPDATE posts set deleted_at=NULL WHERE subject_id=$1 AND deleted_at=$2;`
+
+	tx, err := sr.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	var deletedAt time.Time
+	err = tx.QueryRowContext(ctx, q, id).Scan(&deletedAt)
+	if err != nil {
+		return translate(err, "subject")
+	}
+
+	_, err = tx.ExecContext(ctx, qPosts, id, deletedAt)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// Purge removes the subjects deleted before the time, with their posts,
+// and returns how many were removed.
+func (sr *SubjectRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	q := `DELETE FROM subjects WHERE deleted_at < $1;`
+
+	res, err := sr.Data.DB.ExecContext(ctx, q, before)
+	if err != nil {
+		return 0, err
+	}
+
+	return res.RowsAffected()
 }
@@ -0,0 +1,60 @@
+package data
+
+import (
+	"context"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/ticket"
+)
+
+// TicketRepository manages the operations with the database that
+// correspond to the tickets of the streams.
+type TicketRepository struct {
+	Data *Data
+}
+
+// Create adds a new ticket. The tickets that have already expired are
+// purged.
+func (tr *TicketRepository) Create(ctx context.Context, t *ticket.Ticket) error {
+	qPurge := `DELETE FROM stream_tickets WHERE expires_at < $1;`
+	q := `
+	INSERT INTO stream_tickets (ticket_hash, user_id, token, expires_at)
+		VALUES ($1, $2, $3, $4);
+	`
+
+	_, err := tr.Data.DB.ExecContext(ctx, qPurge, time.Now())
+	if err != nil {
+		return err
+	}
+
+	_, err = tr.Data.DB.ExecContext(ctx, q, t.Hash, t.UserID, t.Token, t.ExpiresAt)
+	if err != nil {
+		return translate(err, "ticket")
+	}
+
+	return nil
+}
+
+// Consume removes the ticket by hash and returns its access token,
+// unless it has expired.
+func (tr *TicketRepository) Consume(ctx context.Context, hash string) (string, error) {
+	q := `
+	DELETE FROM stream_tickets
+		WHERE ticket_hash = $1
+		RETURNING token, expires_at;
+	`
+
+	var token string
+	var expiresAt time.Time
+	err := tr.Data.DB.QueryRowContext(ctx, q, hash).Scan(&token, &expiresAt)
+	if err != nil {
+		return "", translate(err, "ticket")
+	}
+
+	if time.Now().After(expiresAt) {
+		return "", apperror.NotFound("ticket not found")
+	}
+
+	return token, nil
+}
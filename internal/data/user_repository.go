@@ -2,8 +2,10 @@ package data
 
 import (
 	"context"
+	"database/sql"
 	"time"
 
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/user"
 )
 
@@ -13,16 +15,19 @@ type UserRepository struct {
 	Data *Data
 }
 
-// GetAll returns all users.
-func (ur *UserRepository) GetAll(ctx context.Context) ([]user.User, error) {
+// GetAll returns a page of users.
+func (ur *UserRepository) GetAll(ctx context.Context, pg page.Request) ([]user.User, string, error) {
 	q := `
-	SELECT id, username, email, year, admin, picture, created_at, updated_at
-		FROM users;
+	SELECT id, username, email, year, admin, avatar, created_at, updated_at
+		FROM users
+		WHERE id > $1 AND deleted_at IS NULL
+		ORDER BY id
+		LIMIT $2;
 	`
 
-	rows, err := ur.Data.DB.QueryContext(ctx, q)
+	rows, err := ur.Data.DB.QueryContext(ctx, q, pg.After.ID, pg.Limit+1)
 	if err != nil {
-		return nil, err
+		return nil, "", translate(err, "user")
 	}
 
 	defer rows.Close()
@@ -30,149 +35,296 @@ func (ur *UserRepository) GetAll(ctx context.Context) ([]user.User, error) {
 	var users []user.User
 	for rows.Next() {
 		var u user.User
+		var avatar sql.NullString
 		rows.Scan(&u.ID, &u.Username, &u.Email, &u.Year, &u.Admin,
-			&u.Picture, &u.CreatedAt, &u.UpdatedAt)
+			&avatar, &u.CreatedAt, &u.UpdatedAt)
+		u.Avatar = avatar.String
+		u.SetPicture()
 		users = append(users, u)
 	}
 
-	return users, nil
+	var next string
+	if len(users) > pg.Limit {
+		users = users[:pg.Limit]
+		next = page.Cursor{ID: users[pg.Limit-1].ID}.Encode()
+	}
+
+	return users, next, nil
 }
 
 // GetOne returns one user by id.
 func (ur *UserRepository) GetOne(ctx context.Context, id uint) (user.User, error) {
 	q := `
-	SELECT id, username, email, year, admin, picture,
-		created_at, updated_at
-		FROM users WHERE id = $1;
+	SELECT id, username, email, year, admin, avatar,
+		token_version, created_at, updated_at
+		FROM users WHERE id = $1 AND deleted_at IS NULL;
 	`
 
 	row := ur.Data.DB.QueryRowContext(ctx, q, id)
 
 	var u user.User
+	var avatar sql.NullString
 	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.Year, &u.Admin,
-		&u.Picture, &u.CreatedAt, &u.UpdatedAt)
+		&avatar, &u.TokenVersion, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
-		return user.User{}, err
+		return user.User{}, translate(err, "user")
 	}
 
+	u.Avatar = avatar.String
+	u.SetPicture()
+
 	return u, nil
 }
 
 // GetByUsername returns one user by username.
 func (ur *UserRepository) GetByUsername(ctx context.Context, username string) (user.User, error) {
 	q := `
-	SELECT id, username, email, year, admin, picture,
-		password, created_at, updated_at
-		FROM users WHERE username = $1;
+	SELECT id, username, email, year, admin, avatar,
+		password, token_version, created_at, updated_at
+		FROM users WHERE username = $1 AND deleted_at IS NULL;
 	`
 
 	row := ur.Data.DB.QueryRowContext(ctx, q, username)
 
 	var u user.User
-	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.Year, &u.Admin, &u.Picture,
-		&u.PasswordHash, &u.CreatedAt, &u.UpdatedAt)
+	var avatar sql.NullString
+	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.Year, &u.Admin, &avatar,
+		&u.PasswordHash, &u.TokenVersion, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
-		return user.User{}, err
+		return user.User{}, translate(err, "user")
 	}
 
+	u.Avatar = avatar.String
+	u.SetPicture()
+
 	return u, nil
 }
 
 // GetByUsername returns one user by year.
 func (ur *UserRepository) GetByYear(ctx context.Context, year int) (user.User, error) {
 	q := `
-	SELECT id, username, email, year, admin, picture,
+	SELECT id, username, email, year, admin, avatar,
 		password, created_at, updated_at
-		FROM users WHERE year = $1;
+		FROM users WHERE year = $1 AND deleted_at IS NULL;
 	`
 
 	row := ur.Data.DB.QueryRowContext(ctx, q, year)
 
 	var u user.User
-	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.Year, &u.Admin, &u.Picture,
+	var avatar sql.NullString
+	err := row.Scan(&u.ID, &u.Username, &u.Email, &u.Year, &u.Admin, &avatar,
 		&u.PasswordHash, &u.CreatedAt, &u.UpdatedAt)
 	if err != nil {
-		return user.User{}, err
+		return user.User{}, translate(err, "user")
 	}
 
+	u.Avatar = avatar.String
+	u.SetPicture()
+
 	return u, nil
 }
 
 // Create adds a new user.
 func (ur *UserRepository) Create(ctx context.Context, u *user.User) error {
 	q := `
-	INSERT INTO users (username, password, email, year, admin, picture, created_at, updated_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+	INSERT INTO users (username, password, email, year, admin, created_at, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
 		RETURNING id;
 	`
 
-	if u.Picture == "" {
-		u.Picture = "https://placekitten.com/g/300/300"
-	}
-
 	if err := u.HashPassword(); err != nil {
 		return err
 	}
 
 	stmt, err := ur.Data.DB.PrepareContext(ctx, q)
 	if err != nil {
-		return err
+		return translate(err, "user")
 	}
 
 	defer stmt.Close()
 
 	row := stmt.QueryRowContext(ctx, u.Username, u.PasswordHash, u.Email, u.Year, u.Admin,
-		u.Picture, time.Now(), time.Now(),
+		time.Now(), time.Now(),
 	)
 
 	err = row.Scan(&u.ID)
 	if err != nil {
-		return err
+		return translate(err, "user")
 	}
 
+	u.SetPicture()
+
 	return nil
 }
 
 // Update updates a user by id.
 func (ur *UserRepository) Update(ctx context.Context, id uint, u user.User) error {
 	q := `
-	UPDATE users set email=$1, year=$2, picture=$3, updated_at=$4
-		WHERE id=$5;
+	UPDATE users set email=$1, year=$2, updated_at=$3
+		WHERE id=$4 AND deleted_at IS NULL;
 	`
 
 	stmt, err := ur.Data.DB.PrepareContext(ctx, q)
 	if err != nil {
-		return err
+		return translate(err, "user")
 	}
 
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(
+	res, err := stmt.ExecContext(
 		ctx, u.Email, u.Year,
-		u.Picture, time.Now(), id,
+		time.Now(), id,
 	)
 	if err != nil {
-		return err
+		return translate(err, "user")
 	}
 
-	return nil
+	return affected(res, "user")
 }
 
-// Delete removes a user by id.
-func (ur *UserRepository) Delete(ctx context.Context, id uint) error {
-	q := `DELETE FROM users WHERE id=$1;`
+// SetAvatar sets the prefix of the files of the avatar of a user by
+// id, an empty prefix removes it. The files of the previous one are
+// queued to be removed from the storage.
+func (ur *UserRepository) SetAvatar(ctx context.Context, id uint, avatar string) error {
+	q := `
+	UPDATE users set avatar=$1, updated_at=$2
+		WHERE id=$3 AND deleted_at IS NULL;
+	`
+
+	res, err := ur.Data.DB.ExecContext(ctx, q, sql.NullString{String: avatar, Valid: avatar != ""}, time.Now(), id)
+	if err != nil {
+		return translate(err, "user")
+	}
+
+	return affected(res, "user")
+}
+
+// SetAdmin grants or revokes the admin role of a user by id.
+// The access tokens of the user are revoked so the new role is
+// picked up when they are refreshed.
+func (ur *UserRepository) SetAdmin(ctx context.Context, id uint, admin bool) error {
+	q := `
+	UPDATE users set admin=$1, token_version=token_version + 1, updated_at=$2
+		WHERE id=$3 AND deleted_at IS NULL;
+	`
 
 	stmt, err := ur.Data.DB.PrepareContext(ctx, q)
 	if err != nil {
-		return err
+		return translate(err, "user")
 	}
 
 	defer stmt.Close()
 
-	_, err = stmt.ExecContext(ctx, id)
+	res, err := stmt.ExecContext(ctx, admin, time.Now(), id)
+	if err != nil {
+		return translate(err, "user")
+	}
+
+	return affected(res, "user")
+}
+
+// Delete marks a user by id as deleted and revokes their tokens. The
+// deleted users are hidden and can't log in until they are restored,
+// but their posts and replies are kept.
+func (ur *UserRepository) Delete(ctx context.Context, id uint) error {
+	tx, err := ur.Data.DB.BeginTx(ctx, nil)
 	if err != nil {
 		return err
 	}
 
-	return nil
+	defer tx.Rollback()
+
+	err = deleteUser(ctx, tx, id)
+	if err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// deleteUser marks a user by id as deleted and revokes their tokens in
+// the transaction.
+func deleteUser(ctx context.Context, tx *sql.Tx, id uint) error {
+	q := `
+	UPDATE users set deleted_at=$1, token_version=token_version + 1
+		WHERE id=$2 AND deleted_at IS NULL;
+	`
+	qRefresh := `
+	UPDATE refresh_tokens set revoked_at=$1
+		WHERE user_id=$2 AND revoked_at IS NULL;
+	`
+
+	now := time.Now()
+	res, err := tx.ExecContext(ctx, q, now, id)
+	if err != nil {
+		return translate(err, "user")
+	}
+
+	err = affected(res, "user")
+	if err != nil {
+		return err
+	}
+
+	_, err = tx.ExecContext(ctx, qRefresh, now, id)
+	return err
+}
+
+// Restore restores a deleted user by id, unless it has been purged.
+func (ur *UserRepository) Restore(ctx context.Context, id uint) error {
+	q := `
+	UPDATE users set deleted_at=NULL
+		WHERE id=$1 AND deleted_at IS NOT NULL AND purged_at IS NULL;
+	`
+
+	res, err := ur.Data.DB.ExecContext(ctx, q, id)
+	if err != nil {
+		return translate(err, "user")
+	}
+
+	return affected(res, "user")
+}
+
+// Purge removes the personal data of the users deleted before the time
+// and returns how many were purged. The rows are kept, without name,
+// email, password nor avatar, because of the posts and replies of the
+// users.
+func (ur *UserRepository) Purge(ctx context.Context, before time.Time) (int64, error) {
+	qPersonal := []string{
+		`DELETE FROM refresh_tokens WHERE user_id IN (SELECT id FROM users WHERE deleted_at < $1 AND purged_at IS NULL);`,
+		`DELETE FROM enrollments WHERE user_id IN (SELECT id FROM users WHERE deleted_at < $1 AND purged_at IS NULL);`,
+		`DELETE FROM notifications WHERE user_id IN (SELECT id FROM users WHERE deleted_at < $1 AND purged_at IS NULL);`,
+		`DELETE FROM warnings WHERE user_id IN (SELECT id FROM users WHERE deleted_at < $1 AND purged_at IS NULL);`,
+	}
+	q := `
+	UPDATE users set purged_at=now(), avatar=NULL, password='', admin=false,
+		username='deleted-' || md5(random()::text),
+		email='deleted-' || md5(random()::text) || '@deleted.invalid'
+		WHERE deleted_at < $1 AND purged_at IS NULL;
+	`
+
+	tx, err := ur.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+
+	defer tx.Rollback()
+
+	for _, q := range qPersonal {
+		_, err = tx.ExecContext(ctx, q, before)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := tx.ExecContext(ctx, q, before)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
 }
@@ -0,0 +1,156 @@
+package data
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/vote"
+)
+
+// voteTable are the tables of the votes of a target.
+type voteTable struct {
+	votes  string
+	items  string
+	column string
+}
+
+var voteTables = map[string]voteTable{
+	vote.TargetPost:  {votes: "post_votes", items: "posts", column: "post_id"},
+	vote.TargetReply: {votes: "reply_votes", items: "replies", column: "reply_id"},
+}
+
+// VoteRepository manages the operations with the database that
+// correspond to the votes of posts and replies.
+type VoteRepository struct {
+	Data *Data
+}
+
+// Vote stores the vote of the user, replacing the previous one, and
+// returns the new totals.
+func (vr *VoteRepository) Vote(ctx context.Context, target string, id, userID uint, value int) (vote.Totals, error) {
+	t, err := tableOf(target)
+	if err != nil {
+		return vote.Totals{}, err
+	}
+
+	q := fmt.Sprintf(`
+	INSERT INTO %s (user_id, %s, value, created_at)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, %[2]s) DO UPDATE
+			SET value=EXCLUDED.value, created_at=EXCLUDED.created_at;
+	`, t.votes, t.column)
+
+	return vr.change(ctx, t, target, id, userID, q, userID, id, value, time.Now())
+}
+
+// Unvote removes the vote of the user and returns the new totals.
+func (vr *VoteRepository) Unvote(ctx context.Context, target string, id, userID uint) (vote.Totals, error) {
+	t, err := tableOf(target)
+	if err != nil {
+		return vote.Totals{}, err
+	}
+
+	q := fmt.Sprintf(`DELETE FROM %s WHERE user_id=$1 AND %s=$2;`, t.votes, t.column)
+
+	return vr.change(ctx, t, target, id, userID, q, userID, id)
+}
+
+// change runs the query that changes the vote and updates the totals
+// of the item. The item is locked first, so concurrent votes are
+// counted one after the other.
+func (vr *VoteRepository) change(ctx context.Context, t voteTable, target string, id, userID uint, q string, args ...interface{}) (vote.Totals, error) {
+	qLock := fmt.Sprintf(`SELECT id FROM %s WHERE id=$1 AND deleted_at IS NULL AND hidden_at IS NULL FOR UPDATE;`, t.items)
+	qTotals := fmt.Sprintf(`
+	UPDATE %s set
+		upvotes=(SELECT count(*) FROM %s WHERE %s=$1 AND value=1),
+		downvotes=(SELECT count(*) FROM %[2]s WHERE %[3]s=$1 AND value=-1),
+		score=(SELECT COALESCE(sum(value), 0) FROM %[2]s WHERE %[3]s=$1)
+		WHERE id=$1
+		RETURNING upvotes, downvotes, score;
+	`, t.items, t.votes, t.column)
+	qMine := fmt.Sprintf(`SELECT COALESCE(max(value), 0) FROM %s WHERE user_id=$1 AND %s=$2;`, t.votes, t.column)
+
+	tx, err := vr.Data.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return vote.Totals{}, err
+	}
+
+	defer tx.Rollback()
+
+	var locked uint
+	err = tx.QueryRowContext(ctx, qLock, id).Scan(&locked)
+	if err != nil {
+		return vote.Totals{}, translate(err, target)
+	}
+
+	_, err = tx.ExecContext(ctx, q, args...)
+	if err != nil {
+		return vote.Totals{}, translate(err, "vote")
+	}
+
+	var totals vote.Totals
+	err = tx.QueryRowContext(ctx, qTotals, id).Scan(&totals.Upvotes, &totals.Downvotes, &totals.Score)
+	if err != nil {
+		return vote.Totals{}, err
+	}
+
+	err = tx.QueryRowContext(ctx, qMine, userID, id).Scan(&totals.MyVote)
+	if err != nil {
+		return vote.Totals{}, err
+	}
+
+	return totals, tx.Commit()
+}
+
+// Mine returns the votes of the user to the items, by id. The items
+// without a vote are not in the map.
+func (vr *VoteRepository) Mine(ctx context.Context, target string, userID uint, ids []uint) (map[uint]int, error) {
+	mine := make(map[uint]int)
+	if len(ids) == 0 {
+		return mine, nil
+	}
+
+	t, err := tableOf(target)
+	if err != nil {
+		return nil, err
+	}
+
+	q := fmt.Sprintf(`SELECT %s, value FROM %s WHERE user_id=$1 AND %[1]s = ANY($2);`, t.column, t.votes)
+
+	int64s := make([]int64, len(ids))
+	for i, id := range ids {
+		int64s[i] = int64(id)
+	}
+
+	rows, err := vr.Data.DB.QueryContext(ctx, q, userID, pq.Array(int64s))
+	if err != nil {
+		return nil, err
+	}
+
+	defer rows.Close()
+
+	for rows.Next() {
+		var id uint
+		var value int
+		err := rows.Scan(&id, &value)
+		if err != nil {
+			return nil, err
+		}
+
+		mine[id] = value
+	}
+
+	return mine, rows.Err()
+}
+
+// tableOf returns the tables of the target.
+func tableOf(target string) (voteTable, error) {
+	t, ok := voteTables[target]
+	if !ok {
+		return voteTable{}, fmt.Errorf("unknown vote target %q", target)
+	}
+
+	return t, nil
+}
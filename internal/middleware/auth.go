@@ -7,8 +7,11 @@ import (
 	"os"
 	"strings"
 
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/claim"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/sanction"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/ticket"
 )
 
 type key string
@@ -16,16 +19,59 @@ type key string
 // Context keys
 const (
 	UserIDKey key = "id"
+	AdminKey  key = "admin"
+	ClaimKey  key = "claim"
+
+	readOnlyAllowedKey key = "read_only_allowed"
+	tokenKey           key = "token"
 )
 
-// Authorizator is a middleware that verifies if the token is valid.
-func Authorizator(next http.Handler) http.Handler {
+// Checker checks the tokens on each request. Check reports whether the
+// token has been revoked and returns the active sanction of the user,
+// nil if there is none.
+type Checker interface {
+	Check(ctx context.Context, jti string, userID uint, version int) (bool, *sanction.Sanction, error)
+}
+
+// Tickets exchanges the tickets of the streams for their access tokens.
+// Consume returns an apperror.ErrNotFound error if the ticket doesn't
+// exist, has expired or has already been used.
+type Tickets interface {
+	Consume(ctx context.Context, hash string) (string, error)
+}
+
+// NewAuthorizator returns a middleware that verifies if the token is
+// valid and, with the checker, that it has not been revoked and that
+// the user is not suspended. The users in read-only mode can only read,
+// unless the route uses ReadOnlyAllowed. The streams can send a ticket
+// instead of the token, see ticketFromRequest.
+func NewAuthorizator(checker Checker, tickets Tickets) func(next http.Handler) http.Handler {
 	signingString := os.Getenv("SIGNING_STRING")
+	return func(next http.Handler) http.Handler {
+		return authorizator(next, checker, tickets, signingString)
+	}
+}
+
+func authorizator(next http.Handler, checker Checker, tickets Tickets, signingString string) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		authorization := r.Header.Get("Authorization")
-		tokenString, err := tokenFromAuthorization(authorization)
+		var tokenString string
+		var err error
+		if t := ticketFromRequest(r); t != "" {
+			tokenString, err = tickets.Consume(r.Context(), ticket.Hash(t))
+			if errors.Is(err, apperror.ErrNotFound) {
+				response.HTTPError(w, r, http.StatusUnauthorized, "invalid or expired ticket")
+				return
+			}
+		} else {
+			tokenString, err = tokenFromAuthorization(r.Header.Get("Authorization"))
+			if err != nil {
+				response.HTTPError(w, r, http.StatusUnauthorized, err.Error())
+				return
+			}
+		}
+
 		if err != nil {
-			response.HTTPError(w, r, http.StatusUnauthorized, err.Error())
+			response.Error(w, r, err)
 			return
 		}
 
@@ -36,11 +82,117 @@ func Authorizator(next http.Handler) http.Handler {
 		}
 
 		ctx := r.Context()
+		revoked, s, err := checker.Check(ctx, c.Id, uint(c.ID), c.Version)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		if revoked {
+			response.HTTPError(w, r, http.StatusUnauthorized, "token has been revoked")
+			return
+		}
+
+		if s != nil && (!s.ReadOnly || !(isSafe(r) || readOnlyAllowed(ctx))) {
+			response.Error(w, r, s.Err())
+			return
+		}
+
 		ctx = context.WithValue(ctx, UserIDKey, c.ID)
+		ctx = context.WithValue(ctx, AdminKey, c.Admin)
+		ctx = context.WithValue(ctx, ClaimKey, c)
+		ctx = context.WithValue(ctx, tokenKey, tokenString)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
 
+// AdminOnly is a middleware that only lets admins through.
+// It must be used after the Authorizator.
+func AdminOnly(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !IsAdmin(r.Context()) {
+			response.HTTPError(w, r, http.StatusForbidden, "admin role is required")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// ReadOnlyAllowed is a middleware that lets the users in read-only mode
+// use a route that writes, like the logout. It must be used before the
+// Authorizator.
+func ReadOnlyAllowed(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), readOnlyAllowedKey, true)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+func readOnlyAllowed(ctx context.Context) bool {
+	allowed, _ := ctx.Value(readOnlyAllowedKey).(bool)
+	return allowed
+}
+
+// isSafe reports whether the method of the request only reads.
+func isSafe(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	return false
+}
+
+// UserIDFromContext returns the id of the authenticated user stored
+// in the context by Authorizator.
+func UserIDFromContext(ctx context.Context) (uint, bool) {
+	id, ok := ctx.Value(UserIDKey).(int)
+	if !ok || id <= 0 {
+		return 0, false
+	}
+
+	return uint(id), true
+}
+
+// ClaimFromContext returns the claim of the token used in the request.
+func ClaimFromContext(ctx context.Context) (*claim.Claim, bool) {
+	c, ok := ctx.Value(ClaimKey).(*claim.Claim)
+	return c, ok
+}
+
+// IsAdmin reports whether the authenticated user has the admin role.
+func IsAdmin(ctx context.Context) bool {
+	admin, _ := ctx.Value(AdminKey).(bool)
+	return admin
+}
+
+// TokenFromContext returns the access token used in the request.
+func TokenFromContext(ctx context.Context) (string, bool) {
+	token, ok := ctx.Value(tokenKey).(string)
+	return token, ok
+}
+
+// ticketFromRequest returns the ticket of the ticket query parameter.
+// Browsers can't set headers in WebSocket handshakes nor in EventSource
+// requests, so these send a single-use ticket instead of the token,
+// which would be written to the logs with the URL. The other requests
+// must use the Authorization header.
+func ticketFromRequest(r *http.Request) string {
+	if r.Header.Get("Authorization") != "" || !isStream(r) {
+		return ""
+	}
+
+	return r.URL.Query().Get("ticket")
+}
+
+// isStream reports whether the request is a WebSocket handshake or
+// asks for a stream of Server-Sent Events.
+func isStream(r *http.Request) bool {
+	return strings.EqualFold(r.Header.Get("Upgrade"), "websocket") ||
+		strings.Contains(r.Header.Get("Accept"), "text/event-stream")
+}
+
 func tokenFromAuthorization(authorization string) (string, error) {
 	if authorization == "" {
 		return "", errors.New("autorization is required")
@@ -0,0 +1,196 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/claim"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/sanction"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/ticket"
+)
+
+const testSigningString = "secret"
+
+// fakeChecker returns the same result for every token.
+type fakeChecker struct {
+	revoked  bool
+	sanction *sanction.Sanction
+	err      error
+}
+
+func (f fakeChecker) Check(ctx context.Context, jti string, userID uint, version int) (bool, *sanction.Sanction, error) {
+	return f.revoked, f.sanction, f.err
+}
+
+// fakeTickets has one ticket, "t1" of the token, that can be used once.
+type fakeTickets struct {
+	token string
+	used  *bool
+}
+
+func (f fakeTickets) Consume(ctx context.Context, hash string) (string, error) {
+	if hash != ticket.Hash("t1") || *f.used {
+		return "", apperror.NotFound("ticket not found")
+	}
+
+	*f.used = true
+	return f.token, nil
+}
+
+func testToken(t *testing.T) string {
+	t.Helper()
+
+	c, err := claim.New(7, false, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := c.GetToken(testSigningString)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return token
+}
+
+func TestAuthorizator(t *testing.T) {
+	token := testToken(t)
+	expires := time.Now().Add(time.Hour)
+
+	tests := []struct {
+		name            string
+		method          string
+		target          string
+		stream          bool
+		authorization   string
+		checker         fakeChecker
+		readOnlyAllowed bool
+		want            int
+	}{
+		{name: "valid", method: http.MethodPost, authorization: "Bearer " + token, want: http.StatusOK},
+		{name: "without token", method: http.MethodGet, want: http.StatusUnauthorized},
+		{name: "invalid format", method: http.MethodGet, authorization: "Basic " + token, want: http.StatusUnauthorized},
+		{name: "invalid token", method: http.MethodGet, authorization: "Bearer x.y.z", want: http.StatusUnauthorized},
+		{
+			name:          "revoked",
+			method:        http.MethodGet,
+			authorization: "Bearer " + token,
+			checker:       fakeChecker{revoked: true},
+			want:          http.StatusUnauthorized,
+		},
+		{
+			name:          "checker error",
+			method:        http.MethodGet,
+			authorization: "Bearer " + token,
+			checker:       fakeChecker{err: errors.New("connection refused")},
+			want:          http.StatusInternalServerError,
+		},
+		{
+			name:          "suspended",
+			method:        http.MethodGet,
+			authorization: "Bearer " + token,
+			checker:       fakeChecker{sanction: &sanction.Sanction{ExpiresAt: &expires}},
+			want:          http.StatusForbidden,
+		},
+		{
+			name:          "banned",
+			method:        http.MethodGet,
+			authorization: "Bearer " + token,
+			checker:       fakeChecker{sanction: &sanction.Sanction{}},
+			want:          http.StatusForbidden,
+		},
+		{
+			name:          "read-only reads",
+			method:        http.MethodGet,
+			authorization: "Bearer " + token,
+			checker:       fakeChecker{sanction: &sanction.Sanction{ReadOnly: true}},
+			want:          http.StatusOK,
+		},
+		{
+			name:          "read-only writes",
+			method:        http.MethodPost,
+			authorization: "Bearer " + token,
+			checker:       fakeChecker{sanction: &sanction.Sanction{ReadOnly: true}},
+			want:          http.StatusForbidden,
+		},
+		{
+			name:            "read-only writes where allowed",
+			method:          http.MethodPost,
+			authorization:   "Bearer " + token,
+			checker:         fakeChecker{sanction: &sanction.Sanction{ReadOnly: true}},
+			readOnlyAllowed: true,
+			want:            http.StatusOK,
+		},
+		{name: "stream with ticket", method: http.MethodGet, target: "/?ticket=t1", stream: true, want: http.StatusOK},
+		{name: "stream with unknown ticket", method: http.MethodGet, target: "/?ticket=t2", stream: true, want: http.StatusUnauthorized},
+		{name: "ticket out of a stream", method: http.MethodGet, target: "/?ticket=t1", want: http.StatusUnauthorized},
+		{
+			name:   "stream with token in the query",
+			method: http.MethodGet,
+			target: "/?access_token=" + token,
+			stream: true,
+			want:   http.StatusUnauthorized,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				id, ok := UserIDFromContext(r.Context())
+				if !ok || id != 7 {
+					t.Errorf("UserIDFromContext() = %d, %v, want 7, true", id, ok)
+				}
+			})
+
+			tickets := fakeTickets{token: token, used: new(bool)}
+			h := authorizator(next, tt.checker, tickets, testSigningString)
+			if tt.readOnlyAllowed {
+				h = ReadOnlyAllowed(h)
+			}
+
+			target := tt.target
+			if target == "" {
+				target = "/"
+			}
+
+			req := httptest.NewRequest(tt.method, target, nil)
+			if tt.stream {
+				req.Header.Set("Accept", "text/event-stream")
+			}
+
+			if tt.authorization != "" {
+				req.Header.Set("Authorization", tt.authorization)
+			}
+
+			rec := httptest.NewRecorder()
+			h.ServeHTTP(rec, req)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+		})
+	}
+}
+
+func TestAuthorizatorTicketOnce(t *testing.T) {
+	tickets := fakeTickets{token: testToken(t), used: new(bool)}
+	h := authorizator(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		fakeChecker{}, tickets, testSigningString)
+
+	for i, want := range []int{http.StatusOK, http.StatusUnauthorized} {
+		req := httptest.NewRequest(http.MethodGet, "/?ticket=t1", nil)
+		req.Header.Set("Upgrade", "websocket")
+
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, req)
+
+		if rec.Code != want {
+			t.Errorf("request %d: status = %d, want %d", i+1, rec.Code, want)
+		}
+	}
+}
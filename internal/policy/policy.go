@@ -0,0 +1,72 @@
+package policy
+
+import (
+	"context"
+
+	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/moderator"
+)
+
+// ErrForbidden is returned when the user can't modify the resource.
+var ErrForbidden = apperror.Forbidden("you are not allowed to modify this resource")
+
+// Policy decides which resources a user is allowed to modify.
+// Moderators are the moderators of the subjects.
+type Policy struct {
+	Moderators moderator.Repository
+}
+
+// CanModify returns nil if the authenticated user owns the resource
+// or is an admin, otherwise returns ErrForbidden.
+func (p *Policy) CanModify(ctx context.Context, ownerID uint) error {
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		return ErrForbidden
+	}
+
+	if userID == ownerID || middleware.IsAdmin(ctx) {
+		return nil
+	}
+
+	return ErrForbidden
+}
+
+// CanModerate returns nil if the authenticated user is an admin or a
+// moderator of the subject, otherwise returns ErrForbidden.
+func (p *Policy) CanModerate(ctx context.Context, subjectID uint) error {
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		return ErrForbidden
+	}
+
+	if middleware.IsAdmin(ctx) {
+		return nil
+	}
+
+	if p.Moderators == nil {
+		return ErrForbidden
+	}
+
+	ok, err := p.Moderators.IsModerator(ctx, subjectID, userID)
+	if err != nil {
+		return err
+	}
+
+	if !ok {
+		return ErrForbidden
+	}
+
+	return nil
+}
+
+// CanModifyIn returns nil if the authenticated user owns the resource
+// of the subject, is an admin or a moderator of the subject, otherwise
+// returns ErrForbidden.
+func (p *Policy) CanModifyIn(ctx context.Context, ownerID, subjectID uint) error {
+	if p.CanModify(ctx, ownerID) == nil {
+		return nil
+	}
+
+	return p.CanModerate(ctx, subjectID)
+}
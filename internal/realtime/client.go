@@ -0,0 +1,213 @@
+package realtime
+
+import (
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/event"
+)
+
+const (
+	// writeWait is the time allowed to write a message.
+	writeWait = 10 * time.Second
+	// pongWait is the time allowed to read the next pong.
+	pongWait = 60 * time.Second
+	// pingPeriod must be less than pongWait.
+	pingPeriod = pongWait * 9 / 10
+	// maxMessageSize is the size limit of the messages of the clients.
+	maxMessageSize = 512
+	// sendBuffer is the number of messages queued for a client, slower
+	// clients are disconnected.
+	sendBuffer = 64
+	// maxSubscriptions is the limit of channels of a client.
+	maxSubscriptions = 50
+
+	// closeTokenExpired is the close code sent when the token expires,
+	// the client should refresh it and connect again.
+	closeTokenExpired = 4001
+)
+
+// Actions of the messages of the clients.
+const (
+	ActionSubscribe   = "subscribe"
+	ActionUnsubscribe = "unsubscribe"
+)
+
+// Request is a message sent by the clients, like
+// {"action": "subscribe", "channel": "post:12"}.
+type Request struct {
+	Action  string `json:"action"`
+	Channel string `json:"channel"`
+}
+
+// message is a message sent to the clients. Type is the type of the
+// event, or subscribed, unsubscribed and error.
+type message struct {
+	Type    string          `json:"type"`
+	Channel string          `json:"channel,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+	Message string          `json:"message,omitempty"`
+}
+
+// Client is a WebSocket connection of a user.
+type Client struct {
+	hub       *Hub
+	conn      *websocket.Conn
+	userID    uint
+	expiresAt time.Time
+
+	send chan []byte
+	done chan struct{}
+	once sync.Once
+}
+
+// Serve handles the connection of the user until it is closed or the
+// token expires at expiresAt.
+func (h *Hub) Serve(conn *websocket.Conn, userID uint, expiresAt time.Time) {
+	c := &Client{
+		hub:       h,
+		conn:      conn,
+		userID:    userID,
+		expiresAt: expiresAt,
+		send:      make(chan []byte, sendBuffer),
+		done:      make(chan struct{}),
+	}
+
+	go c.writePump()
+	c.readPump()
+
+	h.remove(c)
+	c.close()
+}
+
+func (c *Client) deliver(channel string, e event.Event) {
+	msg, err := json.Marshal(message{Type: e.Type, Channel: channel, Data: e.Data})
+	if err != nil {
+		return
+	}
+
+	c.push(msg)
+}
+
+// push queues the message, or disconnects the client if its queue is
+// full.
+func (c *Client) push(msg []byte) {
+	select {
+	case c.send <- msg:
+	default:
+		c.close()
+	}
+}
+
+// close stops the write pump, which closes the connection.
+func (c *Client) close() {
+	c.once.Do(func() {
+		close(c.done)
+	})
+}
+
+// reply queues a message for the client.
+func (c *Client) reply(m message) {
+	msg, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+
+	c.push(msg)
+}
+
+// readPump handles the requests of the client.
+func (c *Client) readPump() {
+	c.conn.SetReadLimit(maxMessageSize)
+	c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	c.conn.SetPongHandler(func(string) error {
+		return c.conn.SetReadDeadline(time.Now().Add(pongWait))
+	})
+
+	for {
+		_, b, err := c.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+
+		var req Request
+		err = json.Unmarshal(b, &req)
+		if err != nil {
+			c.reply(message{Type: "error", Message: "invalid message"})
+			continue
+		}
+
+		c.handle(req)
+	}
+}
+
+// handle subscribes or unsubscribes the client to a channel.
+func (c *Client) handle(req Request) {
+	kind, id, err := event.ParseChannel(req.Channel)
+	if err != nil {
+		c.reply(message{Type: "error", Channel: req.Channel, Message: err.Error()})
+		return
+	}
+
+	switch req.Action {
+	case ActionSubscribe:
+		if kind == event.KindUser && id != c.userID {
+			c.reply(message{Type: "error", Channel: req.Channel, Message: "you are not allowed to subscribe to this channel"})
+			return
+		}
+
+		if !c.hub.subscribe(c, req.Channel) {
+			c.reply(message{Type: "error", Channel: req.Channel, Message: "too many subscriptions"})
+			return
+		}
+
+		c.reply(message{Type: "subscribed", Channel: req.Channel})
+	case ActionUnsubscribe:
+		c.hub.unsubscribe(c, req.Channel)
+		c.reply(message{Type: "unsubscribed", Channel: req.Channel})
+	default:
+		c.reply(message{Type: "error", Channel: req.Channel, Message: "invalid action"})
+	}
+}
+
+// writePump sends the queued messages and the pings to the client. It
+// is the only writer of the connection.
+func (c *Client) writePump() {
+	ping := time.NewTicker(pingPeriod)
+	expiration := time.NewTimer(time.Until(c.expiresAt))
+	defer func() {
+		ping.Stop()
+		expiration.Stop()
+		c.conn.Close()
+	}()
+
+	for {
+		select {
+		case msg := <-c.send:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			err := c.conn.WriteMessage(websocket.TextMessage, msg)
+			if err != nil {
+				c.close()
+				return
+			}
+		case <-ping.C:
+			c.conn.SetWriteDeadline(time.Now().Add(writeWait))
+			err := c.conn.WriteMessage(websocket.PingMessage, nil)
+			if err != nil {
+				c.close()
+				return
+			}
+		case <-expiration.C:
+			msg := websocket.FormatCloseMessage(closeTokenExpired, "token expired")
+			c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+			c.close()
+			return
+		case <-c.done:
+			msg := websocket.FormatCloseMessage(websocket.CloseNormalClosure, "")
+			c.conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(writeWait))
+			return
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Package realtime forwards the events of the database to the clients
+// connected through WebSocket and Server-Sent Events.
+package realtime
+
+import (
+	"encoding/json"
+	"log"
+	"sync"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/event"
+)
+
+// subscriber receives the events of the channels it is subscribed to.
+// deliver must not block.
+type subscriber interface {
+	deliver(channel string, e event.Event)
+}
+
+// Hub keeps the subscriptions of the connected clients.
+type Hub struct {
+	mu       sync.RWMutex
+	channels map[string]map[subscriber]bool
+	clients  map[subscriber]map[string]bool
+}
+
+// NewHub returns a hub without clients.
+func NewHub() *Hub {
+	return &Hub{
+		channels: make(map[string]map[subscriber]bool),
+		clients:  make(map[subscriber]map[string]bool),
+	}
+}
+
+// Dispatch sends the event of the payload to the clients subscribed to
+// any of its channels. It is meant to be used as the callback of
+// data.Listen.
+func (h *Hub) Dispatch(payload string) {
+	var e event.Event
+	err := json.Unmarshal([]byte(payload), &e)
+	if err != nil {
+		log.Printf("realtime: invalid event: %v", err)
+		return
+	}
+
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	for _, channel := range e.Channels {
+		for s := range h.channels[channel] {
+			s.deliver(channel, e)
+		}
+	}
+}
+
+// Subscribe returns a subscription to the events of the channel.
+func (h *Hub) Subscribe(channel string) *Subscription {
+	events := make(chan event.Event, sendBuffer)
+	s := &Subscription{
+		Events:  events,
+		Dropped: make(chan struct{}),
+		hub:     h,
+		events:  events,
+	}
+
+	h.subscribe(s, channel)
+	return s
+}
+
+// subscribe adds the subscriber to the channel. It returns false if
+// the subscriber already has the maximum number of subscriptions.
+func (h *Hub) subscribe(s subscriber, channel string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.clients[s] == nil {
+		h.clients[s] = make(map[string]bool)
+	}
+
+	if !h.clients[s][channel] && len(h.clients[s]) >= maxSubscriptions {
+		return false
+	}
+
+	if h.channels[channel] == nil {
+		h.channels[channel] = make(map[subscriber]bool)
+	}
+
+	h.channels[channel][s] = true
+	h.clients[s][channel] = true
+	return true
+}
+
+// unsubscribe removes the subscriber from the channel.
+func (h *Hub) unsubscribe(s subscriber, channel string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.leave(s, channel)
+}
+
+// remove removes the subscriber from every channel.
+func (h *Hub) remove(s subscriber) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for channel := range h.clients[s] {
+		h.leave(s, channel)
+	}
+
+	delete(h.clients, s)
+}
+
+// leave removes the subscriber from the channel, h.mu must be held.
+func (h *Hub) leave(s subscriber, channel string) {
+	delete(h.clients[s], channel)
+	delete(h.channels[channel], s)
+	if len(h.channels[channel]) == 0 {
+		delete(h.channels, channel)
+	}
+}
+
+// Subscription receives the events of a channel in Events. If the
+// events are not read fast enough, Dropped is closed and no more events
+// are sent.
+type Subscription struct {
+	Events  <-chan event.Event
+	Dropped chan struct{}
+
+	hub    *Hub
+	events chan event.Event
+	once   sync.Once
+}
+
+func (s *Subscription) deliver(channel string, e event.Event) {
+	select {
+	case <-s.Dropped:
+	case s.events <- e:
+	default:
+		s.once.Do(func() {
+			close(s.Dropped)
+		})
+	}
+}
+
+// Close cancels the subscription.
+func (s *Subscription) Close() {
+	s.hub.remove(s)
+}
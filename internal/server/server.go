@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"log"
 	"net/http"
 	"time"
@@ -14,6 +15,7 @@ import (
 // Server is a base server configuration.
 type Server struct {
 	server *http.Server
+	api    *v1.API
 }
 
 // New inicialize a new server with configuration.
@@ -34,8 +36,11 @@ func New(port string) (*Server, error) {
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 
-	r.Mount("/api/v1", v1.New())
+	api := v1.New()
+	r.Mount("/api/v1", api)
 
+	// the streams of /ws and /subjects/{id}/events clear these
+	// timeouts for their connections.
 	serv := &http.Server{
 		Addr:         ":" + port,
 		Handler:      r,
@@ -43,19 +48,30 @@ func New(port string) (*Server, error) {
 		WriteTimeout: 10 * time.Second,
 	}
 
-	server := Server{server: serv}
+	server := Server{server: serv, api: api}
 
 	return &server, nil
 }
 
-// Close server resources.
+// Close server resources, waiting up to 10 seconds for the requests in
+// progress.
 func (serv *Server) Close() error {
-	// TODO: add resource closure.
-	return nil
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	return serv.server.Shutdown(ctx)
+}
+
+// Run runs the background workers of the API until ctx is done.
+func (serv *Server) Run(ctx context.Context) {
+	serv.api.Run(ctx)
 }
 
 // Start the server.
 func (serv *Server) Start() {
 	log.Printf("Server running on http://localhost%s", serv.server.Addr)
-	log.Fatal(serv.server.ListenAndServe())
+	err := serv.server.ListenAndServe()
+	if err != http.ErrServerClosed {
+		log.Fatal(err)
+	}
 }
@@ -0,0 +1,311 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/moderator"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/post"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/reply"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/request"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/revocation"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/sanction"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/subject"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/user"
+)
+
+// AdminRouter is the router of the privileged operations.
+type AdminRouter struct {
+	Users        user.Repository
+	Revocations  revocation.Repository
+	Subjects     subject.Repository
+	Posts        post.Repository
+	Replies      reply.Repository
+	Moderators   moderator.Repository
+	Sanctions    sanction.Repository
+	Authorizator func(http.Handler) http.Handler
+}
+
+// PromoteHandler grants the admin role to a user by id.
+func (ar *AdminRouter) PromoteHandler(w http.ResponseWriter, r *http.Request) {
+	ar.setAdmin(w, r, true)
+}
+
+// DemoteHandler revokes the admin role of a user by id.
+func (ar *AdminRouter) DemoteHandler(w http.ResponseWriter, r *http.Request) {
+	ar.setAdmin(w, r, false)
+}
+
+// LogoutHandler revoke every token of a user by id.
+func (ar *AdminRouter) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	_, err = ar.Users.GetOne(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = ar.Revocations.RevokeAll(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, nil)
+}
+
+// GetSanctionsHandler response the active sanctions, the newest first.
+func (ar *AdminRouter) GetSanctionsHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	sanctions, next, err := ar.Sanctions.GetActive(r.Context(), pg)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"sanctions": sanctions, "next_cursor": next})
+}
+
+// SanctionHandler suspends a user by id, temporarily or permanently.
+func (ar *AdminRouter) SanctionHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req sanction.Request
+	err = request.Decode(w, r, &req)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	adminID, _ := middleware.UserIDFromContext(ctx)
+	if adminID == uint(id) {
+		response.Error(w, r, sanction.ErrSelf)
+		return
+	}
+
+	s, err := req.Sanction(uint(id), adminID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = ar.Sanctions.Create(ctx, &s)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusCreated, response.Map{"sanction": s})
+}
+
+// LiftSanctionHandler ends an active sanction by id.
+func (ar *AdminRouter) LiftSanctionHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	adminID, _ := middleware.UserIDFromContext(ctx)
+	err = ar.Sanctions.Lift(ctx, uint(id), adminID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, nil)
+}
+
+// GetModeratorsHandler response the moderators of a subject by id.
+func (ar *AdminRouter) GetModeratorsHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	_, err = ar.Subjects.GetOne(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	moderators, err := ar.Moderators.GetBySubject(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{"moderators": moderators})
+}
+
+// AddModeratorHandler makes a user a moderator of a subject.
+func (ar *AdminRouter) AddModeratorHandler(w http.ResponseWriter, r *http.Request) {
+	m, ok := moderatorFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	err := ar.Moderators.Add(r.Context(), &m)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{"moderator": m})
+}
+
+// RemoveModeratorHandler removes a user from the moderators of a
+// subject.
+func (ar *AdminRouter) RemoveModeratorHandler(w http.ResponseWriter, r *http.Request) {
+	m, ok := moderatorFromURL(w, r)
+	if !ok {
+		return
+	}
+
+	err := ar.Moderators.Remove(r.Context(), m.SubjectID, m.UserID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, nil)
+}
+
+// moderatorFromURL returns the moderator of the subject id and the
+// userId of the URL. If they are not valid, it responses the error.
+func moderatorFromURL(w http.ResponseWriter, r *http.Request) (moderator.Moderator, bool) {
+	subjectID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return moderator.Moderator{}, false
+	}
+
+	userID, err := strconv.Atoi(chi.URLParam(r, "userId"))
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return moderator.Moderator{}, false
+	}
+
+	return moderator.Moderator{SubjectID: uint(subjectID), UserID: uint(userID)}, true
+}
+
+// restoreHandler restores the deleted resource with the id of the URL.
+func restoreHandler(restore func(ctx context.Context, id uint) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		err = restore(r.Context(), uint(id))
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, nil)
+	}
+}
+
+func (ar *AdminRouter) setAdmin(w http.ResponseWriter, r *http.Request, admin bool) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+	if !admin && userID == uint(id) {
+		response.HTTPError(w, r, http.StatusBadRequest, "you can't revoke your own admin role")
+		return
+	}
+
+	_, err = ar.Users.GetOne(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = ar.Users.SetAdmin(ctx, uint(id), admin)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, nil)
+}
+
+// Routes returns admin router with each endpoint.
+func (ar *AdminRouter) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(ar.Authorizator)
+	r.Use(middleware.AdminOnly)
+
+	r.Put("/users/{id}/admin", ar.PromoteHandler)
+
+	r.Delete("/users/{id}/admin", ar.DemoteHandler)
+
+	r.Post("/users/{id}/logout", ar.LogoutHandler)
+
+	r.Post("/users/{id}/restore", restoreHandler(ar.Users.Restore))
+
+	r.Post("/users/{id}/sanctions", ar.SanctionHandler)
+
+	r.Get("/sanctions", ar.GetSanctionsHandler)
+
+	r.Delete("/sanctions/{id}", ar.LiftSanctionHandler)
+
+	r.Post("/subjects/{id}/restore", restoreHandler(ar.Subjects.Restore))
+
+	r.Post("/posts/{id}/restore", restoreHandler(ar.Posts.Restore))
+
+	r.Post("/replies/{id}/restore", restoreHandler(ar.Replies.Restore))
+
+	r.Get("/subjects/{id}/moderators", ar.GetModeratorsHandler)
+
+	r.Put("/subjects/{id}/moderators/{userId}", ar.AddModeratorHandler)
+
+	r.Delete("/subjects/{id}/moderators/{userId}", ar.RemoveModeratorHandler)
+
+	return r
+}
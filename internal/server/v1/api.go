@@ -1,28 +1,184 @@
 package v1
 
 import (
+	"context"
+	"fmt"
+	"log"
 	"net/http"
+	"os"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/orlmonteverde/go-postgres-microblog/internal/data"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/policy"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/realtime"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/storage"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/attachment"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/event"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/report"
 )
 
-// New returns the API V1 Handler with configuration.
-func New() http.Handler {
+// API is the API V1 Handler with the workers that run in the
+// background while it serves.
+type API struct {
+	http.Handler
+	workers []func(ctx context.Context)
+}
+
+// Run runs the background workers and waits for them to stop once ctx
+// is done.
+func (a *API) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, work := range a.workers {
+		wg.Add(1)
+		go func(work func(ctx context.Context)) {
+			defer wg.Done()
+			work(ctx)
+		}(work)
+	}
+
+	wg.Wait()
+}
+
+// New returns the API V1 Handler with configuration. Its workers don't
+// start until Run is called.
+func New() *API {
 	r := chi.NewRouter()
+	api := &API{Handler: r}
+
+	moderators := &data.ModeratorRepository{
+		Data: data.New(),
+	}
+
+	sanctions := &data.SanctionRepository{
+		Data: data.New(),
+	}
+
+	p := &policy.Policy{
+		Moderators: moderators,
+	}
+
+	tickets := &data.TicketRepository{
+		Data: data.New(),
+	}
+
+	authorizator := middleware.NewAuthorizator(&data.RevocationRepository{
+		Data: data.New(),
+	}, tickets)
+
+	hub := realtime.NewHub()
+	api.workers = append(api.workers, func(ctx context.Context) {
+		err := data.Listen(ctx, data.EventChannel, hub.Dispatch)
+		log.Printf("events listener stopped: %v", err)
+	})
+
+	events := &data.EventRepository{
+		Data: data.New(),
+	}
+	api.workers = append(api.workers, func(ctx context.Context) {
+		purgeEvents(ctx, events)
+	})
+
+	store, err := storage.New()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	attachments := &data.AttachmentRepository{
+		Data: data.New(),
+	}
+	api.workers = append(api.workers, func(ctx context.Context) {
+		purgeFiles(ctx, attachments, store)
+	})
+
+	retention, err := deletedRetention()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	purgers := []purger{
+		{"replies", (&data.ReplyRepository{Data: data.New()}).Purge},
+		{"posts", (&data.PostRepository{Data: data.New()}).Purge},
+		{"subjects", (&data.SubjectRepository{Data: data.New()}).Purge},
+		{"users", (&data.UserRepository{Data: data.New()}).Purge},
+	}
+	api.workers = append(api.workers, func(ctx context.Context) {
+		purgeDeleted(ctx, retention, purgers)
+	})
 
 	ur := &UserRouter{
 		Repository: &data.UserRepository{
 			Data: data.New(),
 		},
+		Tokens: &data.RefreshRepository{
+			Data: data.New(),
+		},
+		Revocations: &data.RevocationRepository{
+			Data: data.New(),
+		},
+		Sanctions:    sanctions,
+		Policy:       p,
+		Authorizator: authorizator,
 	}
 
 	r.Mount("/users", ur.Routes())
 
+	er := &EnrollmentRouter{
+		Repository: &data.EnrollmentRepository{
+			Data: data.New(),
+		},
+		Policy:       p,
+		Authorizator: authorizator,
+	}
+
+	r.Mount("/users/{id}/subjects", er.Routes())
+
+	avr := &AvatarRouter{
+		Users: &data.UserRepository{
+			Data: data.New(),
+		},
+		Storage:      store,
+		Policy:       p,
+		Authorizator: authorizator,
+	}
+
+	r.Mount("/users/{id}/avatar", avr.Routes())
+
+	threshold, err := reportThreshold()
+	if err != nil {
+		log.Panic(err)
+	}
+
+	reports := &data.ReportRepository{
+		Data: data.New(),
+	}
+
+	wnr := &WarningRouter{
+		Repository:   reports,
+		Policy:       p,
+		Authorizator: authorizator,
+	}
+
+	r.Mount("/users/{id}/warnings", wnr.Routes())
+
 	pr := &PostRouter{
 		Repository: &data.PostRepository{
 			Data: data.New(),
 		},
+		Notifications: &data.NotificationRepository{
+			Data: data.New(),
+		},
+		Votes: &data.VoteRepository{
+			Data: data.New(),
+		},
+		Revisions: &data.RevisionRepository{
+			Data: data.New(),
+		},
+		Policy:       p,
+		Authorizator: authorizator,
 	}
 
 	r.Mount("/posts", pr.Routes())
@@ -31,6 +187,9 @@ func New() http.Handler {
 		Repository: &data.SubjectRepository{
 			Data: data.New(),
 		},
+		Events:       events,
+		Hub:          hub,
+		Authorizator: authorizator,
 	}
 
 	r.Mount("/subjects", sr.Routes())
@@ -39,9 +198,243 @@ func New() http.Handler {
 		Repository: &data.ReplyRepository{
 			Data: data.New(),
 		},
+		Notifications: &data.NotificationRepository{
+			Data: data.New(),
+		},
+		Votes: &data.VoteRepository{
+			Data: data.New(),
+		},
+		Revisions: &data.RevisionRepository{
+			Data: data.New(),
+		},
+		Policy:       p,
+		Authorizator: authorizator,
 	}
 
 	r.Mount("/replies", rr.Routes())
 
-	return r
+	atr := &AttachmentRouter{
+		Repository: attachments,
+		Storage:    store,
+		Posts: &data.PostRepository{
+			Data: data.New(),
+		},
+		Replies: &data.ReplyRepository{
+			Data: data.New(),
+		},
+		Policy:       p,
+		Authorizator: authorizator,
+	}
+
+	r.Mount("/attachments", atr.Routes())
+
+	shr := &SearchRouter{
+		Repository: &data.SearchRepository{
+			Data: data.New(),
+		},
+		Authorizator: authorizator,
+	}
+
+	r.Mount("/search", shr.Routes())
+
+	fr := &FeedRouter{
+		Posts: &data.PostRepository{
+			Data: data.New(),
+		},
+		Votes: &data.VoteRepository{
+			Data: data.New(),
+		},
+		Authorizator: authorizator,
+	}
+
+	r.Mount("/feed", fr.Routes())
+
+	nr := &NotificationRouter{
+		Repository: &data.NotificationRepository{
+			Data: data.New(),
+		},
+		Authorizator: authorizator,
+	}
+
+	r.Mount("/notifications", nr.Routes())
+
+	wr := &WSRouter{
+		Hub:          hub,
+		Authorizator: authorizator,
+	}
+
+	r.Mount("/ws", wr.Routes())
+
+	tkr := &TicketRouter{
+		Repository:   tickets,
+		Authorizator: authorizator,
+	}
+
+	r.Mount("/tickets", tkr.Routes())
+
+	rpr := &ReportRouter{
+		Repository:   reports,
+		Threshold:    threshold,
+		Authorizator: authorizator,
+	}
+
+	r.Mount("/reports", rpr.Routes())
+
+	mr := &ModerationRouter{
+		Repository:   reports,
+		Authorizator: authorizator,
+	}
+
+	r.Mount("/moderation", mr.Routes())
+
+	ar := &AdminRouter{
+		Users: &data.UserRepository{
+			Data: data.New(),
+		},
+		Revocations: &data.RevocationRepository{
+			Data: data.New(),
+		},
+		Subjects: &data.SubjectRepository{
+			Data: data.New(),
+		},
+		Posts: &data.PostRepository{
+			Data: data.New(),
+		},
+		Replies: &data.ReplyRepository{
+			Data: data.New(),
+		},
+		Moderators:   moderators,
+		Sanctions:    sanctions,
+		Authorizator: authorizator,
+	}
+
+	r.Mount("/admin", ar.Routes())
+
+	return api
+}
+
+// every calls fn now and then every d until ctx is done.
+func every(ctx context.Context, d time.Duration, fn func(ctx context.Context)) {
+	t := time.NewTicker(d)
+	defer t.Stop()
+
+	for {
+		fn(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-t.C:
+		}
+	}
+}
+
+// purgeEvents removes the events older than event.Retention from the
+// log every hour until ctx is done.
+func purgeEvents(ctx context.Context, events event.Repository) {
+	every(ctx, time.Hour, func(ctx context.Context) {
+		n, err := events.Purge(ctx, time.Now().Add(-event.Retention))
+		if err != nil {
+			log.Printf("purge events: %v", err)
+			return
+		}
+
+		if n > 0 {
+			log.Printf("purged %d events", n)
+		}
+	})
+}
+
+// purgeFiles removes from the storage the files of the deleted
+// attachments every 10 minutes until ctx is done.
+func purgeFiles(ctx context.Context, attachments attachment.Repository, store attachment.Storage) {
+	every(ctx, 10*time.Minute, func(ctx context.Context) {
+		keys, err := attachments.Deletions(ctx, 100)
+		if err != nil {
+			log.Printf("purge files: %v", err)
+			return
+		}
+
+		var deleted []string
+		for _, key := range keys {
+			err := store.Delete(ctx, key)
+			if err != nil {
+				log.Printf("purge file %s: %v", key, err)
+				continue
+			}
+
+			deleted = append(deleted, key)
+		}
+
+		if len(deleted) == 0 {
+			return
+		}
+
+		err = attachments.Forget(ctx, deleted)
+		if err != nil {
+			log.Printf("purge files: %v", err)
+		}
+	})
+}
+
+// defaultRetention is the number of days the deleted resources are kept
+// when RETENTION_DAYS is not set.
+const defaultRetention = 30
+
+// deletedRetention returns how long the deleted users, subjects, posts
+// and replies are kept, RETENTION_DAYS days.
+func deletedRetention() (time.Duration, error) {
+	days := defaultRetention
+	if s := os.Getenv("RETENTION_DAYS"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 {
+			return 0, fmt.Errorf("RETENTION_DAYS must be a positive number of days, got %q", s)
+		}
+
+		days = n
+	}
+
+	return time.Duration(days) * 24 * time.Hour, nil
+}
+
+// reportThreshold returns the number of distinct reports that hide a
+// post or reply, REPORT_THRESHOLD or report.DefaultThreshold. 0 never
+// hides them.
+func reportThreshold() (int, error) {
+	s := os.Getenv("REPORT_THRESHOLD")
+	if s == "" {
+		return report.DefaultThreshold, nil
+	}
+
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("REPORT_THRESHOLD must be a number of reports, got %q", s)
+	}
+
+	return n, nil
+}
+
+// purger removes the resources deleted before a time.
+type purger struct {
+	name  string
+	purge func(ctx context.Context, before time.Time) (int64, error)
+}
+
+// purgeDeleted removes every hour the resources deleted before the
+// retention period, in order, until ctx is done.
+func purgeDeleted(ctx context.Context, retention time.Duration, purgers []purger) {
+	every(ctx, time.Hour, func(ctx context.Context) {
+		before := time.Now().Add(-retention)
+		for _, p := range purgers {
+			n, err := p.purge(ctx, before)
+			if err != nil {
+				log.Printf("purge deleted %s: %v", p.name, err)
+				continue
+			}
+
+			if n > 0 {
+				log.Printf("purged %d deleted %s", n, p.name)
+			}
+		}
+	})
 }
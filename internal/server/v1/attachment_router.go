@@ -0,0 +1,418 @@
+package v1
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/policy"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/attachment"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/post"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/reply"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+)
+
+const (
+	// transferWait is the time allowed to upload or download a file,
+	// instead of the timeouts of the server.
+	transferWait = 2 * time.Minute
+	// multipartMemory is the size of the form kept in memory, the rest
+	// of the file goes to a temporary file.
+	multipartMemory = 1 << 20
+	// formOverhead is the size allowed for the form besides the file.
+	formOverhead = 64 << 10
+
+	downloadURL = "/api/v1/attachments/%d/download?expires=%d&signature=%s"
+)
+
+var (
+	errFileRequired = apperror.New(apperror.ErrValidation, "validation_failed",
+		"the request has invalid fields",
+		apperror.FieldError{Field: "file", Code: "required", Message: "is required"})
+	errTargetRequired = apperror.New(apperror.ErrValidation, "validation_failed",
+		"the request has invalid fields",
+		apperror.FieldError{Field: "post_id", Code: "required", Message: "post_id or reply_id is required"})
+	errReplyDeleted = apperror.New(apperror.ErrValidation, "validation_failed",
+		"the request has invalid fields",
+		apperror.FieldError{Field: "reply_id", Code: "deleted", Message: "can't be a deleted reply"})
+	errInvalidURL = apperror.Forbidden("the link is invalid or has expired")
+)
+
+// AttachmentRouter is the router of the files of posts and replies.
+type AttachmentRouter struct {
+	Repository   attachment.Repository
+	Storage      attachment.Storage
+	Posts        post.Repository
+	Replies      reply.Repository
+	Policy       *policy.Policy
+	Authorizator func(http.Handler) http.Handler
+}
+
+// CreateHandler uploads a file as multipart/form-data, in the field
+// file, to the post post_id or the reply reply_id of the user.
+func (ar *AttachmentRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	// the upload can take longer than the ReadTimeout of the server.
+	http.NewResponseController(w).SetReadDeadline(time.Now().Add(transferWait))
+
+	r.Body = http.MaxBytesReader(w, r.Body, attachment.MaxSize+formOverhead)
+	err := r.ParseMultipartForm(multipartMemory)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			response.Error(w, r, attachment.ErrTooLarge)
+			return
+		}
+
+		response.HTTPError(w, r, http.StatusBadRequest, "the body must be multipart/form-data")
+		return
+	}
+
+	defer r.MultipartForm.RemoveAll()
+
+	ctx := r.Context()
+	a, err := ar.target(r)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		response.Error(w, r, errFileRequired)
+		return
+	}
+
+	defer file.Close()
+
+	if header.Size > attachment.MaxSize {
+		response.Error(w, r, attachment.ErrTooLarge)
+		return
+	}
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.ErrUnexpectedEOF && err != io.EOF {
+		response.Error(w, r, err)
+		return
+	}
+
+	contentType, err := attachment.Sniff(head[:n])
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	_, err = file.Seek(0, io.SeekStart)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	a.UserID, _ = middleware.UserIDFromContext(ctx)
+	a.Filename = attachment.Filename(header.Filename)
+	a.ContentType = contentType
+	a.Size = header.Size
+
+	// fail before storing the file, Create checks it again.
+	usage, err := ar.Repository.Usage(ctx, a.UserID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	if usage.Used+a.Size > usage.Quota {
+		response.Error(w, r, attachment.ErrQuotaExceeded)
+		return
+	}
+
+	a.StorageKey, err = newStorageKey()
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = ar.Storage.Put(ctx, a.StorageKey, file, a.Size, a.ContentType)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = ar.Repository.Create(ctx, &a)
+	if err != nil {
+		if err := ar.Storage.Delete(ctx, a.StorageKey); err != nil {
+			log.Printf("delete file %s: %v", a.StorageKey, err)
+		}
+
+		response.Error(w, r, err)
+		return
+	}
+
+	sign(&a)
+
+	w.Header().Add("Location", fmt.Sprintf("%s%d", r.URL.String(), a.ID))
+	response.JSON(w, r, http.StatusCreated, response.Map{"attachment": a})
+}
+
+// target returns an attachment of the post or reply of the form, after
+// checking the user can modify it.
+func (ar *AttachmentRouter) target(r *http.Request) (attachment.Attachment, error) {
+	postID, err := formID(r, "post_id")
+	if err != nil {
+		return attachment.Attachment{}, err
+	}
+
+	replyID, err := formID(r, "reply_id")
+	if err != nil {
+		return attachment.Attachment{}, err
+	}
+
+	ctx := r.Context()
+	switch {
+	case postID != 0 && replyID == 0:
+		p, err := ar.Posts.GetOne(ctx, postID)
+		if err != nil {
+			return attachment.Attachment{}, err
+		}
+
+		return attachment.Attachment{PostID: postID}, ar.Policy.CanModify(ctx, p.UserID)
+	case replyID != 0 && postID == 0:
+		rp, err := ar.Replies.GetOne(ctx, replyID)
+		if err != nil {
+			return attachment.Attachment{}, err
+		}
+
+		if rp.Deleted || rp.Hidden {
+			return attachment.Attachment{}, errReplyDeleted
+		}
+
+		return attachment.Attachment{ReplyID: replyID}, ar.Policy.CanModify(ctx, rp.UserID)
+	}
+
+	return attachment.Attachment{}, errTargetRequired
+}
+
+// GetHandler response the attachments of the post_id or the reply_id
+// of the query string.
+func (ar *AttachmentRouter) GetHandler(w http.ResponseWriter, r *http.Request) {
+	postID, err := formID(r, "post_id")
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	replyID, err := formID(r, "reply_id")
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	var attachments []attachment.Attachment
+	switch {
+	case postID != 0 && replyID == 0:
+		attachments, err = ar.Repository.GetByPost(ctx, postID)
+	case replyID != 0 && postID == 0:
+		attachments, err = ar.Repository.GetByReply(ctx, replyID)
+	default:
+		err = errTargetRequired
+	}
+
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	for i := range attachments {
+		sign(&attachments[i])
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{"attachments": attachments})
+}
+
+// GetOneHandler response one attachment by id, with a new download URL.
+func (ar *AttachmentRouter) GetOneHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	a, err := ar.Repository.GetOne(r.Context(), uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	sign(&a)
+
+	response.JSON(w, r, http.StatusOK, response.Map{"attachment": a})
+}
+
+// DownloadHandler sends the file of an attachment. It doesn't need the
+// token, the signature of the URL authenticates the request, so the
+// URLs can be used in links and images.
+func (ar *AttachmentRouter) DownloadHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	expires, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || !attachment.Verify(os.Getenv("SIGNING_STRING"), uint(id), expires, r.URL.Query().Get("signature")) {
+		response.Error(w, r, errInvalidURL)
+		return
+	}
+
+	ctx := r.Context()
+	a, err := ar.Repository.GetOne(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	file, err := ar.Storage.Get(ctx, a.StorageKey)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	defer file.Close()
+
+	// the download can take longer than the WriteTimeout of the server.
+	http.NewResponseController(w).SetWriteDeadline(time.Now().Add(transferWait))
+
+	w.Header().Set("Content-Type", a.ContentType)
+	w.Header().Set("Content-Length", strconv.FormatInt(a.Size, 10))
+	w.Header().Set("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{"filename": a.Filename}))
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	w.Header().Set("Cache-Control", "private, max-age="+strconv.Itoa(int(attachment.URLExpiration.Seconds())))
+	w.WriteHeader(http.StatusOK)
+
+	_, err = io.Copy(w, file)
+	if err != nil {
+		log.Printf("download attachment %d: %v", a.ID, err)
+	}
+}
+
+// UsageHandler response the space used by the files of the user.
+func (ar *AttachmentRouter) UsageHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+
+	usage, err := ar.Repository.Usage(ctx, userID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{"usage": usage})
+}
+
+// DeleteHandler Remove an attachment by ID. The file is removed from
+// the storage later.
+func (ar *AttachmentRouter) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	stored, err := ar.Repository.GetOne(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = ar.Policy.CanModify(ctx, stored.UserID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = ar.Repository.Delete(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{})
+}
+
+// Routes returns attachment router with each endpoint.
+func (ar *AttachmentRouter) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Get("/{id}/download", ar.DownloadHandler)
+
+	r.Group(func(r chi.Router) {
+		r.Use(ar.Authorizator)
+
+		r.Get("/", ar.GetHandler)
+
+		r.Get("/usage", ar.UsageHandler)
+
+		r.Get("/{id}", ar.GetOneHandler)
+
+		r.Post("/", ar.CreateHandler)
+
+		r.Delete("/{id}", ar.DeleteHandler)
+	})
+
+	return r
+}
+
+// sign sets the download URL of the attachment.
+func sign(a *attachment.Attachment) {
+	expires := time.Now().Add(attachment.URLExpiration).Unix()
+	signature := attachment.Sign(os.Getenv("SIGNING_STRING"), a.ID, expires)
+	a.URL = fmt.Sprintf(downloadURL, a.ID, expires, signature)
+}
+
+// formID returns the id of the form field or query parameter, or 0 if
+// it is empty.
+func formID(r *http.Request, name string) (uint, error) {
+	value := r.FormValue(name)
+	if value == "" {
+		return 0, nil
+	}
+
+	id, err := strconv.ParseUint(value, 10, 32)
+	if err != nil || id == 0 {
+		return 0, apperror.New(apperror.ErrValidation, "validation_failed",
+			"the request has invalid fields",
+			apperror.FieldError{Field: name, Code: "invalid_type", Message: "must be a positive integer"})
+	}
+
+	return uint(id), nil
+}
+
+// newStorageKey returns a random key for a new file.
+func newStorageKey() (string, error) {
+	b := make([]byte, 16)
+	_, err := rand.Read(b)
+	if err != nil {
+		return "", err
+	}
+
+	key := hex.EncodeToString(b)
+	return key[:2] + "/" + key, nil
+}
@@ -0,0 +1,228 @@
+package v1
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"path"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/policy"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/attachment"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/avatar"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/user"
+)
+
+// AvatarRouter is the router of the pictures of the users.
+type AvatarRouter struct {
+	Users        user.Repository
+	Storage      attachment.Storage
+	Policy       *policy.Policy
+	Authorizator func(http.Handler) http.Handler
+}
+
+// GetHandler sends the picture of a user of the size query parameter,
+// 64, 128 (default) or 300 pixels. The users without avatar get an
+// identicon. It doesn't need the token, so it can be used in images.
+func (ar *AvatarRouter) GetHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	size := avatar.DefaultSize
+	if sizeStr := r.URL.Query().Get("size"); sizeStr != "" {
+		size, err = strconv.Atoi(sizeStr)
+		if err != nil || !avatar.ValidSize(size) {
+			response.HTTPError(w, r, http.StatusBadRequest, fmt.Sprintf("size must be one of %v", avatar.Sizes))
+			return
+		}
+	}
+
+	ctx := r.Context()
+	u, err := ar.Users.GetOne(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	if u.Avatar == "" {
+		picture, err := avatar.Identicon(u.Username, size)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		w.Header().Set("Content-Type", "image/png")
+		w.Header().Set("Cache-Control", "public, max-age=86400")
+		w.Write(picture)
+		return
+	}
+
+	file, err := ar.Storage.Get(ctx, avatarKey(u.Avatar, size))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	defer file.Close()
+
+	// the URL of the picture of the user changes with the avatar.
+	if v := r.URL.Query().Get("v"); v != "" && v == path.Base(u.Avatar) {
+		w.Header().Set("Cache-Control", "public, max-age=31536000, immutable")
+	} else {
+		w.Header().Set("Cache-Control", "public, max-age=300")
+	}
+
+	w.Header().Set("Content-Type", "image/jpeg")
+	_, err = io.Copy(w, file)
+	if err != nil {
+		log.Printf("send avatar %d: %v", u.ID, err)
+	}
+}
+
+// UpdateHandler uploads the avatar of a user as multipart/form-data,
+// in the field file.
+func (ar *AvatarRouter) UpdateHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	err = ar.Policy.CanModify(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	// the upload can take longer than the ReadTimeout of the server.
+	http.NewResponseController(w).SetReadDeadline(time.Now().Add(transferWait))
+
+	r.Body = http.MaxBytesReader(w, r.Body, avatar.MaxSize+formOverhead)
+	err = r.ParseMultipartForm(multipartMemory)
+	if err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			response.Error(w, r, avatar.ErrTooLarge)
+			return
+		}
+
+		response.HTTPError(w, r, http.StatusBadRequest, "the body must be multipart/form-data")
+		return
+	}
+
+	defer r.MultipartForm.RemoveAll()
+
+	file, _, err := r.FormFile("file")
+	if err != nil {
+		response.Error(w, r, errFileRequired)
+		return
+	}
+
+	defer file.Close()
+
+	pictures, err := avatar.Process(file)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	key, err := newStorageKey()
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	u := user.User{ID: uint(id), Avatar: "avatars/" + key}
+	for _, size := range avatar.Sizes {
+		picture := pictures[size]
+		err = ar.Storage.Put(ctx, avatarKey(u.Avatar, size), bytes.NewReader(picture), int64(len(picture)), "image/jpeg")
+		if err != nil {
+			break
+		}
+	}
+
+	if err == nil {
+		err = ar.Users.SetAvatar(ctx, u.ID, u.Avatar)
+	}
+
+	if err != nil {
+		for _, size := range avatar.Sizes {
+			if err := ar.Storage.Delete(ctx, avatarKey(u.Avatar, size)); err != nil {
+				log.Printf("delete avatar %s: %v", u.Avatar, err)
+			}
+		}
+
+		response.Error(w, r, err)
+		return
+	}
+
+	u.SetPicture()
+	response.JSON(w, r, http.StatusOK, response.Map{"picture": u.Picture})
+}
+
+// DeleteHandler removes the avatar of a user, who gets the identicon
+// again.
+func (ar *AvatarRouter) DeleteHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	err = ar.Policy.CanModify(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = ar.Users.SetAvatar(ctx, uint(id), "")
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	u := user.User{ID: uint(id)}
+	u.SetPicture()
+	response.JSON(w, r, http.StatusOK, response.Map{"picture": u.Picture})
+}
+
+// Routes returns avatar router with each endpoint.
+func (ar *AvatarRouter) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Get("/", ar.GetHandler)
+
+	r.
+		With(ar.Authorizator).
+		Put("/", ar.UpdateHandler)
+
+	r.
+		With(ar.Authorizator).
+		Delete("/", ar.DeleteHandler)
+
+	return r
+}
+
+// avatarKey returns the key of the file of the avatar of the size.
+func avatarKey(prefix string, size int) string {
+	return fmt.Sprintf("%s-%d.jpg", prefix, size)
+}
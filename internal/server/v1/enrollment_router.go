@@ -0,0 +1,146 @@
+package v1
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/policy"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/enrollment"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/request"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+)
+
+// EnrollmentRouter is the router of the subjects of a user.
+type EnrollmentRouter struct {
+	Repository   enrollment.Repository
+	Policy       *policy.Policy
+	Authorizator func(http.Handler) http.Handler
+}
+
+// GetSubjectsHandler response the subjects the user is enrolled in.
+func (er *EnrollmentRouter) GetSubjectsHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	subjects, err := er.Repository.GetSubjects(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{"subjects": subjects})
+}
+
+// GetSuggestedHandler response the subjects of the year of the user
+// that the user is not enrolled in yet.
+func (er *EnrollmentRouter) GetSuggestedHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	subjects, err := er.Repository.GetSuggested(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{"subjects": subjects})
+}
+
+// EnrollHandler enroll the user in a subject.
+func (er *EnrollmentRouter) EnrollHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req enrollment.Request
+	err = request.Decode(w, r, &req)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	err = er.Policy.CanModify(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	e := enrollment.Enrollment{UserID: uint(id), SubjectID: req.SubjectID}
+	err = er.Repository.Enroll(ctx, &e)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	w.Header().Add("Location", fmt.Sprintf("%s/%d", r.URL.Path, e.SubjectID))
+	response.JSON(w, r, http.StatusCreated, response.Map{"enrollment": e})
+}
+
+// UnenrollHandler remove the user from a subject.
+func (er *EnrollmentRouter) UnenrollHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+	subjectIDStr := chi.URLParam(r, "subjectId")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	subjectID, err := strconv.Atoi(subjectIDStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	err = er.Policy.CanModify(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = er.Repository.Unenroll(ctx, uint(id), uint(subjectID))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{})
+}
+
+// Routes returns enrollment router with each endpoint.
+func (er *EnrollmentRouter) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(er.Authorizator)
+
+	r.Get("/", er.GetSubjectsHandler)
+
+	r.Get("/suggested", er.GetSuggestedHandler)
+
+	r.Post("/", er.EnrollHandler)
+
+	r.Delete("/{subjectId}", er.UnenrollHandler)
+
+	return r
+}
@@ -0,0 +1,58 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/post"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/vote"
+)
+
+// FeedRouter is the router of the home feed of the users.
+type FeedRouter struct {
+	Posts        post.Repository
+	Votes        vote.Repository
+	Authorizator func(http.Handler) http.Handler
+}
+
+// FeedHandler response the latest active posts of the subjects the
+// authenticated user is enrolled in.
+func (fr *FeedRouter) FeedHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+
+	posts, next, err := fr.Posts.GetFeed(ctx, userID, pg)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = myPostVotes(ctx, fr.Votes, posts)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"posts": posts, "next_cursor": next})
+}
+
+// Routes returns feed router with each endpoint.
+func (fr *FeedRouter) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(fr.Authorizator)
+
+	r.Get("/", fr.FeedHandler)
+
+	return r
+}
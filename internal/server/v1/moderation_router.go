@@ -0,0 +1,156 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/report"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/request"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+)
+
+// ModerationRouter is the router of the moderation queue, the cases
+// opened by the reports of the users.
+type ModerationRouter struct {
+	Repository   report.Repository
+	Authorizator func(http.Handler) http.Handler
+}
+
+// GetCasesHandler response the cases, the oldest first. They can be
+// filtered by status, target and assignee_id.
+func (mr *ModerationRouter) GetCasesHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	q := r.URL.Query()
+	f := report.Filter{Status: q.Get("status"), Target: q.Get("target")}
+	switch f.Status {
+	case "", report.StatusOpen, report.StatusResolved, report.StatusDismissed:
+	default:
+		response.HTTPError(w, r, http.StatusBadRequest, "invalid status")
+		return
+	}
+
+	switch f.Target {
+	case "", report.TargetPost, report.TargetReply, report.TargetUser:
+	default:
+		response.HTTPError(w, r, http.StatusBadRequest, "invalid target")
+		return
+	}
+
+	if s := q.Get("assignee_id"); s != "" {
+		assigneeID, err := strconv.Atoi(s)
+		if err != nil || assigneeID < 1 {
+			response.HTTPError(w, r, http.StatusBadRequest, "invalid assignee_id")
+			return
+		}
+
+		f.AssigneeID = uint(assigneeID)
+	}
+
+	cases, next, err := mr.Repository.GetCases(r.Context(), f, pg)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"cases": cases, "next_cursor": next})
+}
+
+// GetCaseHandler response one case by id with its reports and actions.
+func (mr *ModerationRouter) GetCaseHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	c, err := mr.Repository.GetCase(r.Context(), uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{"case": c})
+}
+
+// AssignHandler assigns a case to an admin.
+func (mr *ModerationRouter) AssignHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req report.AssignRequest
+	err = request.Decode(w, r, &req)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = mr.Repository.Assign(r.Context(), uint(id), *req.AssigneeID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, nil)
+}
+
+// ActHandler closes a case with the action of the moderator.
+func (mr *ModerationRouter) ActHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req report.ActionRequest
+	err = request.Decode(w, r, &req)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	moderatorID, _ := middleware.UserIDFromContext(ctx)
+	err = mr.Repository.Act(ctx, uint(id), moderatorID, req.Action, req.Note)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, nil)
+}
+
+// Routes returns moderation router with each endpoint.
+func (mr *ModerationRouter) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(mr.Authorizator)
+	r.Use(middleware.AdminOnly)
+
+	r.Get("/cases", mr.GetCasesHandler)
+
+	r.Get("/cases/{id}", mr.GetCaseHandler)
+
+	r.Put("/cases/{id}/assignee", mr.AssignHandler)
+
+	r.Post("/cases/{id}/actions", mr.ActHandler)
+
+	return r
+}
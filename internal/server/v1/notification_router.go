@@ -0,0 +1,160 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/notification"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/request"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+)
+
+// NotificationRouter is the router of the notifications of the
+// authenticated user.
+type NotificationRouter struct {
+	Repository   notification.Repository
+	Authorizator func(http.Handler) http.Handler
+}
+
+// GetAllHandler response the notifications of the user. With
+// ?unread=true only the unread ones.
+func (nr *NotificationRouter) GetAllHandler(w http.ResponseWriter, r *http.Request) {
+	var unread bool
+	if s := r.URL.Query().Get("unread"); s != "" {
+		var err error
+		unread, err = strconv.ParseBool(s)
+		if err != nil {
+			response.HTTPError(w, r, http.StatusBadRequest, "invalid unread")
+			return
+		}
+	}
+
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+
+	notifications, next, err := nr.Repository.GetByUser(ctx, userID, unread, pg)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"notifications": notifications, "next_cursor": next})
+}
+
+// UnreadCountHandler response the number of unread notifications.
+func (nr *NotificationRouter) UnreadCountHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+
+	count, err := nr.Repository.CountUnread(ctx, userID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{"unread": count})
+}
+
+// MarkReadHandler mark a notification as read.
+func (nr *NotificationRouter) MarkReadHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+
+	err = nr.Repository.MarkRead(ctx, userID, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{})
+}
+
+// MarkAllReadHandler mark every notification of the user as read.
+func (nr *NotificationRouter) MarkAllReadHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+
+	err := nr.Repository.MarkAllRead(ctx, userID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{})
+}
+
+// GetPreferencesHandler response the notification preferences of the user.
+func (nr *NotificationRouter) GetPreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+
+	p, err := nr.Repository.GetPreferences(ctx, userID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{"preferences": p})
+}
+
+// UpdatePreferencesHandler update the notification preferences of the user.
+func (nr *NotificationRouter) UpdatePreferencesHandler(w http.ResponseWriter, r *http.Request) {
+	var req notification.PreferencesRequest
+	err := request.Decode(w, r, &req)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	p := req.Preferences()
+
+	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+
+	err = nr.Repository.UpdatePreferences(ctx, userID, p)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{"preferences": p})
+}
+
+// Routes returns notification router with each endpoint.
+func (nr *NotificationRouter) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(nr.Authorizator)
+
+	r.Get("/", nr.GetAllHandler)
+
+	r.Get("/unread/count", nr.UnreadCountHandler)
+
+	r.Put("/read", nr.MarkAllReadHandler)
+
+	r.Put("/{id}/read", nr.MarkReadHandler)
+
+	r.Get("/preferences", nr.GetPreferencesHandler)
+
+	r.Put("/preferences", nr.UpdatePreferencesHandler)
+
+	return r
+}
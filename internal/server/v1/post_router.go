@@ -1,55 +1,89 @@
 package v1
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
+	"log"
 	"net/http"
 	"strconv"
 
 	"github.com/go-chi/chi"
 	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/policy"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/notification"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/post"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/request"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/revision"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/vote"
 )
 
 // PostRouter is the router of the posts.
 type PostRouter struct {
-	Repository post.Repository
+	Repository    post.Repository
+	Notifications notification.Repository
+	Votes         vote.Repository
+	Revisions     revision.Repository
+	Policy        *policy.Policy
+	Authorizator  func(http.Handler) http.Handler
 }
 
 // CreateHandler Create a new post.
 func (pr *PostRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
-	var p post.Post
-	err := json.NewDecoder(r.Body).Decode(&p)
+	var req post.CreateRequest
+	err := request.Decode(w, r, &req)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	defer r.Body.Close()
+	p := req.Post()
 
 	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+	p.UserID = userID
+
 	err = pr.Repository.Create(ctx, &p)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
+	// the post is already stored, a failed notification is only logged.
+	err = pr.Notifications.NotifyPost(ctx, p)
+	if err != nil {
+		log.Printf("notify post %d: %v", p.ID, err)
+	}
+
 	w.Header().Add("Location", fmt.Sprintf("%s%d", r.URL.String(), p.ID))
 	response.JSON(w, r, http.StatusCreated, response.Map{"post": p})
 }
 
 // GetAllHandler response all the posts.
 func (pr *PostRouter) GetAllHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	ctx := r.Context()
 
-	posts, err := pr.Repository.GetAll(ctx)
+	posts, next, err := pr.Repository.GetAll(ctx, pg)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	response.JSON(w, r, http.StatusOK, response.Map{"posts": posts})
+	err = myPostVotes(ctx, pr.Votes, posts)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"posts": posts, "next_cursor": next})
 }
 
 // GetOneHandler response one post by id.
@@ -65,10 +99,19 @@ func (pr *PostRouter) GetOneHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	p, err := pr.Repository.GetOne(ctx, uint(id))
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
+		return
+	}
+
+	posts := []post.Post{p}
+	err = myPostVotes(ctx, pr.Votes, posts)
+	if err != nil {
+		response.Error(w, r, err)
 		return
 	}
 
+	p = posts[0]
+
 	response.JSON(w, r, http.StatusOK, response.Map{"post": p})
 }
 
@@ -82,19 +125,32 @@ func (pr *PostRouter) UpdateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var p post.Post
-	err = json.NewDecoder(r.Body).Decode(&p)
+	var req post.UpdateRequest
+	err = request.Decode(w, r, &req)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	defer r.Body.Close()
+	p := req.Post()
 
 	ctx := r.Context()
-	err = pr.Repository.Update(ctx, uint(id), p)
+	stored, err := pr.Repository.GetOne(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = pr.Policy.CanModifyIn(ctx, stored.UserID, stored.SubjectId)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(ctx)
+	err = pr.Repository.Update(ctx, uint(id), userID, p)
+	if err != nil {
+		response.Error(w, r, err)
 		return
 	}
 
@@ -112,16 +168,161 @@ func (pr *PostRouter) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
+	stored, err := pr.Repository.GetOne(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = pr.Policy.CanModifyIn(ctx, stored.UserID, stored.SubjectId)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
 	err = pr.Repository.Delete(ctx, uint(id))
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{})
+}
+
+// AcceptHandler mark a reply as the accepted answer of a post.
+func (pr *PostRouter) AcceptHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var req post.AcceptRequest
+	err = request.Decode(w, r, &req)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	stored, err := pr.Repository.GetOne(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = pr.Policy.CanModify(ctx, stored.UserID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = pr.Repository.Accept(ctx, uint(id), req.ReplyID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{})
+}
+
+// UnacceptHandler remove the accepted answer of a post.
+func (pr *PostRouter) UnacceptHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	stored, err := pr.Repository.GetOne(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = pr.Policy.CanModify(ctx, stored.UserID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = pr.Repository.Unaccept(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
 		return
 	}
 
 	response.JSON(w, r, http.StatusOK, response.Map{})
 }
 
-//GetBySubjectHandler response posts by subject id.
+// PinHandler pins a post in its subject.
+func (pr *PostRouter) PinHandler(w http.ResponseWriter, r *http.Request) {
+	pr.moderate(w, r, func(ctx context.Context, id uint) error {
+		return pr.Repository.Pin(ctx, id, true)
+	})
+}
+
+// UnpinHandler unpins a post.
+func (pr *PostRouter) UnpinHandler(w http.ResponseWriter, r *http.Request) {
+	pr.moderate(w, r, func(ctx context.Context, id uint) error {
+		return pr.Repository.Pin(ctx, id, false)
+	})
+}
+
+// LockHandler locks a post, so it doesn't accept new replies.
+func (pr *PostRouter) LockHandler(w http.ResponseWriter, r *http.Request) {
+	pr.moderate(w, r, func(ctx context.Context, id uint) error {
+		return pr.Repository.Lock(ctx, id, true)
+	})
+}
+
+// UnlockHandler unlocks a post.
+func (pr *PostRouter) UnlockHandler(w http.ResponseWriter, r *http.Request) {
+	pr.moderate(w, r, func(ctx context.Context, id uint) error {
+		return pr.Repository.Lock(ctx, id, false)
+	})
+}
+
+// moderate runs the action on the post of the URL if the user is an
+// admin or a moderator of its subject.
+func (pr *PostRouter) moderate(w http.ResponseWriter, r *http.Request, action func(ctx context.Context, id uint) error) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	stored, err := pr.Repository.GetOne(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = pr.Policy.CanModerate(ctx, stored.SubjectId)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = action(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{})
+}
+
+//GetBySubjectHandler response posts by subject id. With the solved
+// query parameter only the solved or unsolved posts.
 func (pr *PostRouter) GetBySubjectHandler(w http.ResponseWriter, r *http.Request) {
 	subjectIDStr := chi.URLParam(r, "subjectId")
 	orderStr := chi.URLParam(r, "order")
@@ -132,14 +333,38 @@ func (pr *PostRouter) GetBySubjectHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	var solved *bool
+	if s := r.URL.Query().Get("solved"); s != "" {
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			response.HTTPError(w, r, http.StatusBadRequest, "invalid solved")
+			return
+		}
+
+		solved = &b
+	}
+
 	ctx := r.Context()
-	posts, err := pr.Repository.GetBySubject(ctx, uint(subjectID), orderStr)
+	posts, next, err := pr.Repository.GetBySubject(ctx, uint(subjectID), orderStr, solved, pg)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	response.JSON(w, r, http.StatusOK, response.Map{"posts": posts})
+	err = myPostVotes(ctx, pr.Votes, posts)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"posts": posts, "next_cursor": next})
 }
 
 //GetByCategoryHandler response posts by subject id and category.
@@ -153,14 +378,27 @@ func (pr *PostRouter) GetByCategoryHandler (w http.ResponseWriter, r *http.Reque
 		return
 	}
 
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	ctx := r.Context()
-	posts, err := pr.Repository.GetByCategory(ctx, uint(subjectID), categoryStr)
+	posts, next, err := pr.Repository.GetByCategory(ctx, uint(subjectID), categoryStr, pg)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	response.JSON(w, r, http.StatusOK, response.Map{"posts": posts})
+	err = myPostVotes(ctx, pr.Votes, posts)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"posts": posts, "next_cursor": next})
 }
 
 //GetByTitleHandler response posts by subject id and title.
@@ -174,14 +412,27 @@ func (pr *PostRouter) GetByTitleHandler (w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	ctx := r.Context()
-	posts, err := pr.Repository.GetByTitle(ctx, uint(subjectID), titleStr)
+	posts, next, err := pr.Repository.GetByTitle(ctx, uint(subjectID), titleStr, pg)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	response.JSON(w, r, http.StatusOK, response.Map{"posts": posts})
+	err = myPostVotes(ctx, pr.Votes, posts)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"posts": posts, "next_cursor": next})
 }
 
 // GetByUserHandler response posts by user id.
@@ -194,21 +445,75 @@ func (pr *PostRouter) GetByUserHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	ctx := r.Context()
-	posts, err := pr.Repository.GetByUser(ctx, uint(userID))
+	posts, next, err := pr.Repository.GetByUser(ctx, uint(userID), pg)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	response.JSON(w, r, http.StatusOK, response.Map{"posts": posts})
+	err = myPostVotes(ctx, pr.Votes, posts)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"posts": posts, "next_cursor": next})
+}
+
+// RestoreRevisionHandler sets the content of a stored post by id to the
+// one of a previous revision, which is added again as the last
+// revision.
+func (pr *PostRouter) RestoreRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	number, err := revisionNumber(chi.URLParam(r, "number"))
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	rev, err := pr.Revisions.GetOne(ctx, revision.TargetPost, uint(id), number)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(ctx)
+	err = pr.Repository.Update(ctx, uint(id), userID, post.Post{Title: rev.Title, Category: rev.Category, Body: rev.Body})
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, nil)
+}
+
+// exists returns an error if the post doesn't exist.
+func (pr *PostRouter) exists(ctx context.Context, id uint) error {
+	_, err := pr.Repository.GetOne(ctx, id)
+	return err
 }
 
 // Routes returns post router with each endpoint.
 func (pr *PostRouter) Routes() http.Handler {
 	r := chi.NewRouter()
 
-	r.Use(middleware.Authorizator)
+	r.Use(pr.Authorizator)
 
 	r.Get("/user/{userId}", pr.GetByUserHandler)
 
@@ -228,5 +533,31 @@ func (pr *PostRouter) Routes() http.Handler {
 
 	r.Delete("/{id}", pr.DeleteHandler)
 
+	r.Put("/{id}/accepted", pr.AcceptHandler)
+
+	r.Delete("/{id}/accepted", pr.UnacceptHandler)
+
+	r.Put("/{id}/pin", pr.PinHandler)
+
+	r.Delete("/{id}/pin", pr.UnpinHandler)
+
+	r.Put("/{id}/lock", pr.LockHandler)
+
+	r.Delete("/{id}/lock", pr.UnlockHandler)
+
+	r.Get("/{id}/revisions", revisionsHandler(pr.Revisions, revision.TargetPost, pr.exists))
+
+	r.Get("/{id}/revisions/diff", revisionDiffHandler(pr.Revisions, revision.TargetPost, pr.exists))
+
+	r.Get("/{id}/revisions/{number}", revisionHandler(pr.Revisions, revision.TargetPost, pr.exists))
+
+	r.
+		With(middleware.AdminOnly).
+		Post("/{id}/revisions/{number}/restore", pr.RestoreRevisionHandler)
+
+	r.Put("/{id}/vote", voteHandler(pr.Votes, vote.TargetPost, false))
+
+	r.Delete("/{id}/vote", voteHandler(pr.Votes, vote.TargetPost, true))
+
 	return r
 }
@@ -1,44 +1,67 @@
 package v1
 
 import (
-	"encoding/json"
+	"context"
 	"fmt"
 	"github.com/go-chi/chi"
 	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/policy"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/notification"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/reply"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/request"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/revision"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/vote"
+	"log"
 	"net/http"
 	"strconv"
 )
 
 // ReplyRouter is the router of the replies.
 type ReplyRouter struct {
-	Repository reply.Repository
+	Repository    reply.Repository
+	Notifications notification.Repository
+	Votes         vote.Repository
+	Revisions     revision.Repository
+	Policy        *policy.Policy
+	Authorizator  func(http.Handler) http.Handler
 }
 
 // CreateHandler Create a new reply.
 func (rr *ReplyRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
-	var reply reply.Reply
-	err := json.NewDecoder(r.Body).Decode(&reply)
+	var req reply.CreateRequest
+	err := request.Decode(w, r, &req)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	defer r.Body.Close()
+	reply := req.Reply()
 
 	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+	reply.UserID = userID
+
 	err = rr.Repository.Create(ctx, &reply)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
+	// the reply is already stored, a failed notification is only logged.
+	err = rr.Notifications.NotifyReply(ctx, reply)
+	if err != nil {
+		log.Printf("notify reply %d: %v", reply.ID, err)
+	}
+
 	w.Header().Add("Location", fmt.Sprintf("%s%d", r.URL.String(), reply.ID))
 	response.JSON(w, r, http.StatusCreated, response.Map{"reply": reply})
 }
 
-//GetByPostHandler response replies by post id.
+//GetByPostHandler response replies by post id, sorted by the order
+// query parameter: created (default), top or hot.
 func (rr *ReplyRouter) GetByPostHandler(w http.ResponseWriter, r *http.Request) {
 	postIDStr := chi.URLParam(r, "postId")
 
@@ -48,14 +71,68 @@ func (rr *ReplyRouter) GetByPostHandler(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	order := r.URL.Query().Get("order")
+	replies, next, err := rr.Repository.GetByPost(ctx, uint(postID), order, pg)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = myReplyVotes(ctx, rr.Votes, replies)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"replies": replies, "next_cursor": next})
+}
+
+// GetBranchHandler response the answers of a reply, at any depth, in
+// thread order.
+func (rr *ReplyRouter) GetBranchHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	ctx := r.Context()
-	replies, err := rr.Repository.GetByPost(ctx, uint(postID))
+	_, err = rr.Repository.GetOne(ctx, uint(id))
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	response.JSON(w, r, http.StatusOK, response.Map{"replies": replies})
+	replies, next, err := rr.Repository.GetBranch(ctx, uint(id), pg)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = myReplyVotes(ctx, rr.Votes, replies)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"replies": replies, "next_cursor": next})
 }
 
 // UpdateHandler update a stored reply by id.
@@ -68,25 +145,39 @@ func (rr *ReplyRouter) UpdateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var reply reply.Reply
-	err = json.NewDecoder(r.Body).Decode(&reply)
+	var req reply.UpdateRequest
+	err = request.Decode(w, r, &req)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	defer r.Body.Close()
+	reply := req.Reply()
 
 	ctx := r.Context()
-	err = rr.Repository.Update(ctx, uint(id), reply)
+	stored, err := rr.Repository.GetOne(ctx, uint(id))
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
+		return
+	}
+
+	err = rr.Policy.CanModifyIn(ctx, stored.UserID, stored.SubjectID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(ctx)
+	err = rr.Repository.Update(ctx, uint(id), userID, reply)
+	if err != nil {
+		response.Error(w, r, err)
 		return
 	}
 
 	response.JSON(w, r, http.StatusOK, nil)
 }
 
+// DeleteHandler Remove a reply by ID.
 func (rr *ReplyRouter) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	idStr := chi.URLParam(r, "id")
 
@@ -97,28 +188,106 @@ func (rr *ReplyRouter) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
+	stored, err := rr.Repository.GetOne(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = rr.Policy.CanModifyIn(ctx, stored.UserID, stored.SubjectID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
 	err = rr.Repository.Delete(ctx, uint(id))
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
 	response.JSON(w, r, http.StatusOK, response.Map{})
 }
 
+// RestoreRevisionHandler sets the content of a stored reply by id to the
+// one of a previous revision, which is added again as the last
+// revision.
+func (rr *ReplyRouter) RestoreRevisionHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	number, err := revisionNumber(chi.URLParam(r, "number"))
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	rev, err := rr.Revisions.GetOne(ctx, revision.TargetReply, uint(id), number)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	userID, _ := middleware.UserIDFromContext(ctx)
+	err = rr.Repository.Update(ctx, uint(id), userID, reply.Reply{Body: rev.Body})
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, nil)
+}
+
+// exists returns an error if the reply doesn't exist, is deleted or
+// is hidden.
+func (rr *ReplyRouter) exists(ctx context.Context, id uint) error {
+	rp, err := rr.Repository.GetOne(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	if rp.Deleted || rp.Hidden {
+		return apperror.NotFound("reply not found")
+	}
+
+	return nil
+}
+
 // Routes returns reply router with each endpoint.
 func (rr *ReplyRouter) Routes() http.Handler {
 	r := chi.NewRouter()
 
-	r.Use(middleware.Authorizator)
+	r.Use(rr.Authorizator)
 
 	r.Get("/post/{postId}", rr.GetByPostHandler)
 
+	r.Get("/{id}/replies", rr.GetBranchHandler)
+
 	r.Post("/", rr.CreateHandler)
 
 	r.Put("/{id}", rr.UpdateHandler)
 
 	r.Delete("/{id}", rr.DeleteHandler)
 
+	r.Get("/{id}/revisions", revisionsHandler(rr.Revisions, revision.TargetReply, rr.exists))
+
+	r.Get("/{id}/revisions/diff", revisionDiffHandler(rr.Revisions, revision.TargetReply, rr.exists))
+
+	r.Get("/{id}/revisions/{number}", revisionHandler(rr.Revisions, revision.TargetReply, rr.exists))
+
+	r.
+		With(middleware.AdminOnly).
+		Post("/{id}/revisions/{number}/restore", rr.RestoreRevisionHandler)
+
+	r.Put("/{id}/vote", voteHandler(rr.Votes, vote.TargetReply, false))
+
+	r.Delete("/{id}/vote", voteHandler(rr.Votes, vote.TargetReply, true))
+
 	return r
 }
@@ -0,0 +1,52 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/report"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/request"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+)
+
+// ReportRouter is the router of the reports of the users. Threshold is
+// the number of reports that hide a post or reply, 0 never hides them.
+type ReportRouter struct {
+	Repository   report.Repository
+	Threshold    int
+	Authorizator func(http.Handler) http.Handler
+}
+
+// CreateHandler reports a post, reply or user.
+func (rr *ReportRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	var req report.Request
+	err := request.Decode(w, r, &req)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+	rp := req.Report(userID)
+
+	err = rr.Repository.Create(ctx, &rp, rr.Threshold)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusCreated, response.Map{"report": rp})
+}
+
+// Routes returns report router with each endpoint.
+func (rr *ReportRouter) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(rr.Authorizator)
+
+	r.Post("/", rr.CreateHandler)
+
+	return r
+}
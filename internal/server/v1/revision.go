@@ -0,0 +1,144 @@
+package v1
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/revision"
+)
+
+var errRevisionNumber = errors.New("the revision number must be a positive integer")
+
+// revisionsHandler responses a page of the revisions of the target with
+// the id of the URL, the oldest first. exists returns an error if the
+// target can't be seen.
+func revisionsHandler(revisions revision.Repository, target string, exists func(ctx context.Context, id uint) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		pg, err := page.FromRequest(r)
+		if err != nil {
+			response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ctx := r.Context()
+		err = exists(ctx, uint(id))
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		list, next, err := revisions.GetByTarget(ctx, target, uint(id), pg)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		page.SetLink(w, r, next)
+		response.JSON(w, r, http.StatusOK, response.Map{"revisions": list, "next_cursor": next})
+	}
+}
+
+// revisionHandler responses the revision of the target with the id and
+// the number of the URL.
+func revisionHandler(revisions revision.Repository, target string, exists func(ctx context.Context, id uint) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		number, err := revisionNumber(chi.URLParam(r, "number"))
+		if err != nil {
+			response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		ctx := r.Context()
+		err = exists(ctx, uint(id))
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		rev, err := revisions.GetOne(ctx, target, uint(id), number)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, response.Map{"revision": rev})
+	}
+}
+
+// revisionDiffHandler responses the changes of the target with the id
+// of the URL between the revisions of the from and to query parameters.
+func revisionDiffHandler(revisions revision.Repository, target string, exists func(ctx context.Context, id uint) error) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		from, err := revisionNumber(r.URL.Query().Get("from"))
+		if err != nil {
+			response.HTTPError(w, r, http.StatusBadRequest, "from: "+err.Error())
+			return
+		}
+
+		to, err := revisionNumber(r.URL.Query().Get("to"))
+		if err != nil {
+			response.HTTPError(w, r, http.StatusBadRequest, "to: "+err.Error())
+			return
+		}
+
+		ctx := r.Context()
+		err = exists(ctx, uint(id))
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		a, err := revisions.GetOne(ctx, target, uint(id), from)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		b, err := revisions.GetOne(ctx, target, uint(id), to)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, response.Map{"diff": revision.Compare(a, b)})
+	}
+}
+
+// revisionNumber returns the number of a revision of the URL.
+func revisionNumber(s string) (int, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 1 {
+		return 0, errRevisionNumber
+	}
+
+	return n, nil
+}
@@ -0,0 +1,133 @@
+package v1
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/search"
+)
+
+// SearchRouter is the router of the full-text search.
+type SearchRouter struct {
+	Repository   search.Repository
+	Authorizator func(http.Handler) http.Handler
+}
+
+// SearchHandler response the posts and replies matching the query.
+func (sr *SearchRouter) SearchHandler(w http.ResponseWriter, r *http.Request) {
+	sq, err := searchQuery(r.URL.Query())
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	results, next, err := sr.Repository.Search(ctx, sq, pg)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"results": results, "next_cursor": next})
+}
+
+// searchQuery returns the search query of the query string parameters
+// q, type, subject, year, user, category, from and to.
+func searchQuery(v url.Values) (search.Query, error) {
+	sq := search.Query{
+		Text:     v.Get("q"),
+		Type:     v.Get("type"),
+		Category: v.Get("category"),
+	}
+
+	if sq.Text == "" {
+		return search.Query{}, errors.New("q is required")
+	}
+
+	if sq.Type != "" && sq.Type != search.TypePost && sq.Type != search.TypeReply {
+		return search.Query{}, errors.New("invalid type")
+	}
+
+	if s := v.Get("subject"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil || id < 1 {
+			return search.Query{}, errors.New("invalid subject")
+		}
+
+		sq.SubjectID = uint(id)
+	}
+
+	if s := v.Get("year"); s != "" {
+		year, err := strconv.Atoi(s)
+		if err != nil || year < 1 {
+			return search.Query{}, errors.New("invalid year")
+		}
+
+		sq.Year = year
+	}
+
+	if s := v.Get("user"); s != "" {
+		id, err := strconv.Atoi(s)
+		if err != nil || id < 1 {
+			return search.Query{}, errors.New("invalid user")
+		}
+
+		sq.UserID = uint(id)
+	}
+
+	var err error
+	sq.From, err = parseDate(v.Get("from"))
+	if err != nil {
+		return search.Query{}, errors.New("invalid from date")
+	}
+
+	sq.To, err = parseDate(v.Get("to"))
+	if err != nil {
+		return search.Query{}, errors.New("invalid to date")
+	}
+
+	// a date without time includes the whole day.
+	if len(v.Get("to")) == len("2006-01-02") {
+		sq.To = sq.To.AddDate(0, 0, 1)
+	}
+
+	return sq, nil
+}
+
+// parseDate parses a date like 2021-05-30 or a RFC 3339 timestamp.
+// An empty string returns the zero time.
+func parseDate(s string) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+
+	if t, err := time.Parse("2006-01-02", s); err == nil {
+		return t, nil
+	}
+
+	return time.Parse(time.RFC3339, s)
+}
+
+// Routes returns search router with each endpoint.
+func (sr *SearchRouter) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(sr.Authorizator)
+
+	r.Get("/", sr.SearchHandler)
+
+	return r
+}
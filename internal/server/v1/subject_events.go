@@ -0,0 +1,181 @@
+package v1
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/event"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+)
+
+const (
+	// heartbeatPeriod is the time between the comments sent to keep
+	// idle streams open through proxies.
+	heartbeatPeriod = 15 * time.Second
+	// streamWriteWait is the time allowed to write to a stream.
+	streamWriteWait = 10 * time.Second
+	// resumeBatch is the number of events read from the log at once
+	// when a stream is resumed.
+	resumeBatch = 100
+)
+
+// EventsHandler streams the new posts and replies of a subject as
+// Server-Sent Events. The stream resumes after the Last-Event-ID
+// header, or the last_event_id query parameter, and ends when the
+// token expires.
+func (sr *SubjectRouter) EventsHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	lastIDStr := r.Header.Get("Last-Event-ID")
+	if lastIDStr == "" {
+		lastIDStr = r.URL.Query().Get("last_event_id")
+	}
+
+	var lastID int64
+	if lastIDStr != "" {
+		lastID, err = strconv.ParseInt(lastIDStr, 10, 64)
+		if err != nil || lastID < 0 {
+			response.HTTPError(w, r, http.StatusBadRequest, "invalid Last-Event-ID")
+			return
+		}
+	}
+
+	ctx := r.Context()
+	c, ok := middleware.ClaimFromContext(ctx)
+	if !ok {
+		response.HTTPError(w, r, http.StatusUnauthorized, "autorization is required")
+		return
+	}
+
+	_, err = sr.Repository.GetOne(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	// the ReadTimeout and WriteTimeout of the server would close the
+	// stream, so they are cleared and every write sets its own deadline.
+	rc := http.NewResponseController(w)
+	err = rc.SetReadDeadline(time.Time{})
+	if err == nil {
+		err = rc.SetWriteDeadline(time.Time{})
+	}
+	if err != nil {
+		response.HTTPError(w, r, http.StatusInternalServerError, "streaming is not supported")
+		return
+	}
+
+	// subscribe before reading the log, so no event is lost in between.
+	sub := sr.Hub.Subscribe(event.Channel(event.KindSubject, uint(id)))
+	defer sub.Close()
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	s := &stream{w: w, rc: rc}
+	err = s.write("retry: 5000\n\n")
+	if err != nil {
+		return
+	}
+
+	for lastID > 0 {
+		events, err := sr.Events.GetBySubject(ctx, uint(id), lastID, resumeBatch)
+		if err != nil {
+			return
+		}
+
+		for _, e := range events {
+			err = s.send(e)
+			if err != nil {
+				return
+			}
+
+			lastID = e.ID
+		}
+
+		if len(events) < resumeBatch {
+			break
+		}
+	}
+
+	heartbeat := time.NewTicker(heartbeatPeriod)
+	defer heartbeat.Stop()
+
+	expiration := time.NewTimer(time.Until(time.Unix(c.ExpiresAt, 0)))
+	defer expiration.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-sub.Dropped:
+			return
+		case e := <-sub.Events:
+			// already sent from the log.
+			if e.ID != 0 && e.ID <= lastID {
+				continue
+			}
+
+			err = s.send(e)
+			if e.ID != 0 {
+				lastID = e.ID
+			}
+		case <-heartbeat.C:
+			err = s.write(": heartbeat\n\n")
+		case <-expiration.C:
+			s.write("event: token.expired\ndata: {}\n\n")
+			return
+		}
+
+		if err != nil {
+			return
+		}
+	}
+}
+
+// stream writes Server-Sent Events.
+type stream struct {
+	w  http.ResponseWriter
+	rc *http.ResponseController
+}
+
+// send writes the event with its id and type.
+func (s *stream) send(e event.Event) error {
+	data := e.Data
+	if len(data) == 0 {
+		data = json.RawMessage("{}")
+	}
+
+	msg := fmt.Sprintf("event: %s\ndata: %s\n\n", e.Type, data)
+	if e.ID != 0 {
+		msg = fmt.Sprintf("id: %d\n%s", e.ID, msg)
+	}
+
+	return s.write(msg)
+}
+
+// write writes and flushes the message.
+func (s *stream) write(msg string) error {
+	s.rc.SetWriteDeadline(time.Now().Add(streamWriteWait))
+
+	_, err := fmt.Fprint(s.w, msg)
+	if err != nil {
+		return err
+	}
+
+	return s.rc.Flush()
+}
@@ -1,10 +1,13 @@
 package v1
 
 import (
-	"encoding/json"
 	"fmt"
 	"github.com/go-chi/chi"
 	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/realtime"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/event"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/request"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/subject"
 	"net/http"
@@ -13,24 +16,27 @@ import (
 
 // SubjectRouter is the router of the subjects.
 type SubjectRouter struct {
-	Repository subject.Repository
+	Repository   subject.Repository
+	Events       event.Repository
+	Hub          *realtime.Hub
+	Authorizator func(http.Handler) http.Handler
 }
 
 // CreateHandler Create a new subject.
 func (sr *SubjectRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
-	var s subject.Subject
-	err := json.NewDecoder(r.Body).Decode(&s)
+	var req subject.Request
+	err := request.Decode(w, r, &req)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	defer r.Body.Close()
+	s := req.Subject()
 
 	ctx := r.Context()
 	err = sr.Repository.Create(ctx, &s)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
@@ -40,15 +46,22 @@ func (sr *SubjectRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
 
 // GetAllHandler response all the subjects.
 func (sr *SubjectRouter) GetAllHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	ctx := r.Context()
 
-	subjects, err := sr.Repository.GetAll(ctx)
+	subjects, next, err := sr.Repository.GetAll(ctx, pg)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	response.JSON(w, r, http.StatusOK, response.Map{"posts": subjects})
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"posts": subjects, "next_cursor": next})
 }
 
 // GetOneHandler response one subject by id.
@@ -64,7 +77,7 @@ func (sr *SubjectRouter) GetOneHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	s, err := sr.Repository.GetOne(ctx, uint(id))
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
@@ -81,19 +94,19 @@ func (sr *SubjectRouter) UpdateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var s subject.Subject
-	err = json.NewDecoder(r.Body).Decode(&s)
+	var req subject.Request
+	err = request.Decode(w, r, &req)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	defer r.Body.Close()
+	s := req.Subject()
 
 	ctx := r.Context()
 	err = sr.Repository.Update(ctx, uint(id), s)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
@@ -113,7 +126,7 @@ func (sr *SubjectRouter) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	err = sr.Repository.Delete(ctx, uint(id))
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
@@ -130,33 +143,48 @@ func (sr *SubjectRouter) GetByYearHandler(w http.ResponseWriter, r *http.Request
 		return
 	}
 
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	ctx := r.Context()
-	subjects, err := sr.Repository.GetByYear(ctx, year)
+	subjects, next, err := sr.Repository.GetByYear(ctx, year, pg)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	response.JSON(w, r, http.StatusOK, response.Map{"posts": subjects})
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"posts": subjects, "next_cursor": next})
 }
 
 // Routes returns post router with each endpoint.
 func (sr *SubjectRouter) Routes() http.Handler {
 	r := chi.NewRouter()
 
-	r.Use(middleware.Authorizator)
+	r.Use(sr.Authorizator)
 
 	r.Get("/subject/{year}", sr.GetByYearHandler)
 
 	r.Get("/", sr.GetAllHandler)
 
-	r.Post("/", sr.CreateHandler)
+	r.
+		With(middleware.AdminOnly).
+		Post("/", sr.CreateHandler)
 
 	r.Get("/{id}", sr.GetOneHandler)
 
-	r.Put("/{id}", sr.UpdateHandler)
+	r.Get("/{id}/events", sr.EventsHandler)
+
+	r.
+		With(middleware.AdminOnly).
+		Put("/{id}", sr.UpdateHandler)
 
-	r.Delete("/{id}", sr.DeleteHandler)
+	r.
+		With(middleware.AdminOnly).
+		Delete("/{id}", sr.DeleteHandler)
 
 	return r
 }
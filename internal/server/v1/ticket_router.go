@@ -0,0 +1,49 @@
+package v1
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/ticket"
+)
+
+// TicketRouter is the router of the tickets of the streams.
+type TicketRouter struct {
+	Repository   ticket.Repository
+	Authorizator func(http.Handler) http.Handler
+}
+
+// CreateHandler issues a single-use ticket with the access token of the
+// request, to open a WebSocket or Server-Sent Events stream.
+func (tr *TicketRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+	token, _ := middleware.TokenFromContext(ctx)
+
+	t, plain, err := ticket.New(userID, token)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = tr.Repository.Create(ctx, &t)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusCreated, response.Map{"ticket": plain, "expires_at": t.ExpiresAt})
+}
+
+// Routes returns ticket router with each endpoint. The users in
+// read-only mode can use the streams too.
+func (tr *TicketRouter) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.With(middleware.ReadOnlyAllowed, tr.Authorizator).
+		Post("/", tr.CreateHandler)
+
+	return r
+}
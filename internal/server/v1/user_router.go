@@ -1,39 +1,51 @@
 package v1
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"os"
 	"strconv"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/policy"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/claim"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/refresh"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/request"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/revocation"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/sanction"
 	"github.com/orlmonteverde/go-postgres-microblog/pkg/user"
 )
 
 // UserRouter is the router of the users.
 type UserRouter struct {
-	Repository user.Repository
+	Repository   user.Repository
+	Tokens       refresh.Repository
+	Revocations  revocation.Repository
+	Sanctions    sanction.Repository
+	Policy       *policy.Policy
+	Authorizator func(http.Handler) http.Handler
 }
 
 // CreateHandler Create a new user.
 func (ur *UserRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
-	var u user.User
-	err := json.NewDecoder(r.Body).Decode(&u)
+	var req user.CreateRequest
+	err := request.Decode(w, r, &req)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	defer r.Body.Close()
+	u := req.User()
 
 	ctx := r.Context()
 	err = ur.Repository.Create(ctx, &u)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
@@ -44,15 +56,22 @@ func (ur *UserRouter) CreateHandler(w http.ResponseWriter, r *http.Request) {
 
 // GetAllHandler response all the users.
 func (ur *UserRouter) GetAllHandler(w http.ResponseWriter, r *http.Request) {
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
 	ctx := r.Context()
 
-	users, err := ur.Repository.GetAll(ctx)
+	users, next, err := ur.Repository.GetAll(ctx, pg)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	response.JSON(w, r, http.StatusOK, response.Map{"users": users})
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"users": users, "next_cursor": next})
 }
 
 // GetOneHandler response one user by id.
@@ -68,7 +87,7 @@ func (ur *UserRouter) GetOneHandler(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 	u, err := ur.Repository.GetOne(ctx, uint(id))
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
@@ -85,19 +104,25 @@ func (ur *UserRouter) UpdateHandler(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	var u user.User
-	err = json.NewDecoder(r.Body).Decode(&u)
+	var req user.UpdateRequest
+	err = request.Decode(w, r, &req)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	defer r.Body.Close()
+	u := req.User()
 
 	ctx := r.Context()
+	err = ur.Policy.CanModify(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
 	err = ur.Repository.Update(ctx, uint(id), u)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
@@ -115,9 +140,15 @@ func (ur *UserRouter) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	ctx := r.Context()
+	err = ur.Policy.CanModify(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
 	err = ur.Repository.Delete(ctx, uint(id))
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
@@ -126,35 +157,225 @@ func (ur *UserRouter) DeleteHandler(w http.ResponseWriter, r *http.Request) {
 
 // LoginHandler search user and return a jwt.
 func (ur *UserRouter) LoginHandler(w http.ResponseWriter, r *http.Request) {
-	var u user.User
-	err := json.NewDecoder(r.Body).Decode(&u)
+	var req user.LoginRequest
+	err := request.Decode(w, r, &req)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		response.Error(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	storedUser, err := ur.Repository.GetByUsername(ctx, req.Username)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	if !storedUser.PasswordMatch(req.Password) {
+		response.HTTPError(w, r, http.StatusBadRequest, "password don't match")
+		return
+	}
+
+	s, err := ur.Sanctions.Active(ctx, storedUser.ID)
+	if err != nil {
+		response.Error(w, r, err)
 		return
 	}
 
-	defer r.Body.Close()
+	if s != nil && !s.ReadOnly {
+		response.Error(w, r, s.Err())
+		return
+	}
+
+	token, err := newAccessToken(storedUser)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	rt, refreshToken, err := refresh.New(storedUser.ID, "")
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = ur.Tokens.Create(ctx, &rt)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{
+		"token":         token,
+		"refresh_token": refreshToken,
+		"user":          storedUser,
+		"sanction":      s,
+	})
+}
+
+// AppealHandler adds the appeal of the user to the active sanction.
+// The suspended users can't log in, so they send their credentials.
+func (ur *UserRouter) AppealHandler(w http.ResponseWriter, r *http.Request) {
+	var req sanction.AppealRequest
+	err := request.Decode(w, r, &req)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
 
 	ctx := r.Context()
-	storedUser, err := ur.Repository.GetByUsername(ctx, u.Username)
+	storedUser, err := ur.Repository.GetByUsername(ctx, req.Username)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusNotFound, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	if !storedUser.PasswordMatch(u.Password) {
+	if !storedUser.PasswordMatch(req.Password) {
 		response.HTTPError(w, r, http.StatusBadRequest, "password don't match")
 		return
 	}
 
-	c := claim.Claim{ID: int(storedUser.ID)}
-	token, err := c.GetToken(os.Getenv("SIGNING_STRING"))
+	s, err := ur.Sanctions.Appeal(ctx, storedUser.ID, req.Appeal)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{"sanction": s})
+}
+
+// RefreshHandler exchange a refresh token for a new pair of tokens.
+// Using a refresh token twice revokes every token of its family.
+func (ur *UserRouter) RefreshHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refresh_token" validate:"required"`
+	}
+	err := request.Decode(w, r, &body)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	ctx := r.Context()
+	stored, err := ur.Tokens.GetByHash(ctx, refresh.Hash(body.RefreshToken))
+	if err != nil {
+		response.HTTPError(w, r, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+
+	if stored.RevokedAt != nil {
+		err = ur.Tokens.RevokeFamily(ctx, stored.FamilyID)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		response.HTTPError(w, r, http.StatusUnauthorized, "refresh token reuse detected")
+		return
+	}
+
+	if stored.Expired() {
+		response.HTTPError(w, r, http.StatusUnauthorized, "refresh token is expired")
+		return
+	}
+
+	u, err := ur.Repository.GetOne(ctx, stored.UserID)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusUnauthorized, "invalid refresh token")
+		return
+	}
+
+	rt, refreshToken, err := refresh.New(u.ID, stored.FamilyID)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	err = ur.Tokens.Rotate(ctx, stored.ID, &rt)
+	if errors.Is(err, refresh.ErrAlreadyRevoked) {
+		err = ur.Tokens.RevokeFamily(ctx, stored.FamilyID)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		response.HTTPError(w, r, http.StatusUnauthorized, "refresh token reuse detected")
+		return
+	}
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	token, err := newAccessToken(u)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	response.JSON(w, r, http.StatusOK, response.Map{
+		"token":         token,
+		"refresh_token": refreshToken,
+	})
+}
+
+// LogoutHandler revoke the token used in the request and, if it is
+// sent, the refresh token obtained with it.
+func (ur *UserRouter) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	var body struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if r.ContentLength != 0 {
+		err := request.Decode(w, r, &body)
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+	}
+
+	ctx := r.Context()
+	c, _ := middleware.ClaimFromContext(ctx)
+	err := ur.Revocations.Revoke(ctx, c.Id, uint(c.ID), time.Unix(c.ExpiresAt, 0))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	if body.RefreshToken != "" {
+		stored, err := ur.Tokens.GetByHash(ctx, refresh.Hash(body.RefreshToken))
+		if err == nil && stored.UserID == uint(c.ID) {
+			err = ur.Tokens.RevokeFamily(ctx, stored.FamilyID)
+			if err != nil {
+				response.Error(w, r, err)
+				return
+			}
+		}
+	}
+
+	response.JSON(w, r, http.StatusOK, nil)
+}
+
+// LogoutAllHandler revoke every token of the authenticated user.
+func (ur *UserRouter) LogoutAllHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+	err := ur.Revocations.RevokeAll(ctx, userID)
 	if err != nil {
-		response.HTTPError(w, r, http.StatusInternalServerError, err.Error())
+		response.Error(w, r, err)
 		return
 	}
 
-	response.JSON(w, r, http.StatusOK, response.Map{"token": token, "user": storedUser})
+	response.JSON(w, r, http.StatusOK, nil)
+}
+
+// newAccessToken returns a signed short-lived token for the user.
+func newAccessToken(u user.User) (string, error) {
+	c, err := claim.New(int(u.ID), u.Admin, u.TokenVersion)
+	if err != nil {
+		return "", err
+	}
+
+	return c.GetToken(os.Getenv("SIGNING_STRING"))
 }
 //TODO
 // GetByYearHandler response users by user year.
@@ -165,24 +386,36 @@ func (ur *UserRouter) Routes() http.Handler {
 	r := chi.NewRouter()
 
 	r.
-		With(middleware.Authorizator).
+		With(ur.Authorizator).
 		Get("/", ur.GetAllHandler)
 
 	r.Post("/", ur.CreateHandler)
 
 	r.
-		With(middleware.Authorizator).
+		With(ur.Authorizator).
 		Get("/{id}", ur.GetOneHandler)
 
 	r.
-		With(middleware.Authorizator).
+		With(ur.Authorizator).
 		Put("/{id}", ur.UpdateHandler)
 
 	r.
-		With(middleware.Authorizator).
+		With(ur.Authorizator).
 		Delete("/{id}", ur.DeleteHandler)
 
 	r.Post("/login/", ur.LoginHandler)
 
+	r.Post("/token/refresh", ur.RefreshHandler)
+
+	r.Post("/login/appeal", ur.AppealHandler)
+
+	r.
+		With(middleware.ReadOnlyAllowed, ur.Authorizator).
+		Post("/logout", ur.LogoutHandler)
+
+	r.
+		With(middleware.ReadOnlyAllowed, ur.Authorizator).
+		Post("/logout/all", ur.LogoutAllHandler)
+
 	return r
 }
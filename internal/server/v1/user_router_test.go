@@ -0,0 +1,244 @@
+package v1
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/claim"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/refresh"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/user"
+)
+
+const testSigningString = "secret"
+
+// fakeUsers has the user 7 only.
+type fakeUsers struct {
+	user.Repository
+}
+
+func (fakeUsers) GetOne(ctx context.Context, id uint) (user.User, error) {
+	if id != 7 {
+		return user.User{}, apperror.NotFound("user not found")
+	}
+
+	return user.User{ID: 7, TokenVersion: 1}, nil
+}
+
+// fakeTokens keeps the refresh tokens by hash. rotateErr, when set, is
+// returned by Rotate as if another request had rotated the token first.
+type fakeTokens struct {
+	tokens    map[string]*refresh.Token
+	revoked   []string
+	rotateErr error
+	revokeErr error
+}
+
+func (f *fakeTokens) GetByHash(ctx context.Context, hash string) (refresh.Token, error) {
+	t, ok := f.tokens[hash]
+	if !ok {
+		return refresh.Token{}, apperror.NotFound("refresh token not found")
+	}
+
+	return *t, nil
+}
+
+func (f *fakeTokens) Create(ctx context.Context, token *refresh.Token) error {
+	token.ID = uint(len(f.tokens) + 1)
+	f.tokens[token.Hash] = token
+	return nil
+}
+
+func (f *fakeTokens) Rotate(ctx context.Context, id uint, token *refresh.Token) error {
+	if f.rotateErr != nil {
+		return f.rotateErr
+	}
+
+	for _, t := range f.tokens {
+		if t.ID != id {
+			continue
+		}
+
+		if t.RevokedAt != nil {
+			return refresh.ErrAlreadyRevoked
+		}
+
+		now := time.Now()
+		t.RevokedAt = &now
+		return f.Create(ctx, token)
+	}
+
+	return apperror.NotFound("refresh token not found")
+}
+
+func (f *fakeTokens) RevokeFamily(ctx context.Context, familyID string) error {
+	if f.revokeErr != nil {
+		return f.revokeErr
+	}
+
+	f.revoked = append(f.revoked, familyID)
+
+	now := time.Now()
+	for _, t := range f.tokens {
+		if t.FamilyID == familyID && t.RevokedAt == nil {
+			t.RevokedAt = &now
+		}
+	}
+
+	return nil
+}
+
+// newFakeTokens returns the repository with a token of the user 7 in
+// the family "f1", changed by change, and its plain value.
+func newFakeTokens(t *testing.T, change func(t *refresh.Token)) (*fakeTokens, string) {
+	t.Helper()
+
+	rt, plain, err := refresh.New(7, "f1")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	change(&rt)
+
+	f := &fakeTokens{tokens: map[string]*refresh.Token{}}
+	if err := f.Create(context.Background(), &rt); err != nil {
+		t.Fatal(err)
+	}
+
+	return f, plain
+}
+
+// refreshToken calls the RefreshHandler with the plain refresh token.
+func refreshToken(ur *UserRouter, plain string) *httptest.ResponseRecorder {
+	body := `{"refresh_token": "` + plain + `"}`
+	req := httptest.NewRequest(http.MethodPost, "/token/refresh", strings.NewReader(body))
+	rec := httptest.NewRecorder()
+	ur.RefreshHandler(rec, req)
+	return rec
+}
+
+func TestRefreshHandler(t *testing.T) {
+	t.Setenv("SIGNING_STRING", testSigningString)
+
+	past := time.Now().Add(-time.Minute)
+
+	tests := []struct {
+		name        string
+		change      func(t *refresh.Token)
+		plain       string
+		rotateErr   error
+		revokeErr   error
+		want        int
+		wantRevoked bool
+	}{
+		{name: "rotation", change: func(t *refresh.Token) {}, want: http.StatusOK},
+		{name: "unknown token", change: func(t *refresh.Token) {}, plain: "x", want: http.StatusUnauthorized},
+		{name: "expired", change: func(t *refresh.Token) { t.ExpiresAt = past }, want: http.StatusUnauthorized},
+		{
+			name:        "reuse of a revoked token",
+			change:      func(t *refresh.Token) { t.RevokedAt = &past },
+			want:        http.StatusUnauthorized,
+			wantRevoked: true,
+		},
+		{
+			name:        "rotated by another request",
+			change:      func(t *refresh.Token) {},
+			rotateErr:   refresh.ErrAlreadyRevoked,
+			want:        http.StatusUnauthorized,
+			wantRevoked: true,
+		},
+		{
+			name:      "rotation error",
+			change:    func(t *refresh.Token) {},
+			rotateErr: errors.New("connection refused"),
+			want:      http.StatusInternalServerError,
+		},
+		{
+			name:      "family revocation error",
+			change:    func(t *refresh.Token) { t.RevokedAt = &past },
+			revokeErr: errors.New("connection refused"),
+			want:      http.StatusInternalServerError,
+		},
+		{name: "deleted user", change: func(t *refresh.Token) { t.UserID = 8 }, want: http.StatusUnauthorized},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tokens, plain := newFakeTokens(t, tt.change)
+			tokens.rotateErr = tt.rotateErr
+			tokens.revokeErr = tt.revokeErr
+			if tt.plain != "" {
+				plain = tt.plain
+			}
+
+			ur := &UserRouter{Repository: fakeUsers{}, Tokens: tokens}
+			rec := refreshToken(ur, plain)
+
+			if rec.Code != tt.want {
+				t.Errorf("status = %d, want %d: %s", rec.Code, tt.want, rec.Body)
+			}
+
+			revoked := len(tokens.revoked) == 1 && tokens.revoked[0] == "f1"
+			if revoked != tt.wantRevoked {
+				t.Errorf("revoked families = %v, want f1 revoked %v", tokens.revoked, tt.wantRevoked)
+			}
+		})
+	}
+}
+
+func TestRefreshHandlerReuse(t *testing.T) {
+	t.Setenv("SIGNING_STRING", testSigningString)
+
+	tokens, first := newFakeTokens(t, func(t *refresh.Token) {})
+	ur := &UserRouter{Repository: fakeUsers{}, Tokens: tokens}
+
+	rec := refreshToken(ur, first)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("first refresh: status = %d, want %d: %s", rec.Code, http.StatusOK, rec.Body)
+	}
+
+	var body struct {
+		Token        string `json:"token"`
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&body); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := claim.GetFromToken(body.Token, testSigningString)
+	if err != nil {
+		t.Fatalf("access token: %v", err)
+	}
+
+	if c.ID != 7 || c.Version != 1 {
+		t.Errorf("access token of user %d version %d, want 7, 1", c.ID, c.Version)
+	}
+
+	second := body.RefreshToken
+	if second == "" || second == first {
+		t.Fatalf("refresh token = %q, want a new one", second)
+	}
+
+	if got := tokens.tokens[refresh.Hash(second)]; got == nil || got.FamilyID != "f1" {
+		t.Fatalf("new refresh token = %+v, want it in the family f1", got)
+	}
+
+	// the first token is used again, every token of the family is
+	// revoked, the second one included.
+	for i, plain := range []string{first, second} {
+		rec = refreshToken(ur, plain)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("refresh %d after the reuse: status = %d, want %d", i+1, rec.Code, http.StatusUnauthorized)
+		}
+	}
+
+	if len(tokens.revoked) == 0 || tokens.revoked[0] != "f1" {
+		t.Errorf("revoked families = %v, want f1", tokens.revoked)
+	}
+}
@@ -0,0 +1,96 @@
+package v1
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/post"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/reply"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/request"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/vote"
+)
+
+// voteHandler stores the vote of the body to the target with the id of
+// the URL, or removes it if unvote is true, and responses the totals.
+func voteHandler(votes vote.Repository, target string, unvote bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		idStr := chi.URLParam(r, "id")
+
+		id, err := strconv.Atoi(idStr)
+		if err != nil {
+			response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+			return
+		}
+
+		var req vote.Request
+		if !unvote {
+			err = request.Decode(w, r, &req)
+			if err != nil {
+				response.Error(w, r, err)
+				return
+			}
+		}
+
+		ctx := r.Context()
+		userID, _ := middleware.UserIDFromContext(ctx)
+
+		var totals vote.Totals
+		if unvote {
+			totals, err = votes.Unvote(ctx, target, uint(id), userID)
+		} else {
+			totals, err = votes.Vote(ctx, target, uint(id), userID, req.Value)
+		}
+		if err != nil {
+			response.Error(w, r, err)
+			return
+		}
+
+		response.JSON(w, r, http.StatusOK, response.Map{"votes": totals})
+	}
+}
+
+// myPostVotes sets the vote of the authenticated user in the posts.
+func myPostVotes(ctx context.Context, votes vote.Repository, posts []post.Post) error {
+	userID, _ := middleware.UserIDFromContext(ctx)
+
+	ids := make([]uint, len(posts))
+	for i, p := range posts {
+		ids[i] = p.ID
+	}
+
+	mine, err := votes.Mine(ctx, vote.TargetPost, userID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range posts {
+		posts[i].MyVote = mine[posts[i].ID]
+	}
+
+	return nil
+}
+
+// myReplyVotes sets the vote of the authenticated user in the replies.
+func myReplyVotes(ctx context.Context, votes vote.Repository, replies []reply.Reply) error {
+	userID, _ := middleware.UserIDFromContext(ctx)
+
+	ids := make([]uint, len(replies))
+	for i, r := range replies {
+		ids[i] = r.ID
+	}
+
+	mine, err := votes.Mine(ctx, vote.TargetReply, userID, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range replies {
+		replies[i].MyVote = mine[replies[i].ID]
+	}
+
+	return nil
+}
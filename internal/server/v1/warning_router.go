@@ -0,0 +1,64 @@
+package v1
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/policy"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/report"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+)
+
+// WarningRouter is the router of the warnings of a user.
+type WarningRouter struct {
+	Repository   report.Repository
+	Policy       *policy.Policy
+	Authorizator func(http.Handler) http.Handler
+}
+
+// GetWarningsHandler response the warnings of the user, only for the
+// user and the admins.
+func (wr *WarningRouter) GetWarningsHandler(w http.ResponseWriter, r *http.Request) {
+	idStr := chi.URLParam(r, "id")
+
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	pg, err := page.FromRequest(r)
+	if err != nil {
+		response.HTTPError(w, r, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ctx := r.Context()
+	err = wr.Policy.CanModify(ctx, uint(id))
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	warnings, next, err := wr.Repository.Warnings(ctx, uint(id), pg)
+	if err != nil {
+		response.Error(w, r, err)
+		return
+	}
+
+	page.SetLink(w, r, next)
+	response.JSON(w, r, http.StatusOK, response.Map{"warnings": warnings, "next_cursor": next})
+}
+
+// Routes returns warning router with each endpoint.
+func (wr *WarningRouter) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(wr.Authorizator)
+
+	r.Get("/", wr.GetWarningsHandler)
+
+	return r
+}
@@ -0,0 +1,58 @@
+package v1
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/gorilla/websocket"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/middleware"
+	"github.com/orlmonteverde/go-postgres-microblog/internal/realtime"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/response"
+)
+
+var upgrader = websocket.Upgrader{
+	ReadBufferSize:  1024,
+	WriteBufferSize: 1024,
+	// the API is authenticated with tokens, not cookies, so any origin
+	// allowed by CORS can connect.
+	CheckOrigin: func(r *http.Request) bool { return true },
+}
+
+// WSRouter is the router of the real-time updates over WebSocket.
+type WSRouter struct {
+	Hub          *realtime.Hub
+	Authorizator func(http.Handler) http.Handler
+}
+
+// ConnectHandler upgrades the connection to WebSocket. The clients
+// subscribe to the channels post:{id}, subject:{id} and user:{id}
+// sending {"action": "subscribe", "channel": "post:12"}.
+func (wr *WSRouter) ConnectHandler(w http.ResponseWriter, r *http.Request) {
+	ctx := r.Context()
+	userID, _ := middleware.UserIDFromContext(ctx)
+	c, ok := middleware.ClaimFromContext(ctx)
+	if !ok {
+		response.HTTPError(w, r, http.StatusUnauthorized, "autorization is required")
+		return
+	}
+
+	// Upgrade writes the error response itself.
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+
+	wr.Hub.Serve(conn, userID, time.Unix(c.ExpiresAt, 0))
+}
+
+// Routes returns websocket router with each endpoint.
+func (wr *WSRouter) Routes() http.Handler {
+	r := chi.NewRouter()
+
+	r.Use(wr.Authorizator)
+
+	r.Get("/", wr.ConnectHandler)
+
+	return r
+}
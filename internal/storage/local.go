@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/attachment"
+)
+
+// errInvalidKey is returned for the keys that would leave the directory.
+var errInvalidKey = errors.New("storage: invalid key")
+
+// Local keeps the files in a directory of the local filesystem.
+type Local struct {
+	Dir string
+}
+
+// NewLocal returns a storage in the directory, creating it if needed.
+func NewLocal(dir string) (*Local, error) {
+	err := os.MkdirAll(dir, 0o750)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Local{Dir: dir}, nil
+}
+
+// Put writes the file. It is written to a temporary file first, so a
+// failed upload never leaves a partial file.
+func (l *Local) Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.MkdirAll(filepath.Dir(path), 0o750)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, r)
+	if err != nil {
+		tmp.Close()
+		return err
+	}
+
+	err = tmp.Close()
+	if err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// Get opens the file.
+func (l *Local) Get(ctx context.Context, key string) (io.ReadCloser, error) {
+	path, err := l.path(key)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, attachment.ErrFileNotFound
+	}
+
+	return f, err
+}
+
+// Delete removes the file, if it exists.
+func (l *Local) Delete(ctx context.Context, key string) error {
+	path, err := l.path(key)
+	if err != nil {
+		return err
+	}
+
+	err = os.Remove(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	return nil
+}
+
+// path returns the path of the file of the key.
+func (l *Local) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || clean != "/"+key || strings.Contains(key, `\`) {
+		return "", errInvalidKey
+	}
+
+	return filepath.Join(l.Dir, filepath.FromSlash(clean)), nil
+}
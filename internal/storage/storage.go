@@ -0,0 +1,41 @@
+// Package storage keeps the files of the attachments in the local
+// filesystem or in an S3-compatible service.
+package storage
+
+import (
+	"errors"
+	"os"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/attachment"
+)
+
+// New returns the storage configured in STORAGE: "s3" uses the bucket
+// S3_BUCKET of S3_ENDPOINT, any other value the directory STORAGE_PATH.
+func New() (attachment.Storage, error) {
+	if os.Getenv("STORAGE") != "s3" {
+		dir := os.Getenv("STORAGE_PATH")
+		if dir == "" {
+			dir = "uploads"
+		}
+
+		return NewLocal(dir)
+	}
+
+	s := &S3{
+		Endpoint:  os.Getenv("S3_ENDPOINT"),
+		Region:    os.Getenv("S3_REGION"),
+		Bucket:    os.Getenv("S3_BUCKET"),
+		AccessKey: os.Getenv("S3_ACCESS_KEY"),
+		SecretKey: os.Getenv("S3_SECRET_KEY"),
+	}
+
+	if s.Endpoint == "" || s.Bucket == "" {
+		return nil, errors.New("storage: S3_ENDPOINT and S3_BUCKET are required")
+	}
+
+	if s.Region == "" {
+		s.Region = "us-east-1"
+	}
+
+	return s, nil
+}
@@ -0,0 +1,83 @@
+package apperror
+
+import "errors"
+
+// Kinds of the domain errors.
+var (
+	ErrNotFound   = errors.New("not found")
+	ErrConflict   = errors.New("conflict")
+	ErrValidation = errors.New("validation failed")
+	ErrForbidden  = errors.New("forbidden")
+	ErrTooLarge   = errors.New("too large")
+)
+
+// codes of the kinds, used when the error has no specific code.
+var kindCodes = map[error]string{
+	ErrNotFound:   "not_found",
+	ErrConflict:   "conflict",
+	ErrValidation: "validation_failed",
+	ErrForbidden:  "forbidden",
+	ErrTooLarge:   "too_large",
+}
+
+// FieldError is a violation of a field of the request.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Error is a domain error whose message is safe to show to the clients.
+// Use errors.Is with the kinds to check it.
+type Error struct {
+	Kind    error
+	Code    string
+	Message string
+	Fields  []FieldError
+}
+
+// New returns an error of the kind with a stable machine-readable code
+// and the fields that caused it, if any.
+func New(kind error, code, message string, fields ...FieldError) error {
+	return &Error{Kind: kind, Code: code, Message: message, Fields: fields}
+}
+
+// Error returns the message of the error.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the kind of the error.
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// ErrorCode returns the code of the error or, if it has none, the
+// code of its kind.
+func (e *Error) ErrorCode() string {
+	if e.Code != "" {
+		return e.Code
+	}
+
+	return kindCodes[e.Kind]
+}
+
+// NotFound returns an error of kind ErrNotFound.
+func NotFound(message string) error {
+	return &Error{Kind: ErrNotFound, Message: message}
+}
+
+// Conflict returns an error of kind ErrConflict.
+func Conflict(message string) error {
+	return &Error{Kind: ErrConflict, Message: message}
+}
+
+// Validation returns an error of kind ErrValidation.
+func Validation(message string) error {
+	return &Error{Kind: ErrValidation, Message: message}
+}
+
+// Forbidden returns an error of kind ErrForbidden.
+func Forbidden(message string) error {
+	return &Error{Kind: ErrForbidden, Message: message}
+}
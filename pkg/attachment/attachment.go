@@ -0,0 +1,132 @@
+package attachment
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+)
+
+const (
+	// MaxSize is the maximum size in bytes of a file.
+	MaxSize = 10 << 20
+	// Quota is the maximum size in bytes of all the files of a user.
+	Quota = 100 << 20
+	// MaxPerItem is the maximum number of files of a post or reply.
+	MaxPerItem = 10
+	// URLExpiration is the time the download URLs are valid.
+	URLExpiration = 15 * time.Minute
+)
+
+// Types are the content types allowed, detected from the content of
+// the files and not from their names.
+var Types = map[string]bool{
+	"application/pdf": true,
+	"image/gif":       true,
+	"image/jpeg":      true,
+	"image/png":       true,
+	"image/webp":      true,
+	"text/plain":      true,
+}
+
+// Errors of the uploads.
+var (
+	ErrTooLarge = apperror.New(apperror.ErrTooLarge, "file_too_large",
+		fmt.Sprintf("the file must not be larger than %d bytes", MaxSize))
+	ErrQuotaExceeded = apperror.New(apperror.ErrTooLarge, "quota_exceeded",
+		fmt.Sprintf("your files must not be larger than %d bytes in total", Quota))
+	ErrTooMany = apperror.New(apperror.ErrConflict, "too_many_attachments",
+		fmt.Sprintf("a post or reply can't have more than %d files", MaxPerItem))
+	ErrType = apperror.New(apperror.ErrValidation, "validation_failed",
+		"the request has invalid fields",
+		apperror.FieldError{Field: "file", Code: "invalid_type", Message: "must be a PDF, an image or a text file"})
+)
+
+// Attachment is a file of a post or a reply, PostID or ReplyID is 0.
+// URL is a temporary link to download it.
+type Attachment struct {
+	ID          uint      `json:"id,omitempty"`
+	UserID      uint      `json:"user_id,omitempty"`
+	PostID      uint      `json:"post_id,omitempty"`
+	ReplyID     uint      `json:"reply_id,omitempty"`
+	Filename    string    `json:"filename"`
+	ContentType string    `json:"content_type"`
+	Size        int64     `json:"size"`
+	StorageKey  string    `json:"-"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+	URL         string    `json:"url,omitempty"`
+}
+
+// Usage is the space used by the files of a user.
+type Usage struct {
+	Used  int64 `json:"used"`
+	Quota int64 `json:"quota"`
+}
+
+// Sniff returns the content type of the first bytes of a file, without
+// parameters, or ErrType if it is not allowed.
+func Sniff(head []byte) (string, error) {
+	contentType, _, err := mime.ParseMediaType(http.DetectContentType(head))
+	if err != nil || !Types[contentType] {
+		return "", ErrType
+	}
+
+	return contentType, nil
+}
+
+// Filename returns the name of the file sent by the client without
+// directories nor control characters, up to 255 characters.
+func Filename(name string) string {
+	name = filepath.Base(strings.ReplaceAll(name, `\`, "/"))
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+
+		return r
+	}, name)
+
+	if name == "." || name == "/" || name == "" {
+		return "file"
+	}
+
+	// the end is kept for the extension, on a rune boundary.
+	if runes := []rune(name); len(runes) > 255 {
+		name = string(runes[len(runes)-255:])
+	}
+
+	return name
+}
+
+// urlKey is the purpose of the key derived from the secret to sign the
+// download URLs, so they are not signed with the key of the tokens.
+const urlKey = "attachment-url"
+
+// Sign returns the signature of the download URL of the attachment
+// that expires at the Unix time.
+func Sign(secret string, id uint, expires int64) string {
+	key := hmac.New(sha256.New, []byte(secret))
+	key.Write([]byte(urlKey))
+
+	mac := hmac.New(sha256.New, key.Sum(nil))
+	fmt.Fprintf(mac, "%d:%d", id, expires)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether the signature of the download URL is valid and
+// has not expired.
+func Verify(secret string, id uint, expires int64, signature string) bool {
+	if time.Now().Unix() > expires {
+		return false
+	}
+
+	return hmac.Equal([]byte(Sign(secret, id, expires)), []byte(signature))
+}
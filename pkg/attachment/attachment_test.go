@@ -0,0 +1,70 @@
+package attachment
+
+import (
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestFilename(t *testing.T) {
+	long := strings.Repeat("ñ", 300) + ".png"
+
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "plain", in: "a.png", want: "a.png"},
+		{name: "directories", in: "../../etc/passwd", want: "passwd"},
+		{name: "windows directories", in: `C:\Users\a\b.txt`, want: "b.txt"},
+		{name: "control characters", in: "a\x00b\nc.txt", want: "abc.txt"},
+		{name: "empty", in: "", want: "file"},
+		{name: "only a directory", in: "a/", want: "a"},
+		{name: "root", in: "/", want: "file"},
+		{name: "long multibyte name", in: long, want: strings.Repeat("ñ", 251) + ".png"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Filename(tt.in)
+			if got != tt.want {
+				t.Errorf("Filename(%q) = %q, want %q", tt.in, got, tt.want)
+			}
+
+			if !utf8.ValidString(got) {
+				t.Errorf("Filename(%q) = %q is not valid UTF-8", tt.in, got)
+			}
+		})
+	}
+}
+
+func TestVerify(t *testing.T) {
+	expires := time.Now().Add(time.Hour).Unix()
+	signature := Sign("secret", 1, expires)
+
+	tests := []struct {
+		name      string
+		secret    string
+		id        uint
+		expires   int64
+		signature string
+		want      bool
+	}{
+		{name: "valid", secret: "secret", id: 1, expires: expires, signature: signature, want: true},
+		{name: "other secret", secret: "other", id: 1, expires: expires, signature: signature},
+		{name: "other attachment", secret: "secret", id: 2, expires: expires, signature: signature},
+		{name: "other expiration", secret: "secret", id: 1, expires: expires + 1, signature: signature},
+		{name: "expired", secret: "secret", id: 1, expires: 1, signature: Sign("secret", 1, 1)},
+		{name: "empty signature", secret: "secret", id: 1, expires: expires},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Verify(tt.secret, tt.id, tt.expires, tt.signature)
+			if got != tt.want {
+				t.Errorf("Verify() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
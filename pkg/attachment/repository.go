@@ -0,0 +1,17 @@
+package attachment
+
+import "context"
+
+// Repository handle the metadata of the attachments.
+type Repository interface {
+	GetOne(ctx context.Context, id uint) (Attachment, error)
+	GetByPost(ctx context.Context, postID uint) ([]Attachment, error)
+	GetByReply(ctx context.Context, replyID uint) ([]Attachment, error)
+	Usage(ctx context.Context, userID uint) (Usage, error)
+	Create(ctx context.Context, a *Attachment) error
+	Delete(ctx context.Context, id uint) error
+	// Deletions returns the keys of the files of the deleted
+	// attachments, Forget removes them once the files are deleted.
+	Deletions(ctx context.Context, limit int) ([]string, error)
+	Forget(ctx context.Context, keys []string) error
+}
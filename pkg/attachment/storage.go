@@ -0,0 +1,18 @@
+package attachment
+
+import (
+	"context"
+	"io"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+)
+
+// ErrFileNotFound is returned when the file is not in the storage.
+var ErrFileNotFound = apperror.NotFound("file not found")
+
+// Storage keeps the content of the files by key.
+type Storage interface {
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	Get(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,170 @@
+// Package avatar processes the pictures uploaded by the users and
+// generates the default ones.
+package avatar
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"io"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+	"golang.org/x/image/draw"
+
+	// registering the formats of the uploads.
+	_ "image/gif"
+	_ "image/png"
+
+	_ "golang.org/x/image/webp"
+)
+
+const (
+	// MaxSize is the maximum size in bytes of an upload.
+	MaxSize = 5 << 20
+	// MaxDimension is the maximum width and height of an upload, bigger
+	// images would need too much memory to be decoded.
+	MaxDimension = 2048
+	// MaxPixels is the maximum number of pixels of an upload, the
+	// decoded image needs 4 bytes for each one.
+	MaxPixels = MaxDimension * MaxDimension
+	// DefaultSize is the size served when no size is requested.
+	DefaultSize = 128
+
+	quality = 85
+)
+
+// Sizes are the sizes in pixels of the square pictures generated.
+var Sizes = []int{64, 128, 300}
+
+// Errors of the uploads.
+var (
+	ErrTooLarge = apperror.New(apperror.ErrTooLarge, "file_too_large",
+		fmt.Sprintf("the picture must not be larger than %d bytes", MaxSize))
+	ErrFormat = apperror.New(apperror.ErrValidation, "validation_failed",
+		"the request has invalid fields",
+		apperror.FieldError{Field: "file", Code: "invalid_type", Message: "must be a JPEG, PNG, GIF or WebP image"})
+	ErrDimensions = apperror.New(apperror.ErrValidation, "validation_failed",
+		"the request has invalid fields",
+		apperror.FieldError{Field: "file", Code: "invalid_dimensions",
+			Message: fmt.Sprintf("must not be wider nor taller than %d pixels", MaxDimension)})
+)
+
+// ValidSize reports whether the size is one of Sizes.
+func ValidSize(size int) bool {
+	for _, s := range Sizes {
+		if s == size {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Process decodes the image and returns it as a JPEG of each of Sizes,
+// cropped to a square from its center. The EXIF orientation of the
+// JPEG images is applied, and the metadata is not copied.
+func Process(r io.Reader) (map[int][]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, MaxSize+1))
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) > MaxSize {
+		return nil, ErrTooLarge
+	}
+
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrFormat
+	}
+
+	if cfg.Width > MaxDimension || cfg.Height > MaxDimension || cfg.Width <= 0 || cfg.Height <= 0 ||
+		int64(cfg.Width)*int64(cfg.Height) > MaxPixels {
+		return nil, ErrDimensions
+	}
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, ErrFormat
+	}
+
+	// the square is scaled to the biggest size before it is turned, so
+	// only the pixels of that size are copied, over a white background
+	// so the transparent pixels are not black in the JPEG.
+	largest := 0
+	for _, size := range Sizes {
+		if size > largest {
+			largest = size
+		}
+	}
+
+	square := image.NewRGBA(image.Rect(0, 0, largest, largest))
+	draw.Draw(square, square.Bounds(), image.NewUniform(color.White), image.Point{}, draw.Src)
+	draw.CatmullRom.Scale(square, square.Bounds(), img, crop(img), draw.Over, nil)
+	square = orient(square, orientation(data))
+
+	pictures := make(map[int][]byte, len(Sizes))
+	for _, size := range Sizes {
+		dst := square
+		if size != largest {
+			dst = image.NewRGBA(image.Rect(0, 0, size, size))
+			draw.CatmullRom.Scale(dst, dst.Bounds(), square, square.Bounds(), draw.Src, nil)
+		}
+
+		var buf bytes.Buffer
+		err := jpeg.Encode(&buf, dst, &jpeg.Options{Quality: quality})
+		if err != nil {
+			return nil, err
+		}
+
+		pictures[size] = buf.Bytes()
+	}
+
+	return pictures, nil
+}
+
+// crop returns the biggest square of the center of the image.
+func crop(img image.Image) image.Rectangle {
+	b := img.Bounds()
+	side := b.Dx()
+	if b.Dy() < side {
+		side = b.Dy()
+	}
+
+	x := b.Min.X + (b.Dx()-side)/2
+	y := b.Min.Y + (b.Dy()-side)/2
+	return image.Rect(x, y, x+side, y+side)
+}
+
+// orient returns the square turned by the EXIF orientation o.
+func orient(square *image.RGBA, o int) *image.RGBA {
+	if o < 2 || o > 8 {
+		return square
+	}
+
+	// dst(x, y) = src(at(x, y)) for each orientation, see the
+	// Orientation tag of the EXIF specification.
+	n := square.Bounds().Dx()
+	last := n - 1
+	at := map[int]func(x, y int) (int, int){
+		2: func(x, y int) (int, int) { return last - x, y },
+		3: func(x, y int) (int, int) { return last - x, last - y },
+		4: func(x, y int) (int, int) { return x, last - y },
+		5: func(x, y int) (int, int) { return y, x },
+		6: func(x, y int) (int, int) { return y, last - x },
+		7: func(x, y int) (int, int) { return last - y, last - x },
+		8: func(x, y int) (int, int) { return last - y, x },
+	}[o]
+
+	turned := image.NewRGBA(square.Bounds())
+	for y := 0; y < n; y++ {
+		for x := 0; x < n; x++ {
+			sx, sy := at(x, y)
+			turned.SetRGBA(x, y, square.RGBAAt(sx, sy))
+		}
+	}
+
+	return turned
+}
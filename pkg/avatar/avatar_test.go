@@ -0,0 +1,103 @@
+package avatar
+
+import (
+	"bytes"
+	"errors"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"testing"
+)
+
+func TestOrient(t *testing.T) {
+	// a b
+	// c d
+	a := color.RGBA{R: 1, A: 255}
+	b := color.RGBA{R: 2, A: 255}
+	c := color.RGBA{R: 3, A: 255}
+	d := color.RGBA{R: 4, A: 255}
+
+	tests := []struct {
+		o    int
+		want [4]color.RGBA
+	}{
+		{o: 0, want: [4]color.RGBA{a, b, c, d}},
+		{o: 1, want: [4]color.RGBA{a, b, c, d}},
+		{o: 2, want: [4]color.RGBA{b, a, d, c}},
+		{o: 3, want: [4]color.RGBA{d, c, b, a}},
+		{o: 4, want: [4]color.RGBA{c, d, a, b}},
+		{o: 5, want: [4]color.RGBA{a, c, b, d}},
+		{o: 6, want: [4]color.RGBA{c, a, d, b}},
+		{o: 7, want: [4]color.RGBA{d, b, c, a}},
+		{o: 8, want: [4]color.RGBA{b, d, a, c}},
+		{o: 9, want: [4]color.RGBA{a, b, c, d}},
+	}
+
+	for _, tt := range tests {
+		square := image.NewRGBA(image.Rect(0, 0, 2, 2))
+		square.SetRGBA(0, 0, a)
+		square.SetRGBA(1, 0, b)
+		square.SetRGBA(0, 1, c)
+		square.SetRGBA(1, 1, d)
+
+		turned := orient(square, tt.o)
+		got := [4]color.RGBA{turned.RGBAAt(0, 0), turned.RGBAAt(1, 0), turned.RGBAAt(0, 1), turned.RGBAAt(1, 1)}
+		if got != tt.want {
+			t.Errorf("orient(%d) = %v, want %v", tt.o, got, tt.want)
+		}
+	}
+}
+
+// encodePNG returns a PNG of the size.
+func encodePNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, image.NewNRGBA(image.Rect(0, 0, width, height)))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestProcess(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		err  error
+	}{
+		{name: "landscape", data: encodePNG(t, 40, 20)},
+		{name: "portrait", data: encodePNG(t, 20, 400)},
+		{name: "max dimension", data: encodePNG(t, MaxDimension, 1)},
+		{name: "too wide", data: encodePNG(t, MaxDimension+1, 1), err: ErrDimensions},
+		{name: "too tall", data: encodePNG(t, 1, MaxDimension+1), err: ErrDimensions},
+		{name: "not an image", data: []byte("hello"), err: ErrFormat},
+		{name: "too large", data: make([]byte, MaxSize+1), err: ErrTooLarge},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			pictures, err := Process(bytes.NewReader(tt.data))
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("Process() error = %v, want %v", err, tt.err)
+			}
+
+			if tt.err != nil {
+				return
+			}
+
+			for _, size := range Sizes {
+				cfg, err := jpeg.DecodeConfig(bytes.NewReader(pictures[size]))
+				if err != nil {
+					t.Fatalf("size %d: %v", size, err)
+				}
+
+				if cfg.Width != size || cfg.Height != size {
+					t.Errorf("size %d: got %dx%d", size, cfg.Width, cfg.Height)
+				}
+			}
+		})
+	}
+}
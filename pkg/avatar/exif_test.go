@@ -0,0 +1,134 @@
+package avatar
+
+import (
+	"encoding/binary"
+	"testing"
+)
+
+// jpegWithExif returns the start of a JPEG with an APP1 segment with
+// the TIFF structure and the segments before and after it.
+func jpegWithExif(tiff []byte, before, after []byte) []byte {
+	app1 := append([]byte("Exif\x00\x00"), tiff...)
+
+	data := []byte{0xFF, 0xD8}
+	data = append(data, before...)
+	data = append(data, 0xFF, 0xE1)
+	data = binary.BigEndian.AppendUint16(data, uint16(len(app1)+2))
+	data = append(data, app1...)
+	data = append(data, after...)
+	return data
+}
+
+// tiffWith returns a TIFF structure with one IFD of the tags and their
+// values, in the byte order.
+func tiffWith(order binary.AppendByteOrder, tags map[uint16]uint16) []byte {
+	tiff := []byte("II")
+	if order == binary.BigEndian {
+		tiff = []byte("MM")
+	}
+
+	tiff = order.AppendUint16(tiff, 42)
+	tiff = order.AppendUint32(tiff, 8)
+	tiff = order.AppendUint16(tiff, uint16(len(tags)))
+	for tag, value := range tags {
+		tiff = order.AppendUint16(tiff, tag)
+		tiff = order.AppendUint16(tiff, 3) // SHORT
+		tiff = order.AppendUint32(tiff, 1)
+		tiff = order.AppendUint16(tiff, value)
+		tiff = order.AppendUint16(tiff, 0)
+	}
+
+	return order.AppendUint32(tiff, 0)
+}
+
+func TestOrientation(t *testing.T) {
+	app0 := []byte{0xFF, 0xE0, 0x00, 0x04, 'J', 'F'}
+	sos := []byte{0xFF, 0xDA, 0x00, 0x02}
+
+	tests := []struct {
+		name string
+		data []byte
+		want int
+	}{
+		{
+			name: "little endian",
+			data: jpegWithExif(tiffWith(binary.LittleEndian, map[uint16]uint16{orientationTag: 6}), nil, sos),
+			want: 6,
+		},
+		{
+			name: "big endian",
+			data: jpegWithExif(tiffWith(binary.BigEndian, map[uint16]uint16{orientationTag: 8}), nil, sos),
+			want: 8,
+		},
+		{
+			name: "after other segments",
+			data: jpegWithExif(tiffWith(binary.BigEndian, map[uint16]uint16{orientationTag: 3}), app0, sos),
+			want: 3,
+		},
+		{
+			name: "without the orientation tag",
+			data: jpegWithExif(tiffWith(binary.LittleEndian, map[uint16]uint16{0x010F: 1}), nil, sos),
+			want: 0,
+		},
+		{
+			name: "without exif",
+			data: append([]byte{0xFF, 0xD8}, append(app0, sos...)...),
+			want: 0,
+		},
+		{
+			name: "exif after the image data",
+			data: append(append([]byte{0xFF, 0xD8}, sos...),
+				jpegWithExif(tiffWith(binary.LittleEndian, map[uint16]uint16{orientationTag: 6}), nil, nil)[2:]...),
+			want: 0,
+		},
+		{
+			name: "unknown byte order",
+			data: jpegWithExif(append([]byte("XX"), tiffWith(binary.LittleEndian, map[uint16]uint16{orientationTag: 6})[2:]...), nil, sos),
+			want: 0,
+		},
+		{
+			name: "IFD offset out of the segment",
+			data: jpegWithExif([]byte{'I', 'I', 42, 0, 0xFF, 0, 0, 0}, nil, sos),
+			want: 0,
+		},
+		{
+			name: "truncated entries",
+			data: jpegWithExif([]byte{'I', 'I', 42, 0, 8, 0, 0, 0, 5, 0, 0x12, 0x01}, nil, sos),
+			want: 0,
+		},
+		{
+			name: "segment longer than the data",
+			data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0xFF, 0xFF, 'E', 'x'},
+			want: 0,
+		},
+		{
+			name: "segment length too short",
+			data: []byte{0xFF, 0xD8, 0xFF, 0xE1, 0x00, 0x01, 0xFF, 0xDA},
+			want: 0,
+		},
+		{
+			name: "not a marker",
+			data: []byte{0xFF, 0xD8, 0x00, 0xE1, 0x00, 0x02},
+			want: 0,
+		},
+		{
+			name: "not a JPEG",
+			data: []byte("\x89PNG\r\n\x1a\n"),
+			want: 0,
+		},
+		{
+			name: "empty",
+			data: nil,
+			want: 0,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := orientation(tt.data)
+			if got != tt.want {
+				t.Errorf("orientation() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,58 @@
+package avatar
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// identiconGrid is the number of cells of each side of the identicons.
+const identiconGrid = 5
+
+// Identicon returns a PNG of size pixels generated from the seed: a
+// symmetric grid of cells of a color, both taken from the hash of the
+// seed, so the same seed always has the same picture.
+func Identicon(seed string, size int) ([]byte, error) {
+	sum := sha256.Sum256([]byte(seed))
+
+	fg := color.RGBA{R: sum[0]/2 + 64, G: sum[1]/2 + 64, B: sum[2]/2 + 64, A: 255}
+	bg := color.RGBA{R: 240, G: 240, B: 240, A: 255}
+
+	// the left half and the middle column are taken from the hash, the
+	// right half mirrors the left one.
+	var cells [identiconGrid][identiconGrid]bool
+	half := (identiconGrid + 1) / 2
+	for y := 0; y < identiconGrid; y++ {
+		for x := 0; x < half; x++ {
+			on := sum[3+y*half+x]%2 == 0
+			cells[y][x] = on
+			cells[y][identiconGrid-1-x] = on
+		}
+	}
+
+	// a margin of half a cell around the grid.
+	cell := float64(size) / (identiconGrid + 1)
+	margin := cell / 2
+
+	img := image.NewPaletted(image.Rect(0, 0, size, size), color.Palette{bg, fg})
+	for py := 0; py < size; py++ {
+		for px := 0; px < size; px++ {
+			x := int((float64(px) - margin) / cell)
+			y := int((float64(py) - margin) / cell)
+			if float64(px) >= margin && float64(py) >= margin &&
+				x < identiconGrid && y < identiconGrid && cells[y][x] {
+				img.SetColorIndex(px, py, 1)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	err := png.Encode(&buf, img)
+	if err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
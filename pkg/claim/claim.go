@@ -1,15 +1,85 @@
 package claim
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"errors"
+	"time"
 
 	jwt "github.com/dgrijalva/jwt-go"
 )
 
+// Values of the registered claims of the access tokens.
+const (
+	Issuer   = "einatic"
+	Audience = "einatic-api"
+	TTL      = 15 * time.Minute
+)
+
 // Claim to JWT.
 type Claim struct {
 	jwt.StandardClaims
-	ID int `json:"id"`
+	ID      int  `json:"id"`
+	Admin   bool `json:"admin"`
+	Version int  `json:"ver"`
+}
+
+// New returns a claim for the user that expires after TTL.
+// version is the token version of the user, increasing it revokes
+// every token issued before.
+func New(id int, admin bool, version int) (*Claim, error) {
+	jti, err := newID()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	c := &Claim{
+		StandardClaims: jwt.StandardClaims{
+			Audience:  Audience,
+			ExpiresAt: now.Add(TTL).Unix(),
+			Id:        jti,
+			IssuedAt:  now.Unix(),
+			Issuer:    Issuer,
+		},
+		ID:      id,
+		Admin:   admin,
+		Version: version,
+	}
+
+	return c, nil
+}
+
+// Valid checks the time based claims, the issuer and the audience.
+// Unlike jwt.StandardClaims all of them are required.
+func (c Claim) Valid() error {
+	now := jwt.TimeFunc().Unix()
+
+	if !c.VerifyExpiresAt(now, true) {
+		return errors.New("token is expired")
+	}
+
+	if !c.VerifyIssuedAt(now, true) {
+		return errors.New("token used before issued")
+	}
+
+	if !c.VerifyIssuer(Issuer, true) {
+		return errors.New("invalid token issuer")
+	}
+
+	if !c.VerifyAudience(Audience, true) {
+		return errors.New("invalid token audience")
+	}
+
+	if c.Id == "" {
+		return errors.New("token id not found")
+	}
+
+	if c.ID <= 0 {
+		return errors.New("invalid user id")
+	}
+
+	return nil
 }
 
 // GetToken returns a token with the claim.
@@ -20,7 +90,11 @@ func (c *Claim) GetToken(signingString string) (string, error) {
 
 // GetFromToken returns a claim from a token.
 func GetFromToken(tokenString, signingString string) (*Claim, error) {
-	token, err := jwt.Parse(tokenString, func(*jwt.Token) (interface{}, error) {
+	token, err := jwt.ParseWithClaims(tokenString, &Claim{}, func(t *jwt.Token) (interface{}, error) {
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, errors.New("unexpected signing method")
+		}
+
 		return []byte(signingString), nil
 	})
 	if err != nil {
@@ -31,20 +105,20 @@ func GetFromToken(tokenString, signingString string) (*Claim, error) {
 		return nil, errors.New("invalid token")
 	}
 
-	claim, ok := token.Claims.(jwt.MapClaims)
+	claim, ok := token.Claims.(*Claim)
 	if !ok {
 		return nil, errors.New("invalid claim")
 	}
 
-	iID, ok := claim["id"]
-	if !ok {
-		return nil, errors.New("user id not found")
-	}
+	return claim, nil
+}
 
-	id, ok := iID.(float64)
-	if !ok {
-		return nil, errors.New("invalid user id")
+// newID returns a random identifier for the jti claim.
+func newID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
 	}
 
-	return &Claim{ID: int(id)}, nil
+	return hex.EncodeToString(b), nil
 }
@@ -0,0 +1,146 @@
+package claim
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"testing"
+	"time"
+
+	jwt "github.com/dgrijalva/jwt-go"
+)
+
+const testSigningString = "secret"
+
+// validClaim returns a claim that passes every check.
+func validClaim() Claim {
+	now := time.Now()
+	return Claim{
+		StandardClaims: jwt.StandardClaims{
+			Audience:  Audience,
+			ExpiresAt: now.Add(TTL).Unix(),
+			Id:        "jti",
+			IssuedAt:  now.Unix(),
+			Issuer:    Issuer,
+		},
+		ID: 7,
+	}
+}
+
+func TestClaimValid(t *testing.T) {
+	tests := []struct {
+		name    string
+		change  func(c *Claim)
+		wantErr bool
+	}{
+		{name: "valid", change: func(c *Claim) {}},
+		{name: "expired", change: func(c *Claim) { c.ExpiresAt = time.Now().Add(-time.Minute).Unix() }, wantErr: true},
+		{name: "without expiration", change: func(c *Claim) { c.ExpiresAt = 0 }, wantErr: true},
+		{name: "issued in the future", change: func(c *Claim) { c.IssuedAt = time.Now().Add(time.Minute).Unix() }, wantErr: true},
+		{name: "wrong issuer", change: func(c *Claim) { c.Issuer = "other" }, wantErr: true},
+		{name: "without issuer", change: func(c *Claim) { c.Issuer = "" }, wantErr: true},
+		{name: "wrong audience", change: func(c *Claim) { c.Audience = "other-api" }, wantErr: true},
+		{name: "without audience", change: func(c *Claim) { c.Audience = "" }, wantErr: true},
+		{name: "without jti", change: func(c *Claim) { c.Id = "" }, wantErr: true},
+		{name: "without user id", change: func(c *Claim) { c.ID = 0 }, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c := validClaim()
+			tt.change(&c)
+
+			err := c.Valid()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Valid() = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestGetFromToken(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sign := func(method jwt.SigningMethod, key interface{}, change func(c *Claim)) string {
+		c := validClaim()
+		change(&c)
+
+		token, err := jwt.NewWithClaims(method, c).SignedString(key)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		return token
+	}
+	hmac := []byte(testSigningString)
+
+	tests := []struct {
+		name    string
+		token   string
+		wantErr bool
+	}{
+		{name: "valid", token: sign(jwt.SigningMethodHS256, hmac, func(c *Claim) {})},
+		{name: "other HMAC", token: sign(jwt.SigningMethodHS512, hmac, func(c *Claim) {})},
+		{name: "wrong key", token: sign(jwt.SigningMethodHS256, []byte("other"), func(c *Claim) {}), wantErr: true},
+		{
+			name:    "expired",
+			token:   sign(jwt.SigningMethodHS256, hmac, func(c *Claim) { c.ExpiresAt = time.Now().Add(-time.Minute).Unix() }),
+			wantErr: true,
+		},
+		{name: "wrong issuer", token: sign(jwt.SigningMethodHS256, hmac, func(c *Claim) { c.Issuer = "other" }), wantErr: true},
+		{name: "wrong audience", token: sign(jwt.SigningMethodHS256, hmac, func(c *Claim) { c.Audience = "other-api" }), wantErr: true},
+		{name: "without jti", token: sign(jwt.SigningMethodHS256, hmac, func(c *Claim) { c.Id = "" }), wantErr: true},
+		{
+			name:    "alg none",
+			token:   sign(jwt.SigningMethodNone, jwt.UnsafeAllowNoneSignatureType, func(c *Claim) {}),
+			wantErr: true,
+		},
+		{name: "alg RS256", token: sign(jwt.SigningMethodRS256, key, func(c *Claim) {}), wantErr: true},
+		{name: "malformed", token: "x.y.z", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, err := GetFromToken(tt.token, testSigningString)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetFromToken() = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && (c.ID != 7 || c.Id != "jti") {
+				t.Errorf("GetFromToken() = id %d, jti %q, want 7, %q", c.ID, c.Id, "jti")
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	c, err := New(7, true, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	token, err := c.GetToken(testSigningString)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := GetFromToken(token, testSigningString)
+	if err != nil {
+		t.Fatalf("GetFromToken: %v", err)
+	}
+
+	if got.ID != 7 || !got.Admin || got.Version != 3 || got.Id != c.Id {
+		t.Errorf("GetFromToken() = %+v, want %+v", got, c)
+	}
+
+	other, err := New(7, true, 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if other.Id == c.Id {
+		t.Errorf("New() returned the jti %q twice", c.Id)
+	}
+}
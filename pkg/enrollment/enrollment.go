@@ -0,0 +1,15 @@
+package enrollment
+
+import "time"
+
+// Enrollment of a user in a subject.
+type Enrollment struct {
+	UserID    uint      `json:"user_id,omitempty"`
+	SubjectID uint      `json:"subject_id,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
+
+// Request is the body of the request to enroll in a subject.
+type Request struct {
+	SubjectID uint `json:"subject_id" validate:"required"`
+}
@@ -0,0 +1,15 @@
+package enrollment
+
+import (
+	"context"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/subject"
+)
+
+// Repository handle the enrollments of the users in subjects.
+type Repository interface {
+	GetSubjects(ctx context.Context, userID uint) ([]subject.Subject, error)
+	GetSuggested(ctx context.Context, userID uint) ([]subject.Subject, error)
+	Enroll(ctx context.Context, e *Enrollment) error
+	Unenroll(ctx context.Context, userID, subjectID uint) error
+}
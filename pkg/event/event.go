@@ -0,0 +1,62 @@
+// Package event describes the real-time events of the posts, replies
+// and notifications, and the channels they are published in.
+package event
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Retention is the time the events are kept in the log.
+const Retention = 7 * 24 * time.Hour
+
+// Kinds of channels. A channel is named kind:id, like post:12.
+const (
+	// KindPost receives the events of the replies of a post.
+	KindPost = "post"
+	// KindSubject receives the new posts of a subject.
+	KindSubject = "subject"
+	// KindUser receives the notifications of a user.
+	KindUser = "user"
+)
+
+// ErrInvalidChannel is returned when a channel name is malformed.
+var ErrInvalidChannel = errors.New("invalid channel")
+
+// Event published to the channels. The events of the subjects are
+// also stored in a log and have an ID.
+type Event struct {
+	ID       int64           `json:"id,omitempty"`
+	Type     string          `json:"type"`
+	Channels []string        `json:"channels,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+// Channel returns the name of the channel of a kind and id.
+func Channel(kind string, id uint) string {
+	return fmt.Sprintf("%s:%d", kind, id)
+}
+
+// ParseChannel returns the kind and id of a channel name.
+func ParseChannel(channel string) (string, uint, error) {
+	i := strings.Index(channel, ":")
+	if i < 0 {
+		return "", 0, ErrInvalidChannel
+	}
+
+	kind := channel[:i]
+	if kind != KindPost && kind != KindSubject && kind != KindUser {
+		return "", 0, ErrInvalidChannel
+	}
+
+	id, err := strconv.ParseUint(channel[i+1:], 10, 32)
+	if err != nil || id == 0 {
+		return "", 0, ErrInvalidChannel
+	}
+
+	return kind, uint(id), nil
+}
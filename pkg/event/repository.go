@@ -0,0 +1,12 @@
+package event
+
+import (
+	"context"
+	"time"
+)
+
+// Repository handle the log of the events of the subjects.
+type Repository interface {
+	GetBySubject(ctx context.Context, subjectID uint, afterID int64, limit int) ([]Event, error)
+	Purge(ctx context.Context, before time.Time) (int64, error)
+}
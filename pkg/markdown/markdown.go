@@ -0,0 +1,38 @@
+// Package markdown renders the bodies of posts and replies as HTML that
+// is safe to be shown in the clients.
+package markdown
+
+import (
+	"bytes"
+
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/renderer/html"
+)
+
+// md converts GitHub Flavored Markdown: tables, strikethrough, task
+// lists and autolinks. The fenced code blocks keep the language as the
+// class language-{lang} of the code element. The raw HTML of the body
+// is omitted and the output is sanitized too.
+var md = goldmark.New(
+	goldmark.WithExtensions(
+		extension.NewTable(extension.WithTableCellAlignMethod(extension.TableCellAlignAttribute)),
+		extension.Strikethrough,
+		extension.Linkify,
+		extension.TaskList,
+	),
+	goldmark.WithRendererOptions(
+		html.WithHardWraps(),
+	),
+)
+
+// Render returns the Markdown of the body as sanitized HTML.
+func Render(body string) (string, error) {
+	var buf bytes.Buffer
+	err := md.Convert([]byte(body), &buf)
+	if err != nil {
+		return "", err
+	}
+
+	return Sanitize(buf.String()), nil
+}
@@ -0,0 +1,112 @@
+package markdown
+
+import "testing"
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "fenced code with language",
+			in:   "```go\nfmt.Println(1)\n```",
+			want: "<pre><code class=\"language-go\">fmt.Println(1)\n</code></pre>\n",
+		},
+		{
+			name: "fenced code with symbols in the language",
+			in:   "```c++\nx\n```",
+			want: "<pre><code class=\"language-c++\">x\n</code></pre>\n",
+		},
+		{
+			name: "fenced code with attributes after the language",
+			in:   "```go x y\nx\n```",
+			want: "<pre><code class=\"language-go\">x\n</code></pre>\n",
+		},
+		{
+			name: "fenced code with an injected language",
+			in:   "```\" onclick=\"x\nx\n```",
+			want: "<pre><code>x\n</code></pre>\n",
+		},
+		{
+			name: "raw HTML block is omitted",
+			in:   "<script>alert(1)</script>",
+			want: "\n",
+		},
+		{
+			name: "inline raw HTML is omitted",
+			in:   "a <b onclick=\"x\">b</b> c",
+			want: "<p>a b c</p>\n",
+		},
+		{
+			name: "javascript link",
+			in:   "[x](javascript:alert(1))",
+			want: "<p><a href=\"\" rel=\"nofollow ugc\">x</a></p>\n",
+		},
+		{
+			name: "mixed case javascript link",
+			in:   "[x](JaVaScRiPt:alert(1))",
+			want: "<p><a href=\"\" rel=\"nofollow ugc\">x</a></p>\n",
+		},
+		{
+			name: "entity encoded javascript link",
+			in:   "[x](&#106;avascript:alert(1))",
+			want: "<p><a rel=\"nofollow ugc\">x</a></p>\n",
+		},
+		{
+			name: "protocol-relative image",
+			in:   "![x](//evil.example.com/a.png)",
+			want: "<p><img alt=\"x\"/></p>\n",
+		},
+		{
+			name: "data image",
+			in:   "![x](data:image/png;base64,AAAA)",
+			want: "<p><img alt=\"x\"/></p>\n",
+		},
+		{
+			name: "link",
+			in:   "[x](https://example.com)",
+			want: "<p><a href=\"https://example.com\" rel=\"nofollow ugc\">x</a></p>\n",
+		},
+		{
+			name: "autolink",
+			in:   "https://example.com",
+			want: "<p><a href=\"https://example.com\" rel=\"nofollow ugc\">https://example.com</a></p>\n",
+		},
+		{
+			name: "task list",
+			in:   "- [x] done\n- [ ] todo",
+			want: "<ul>\n<li><input type=\"checkbox\" checked=\"\" disabled=\"\"/> done</li>\n" +
+				"<li><input type=\"checkbox\" disabled=\"\"/> todo</li>\n</ul>\n",
+		},
+		{
+			name: "table",
+			in:   "| a | b |\n|:-|:-:|\n| 1 | 2 |",
+			want: "<table>\n<thead>\n<tr>\n<th align=\"left\">a</th>\n<th align=\"center\">b</th>\n</tr>\n</thead>\n" +
+				"<tbody>\n<tr>\n<td align=\"left\">1</td>\n<td align=\"center\">2</td>\n</tr>\n</tbody>\n</table>\n",
+		},
+		{
+			name: "strikethrough",
+			in:   "~~a~~",
+			want: "<p><del>a</del></p>\n",
+		},
+		{
+			name: "hard wraps",
+			in:   "a\nb",
+			want: "<p>a<br/>\nb</p>\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Render(tt.in)
+			if err != nil {
+				t.Fatalf("Render(%q): %v", tt.in, err)
+			}
+
+			if got != tt.want {
+				t.Errorf("Render(%q)\n got: %q\nwant: %q", tt.in, got, tt.want)
+			}
+		})
+	}
+}
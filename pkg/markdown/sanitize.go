@@ -0,0 +1,225 @@
+package markdown
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+
+	"golang.org/x/net/html"
+)
+
+// tags are the elements allowed and, for each one, its attributes.
+var tags = map[string]map[string]bool{
+	"a":          {"href": true, "title": true},
+	"b":          {},
+	"blockquote": {},
+	"br":         {},
+	"code":       {"class": true},
+	"del":        {},
+	"em":         {},
+	"h1":         {},
+	"h2":         {},
+	"h3":         {},
+	"h4":         {},
+	"h5":         {},
+	"h6":         {},
+	"hr":         {},
+	"i":          {},
+	"img":        {"src": true, "alt": true, "title": true},
+	"input":      {"type": true, "checked": true, "disabled": true},
+	"kbd":        {},
+	"li":         {},
+	"ol":         {"start": true},
+	"p":          {},
+	"pre":        {},
+	"s":          {},
+	"strong":     {},
+	"sub":        {},
+	"sup":        {},
+	"table":      {},
+	"tbody":      {},
+	"td":         {"align": true},
+	"th":         {"align": true},
+	"thead":      {},
+	"tr":         {},
+	"ul":         {},
+}
+
+// voidTags have no end tag.
+var voidTags = map[string]bool{"br": true, "hr": true, "img": true, "input": true}
+
+// dropTags are removed with their content, the rest of the elements not
+// allowed are removed but their text is kept.
+var dropTags = map[string]bool{
+	"iframe": true, "noscript": true, "object": true, "script": true,
+	"style": true, "svg": true, "math": true, "template": true, "textarea": true,
+}
+
+var (
+	languageClass = regexp.MustCompile(`^language-[\w+#.-]{1,30}$`)
+	number        = regexp.MustCompile(`^\d{1,9}$`)
+)
+
+// Sanitize returns the HTML with only the allowed elements and
+// attributes. The links get rel="nofollow ugc" and only http, https
+// and mailto URLs, or relative ones, are kept. Every element is closed.
+func Sanitize(s string) string {
+	var b strings.Builder
+	var open []string
+	drop := 0
+
+	z := html.NewTokenizer(strings.NewReader(s))
+	for tt := z.Next(); tt != html.ErrorToken; tt = z.Next() {
+		t := z.Token()
+		switch tt {
+		case html.TextToken:
+			if drop == 0 {
+				b.WriteString(html.EscapeString(t.Data))
+			}
+		case html.StartTagToken, html.SelfClosingTagToken:
+			if dropTags[t.Data] {
+				if tt == html.StartTagToken {
+					drop++
+				}
+
+				continue
+			}
+
+			allowed, ok := tags[t.Data]
+			if !ok || drop > 0 {
+				continue
+			}
+
+			t.Attr = attributes(t.Data, t.Attr, allowed)
+			if voidTags[t.Data] {
+				t.Type = html.SelfClosingTagToken
+			} else {
+				t.Type = html.StartTagToken
+				open = append(open, t.Data)
+			}
+
+			b.WriteString(t.String())
+		case html.EndTagToken:
+			if dropTags[t.Data] {
+				if drop > 0 {
+					drop--
+				}
+
+				continue
+			}
+
+			// the end tags close the elements opened after them, the
+			// ones without start tag are skipped.
+			for i := len(open) - 1; i >= 0; i-- {
+				if open[i] != t.Data {
+					continue
+				}
+
+				for j := len(open) - 1; j >= i; j-- {
+					b.WriteString("</" + open[j] + ">")
+				}
+
+				open = open[:i]
+				break
+			}
+		}
+	}
+
+	for i := len(open) - 1; i >= 0; i-- {
+		b.WriteString("</" + open[i] + ">")
+	}
+
+	return b.String()
+}
+
+// attributes returns the allowed attributes of the element with valid
+// values.
+func attributes(tag string, attrs []html.Attribute, allowed map[string]bool) []html.Attribute {
+	var safe []html.Attribute
+	for _, a := range attrs {
+		if a.Namespace != "" || !allowed[a.Key] {
+			continue
+		}
+
+		switch a.Key {
+		case "href", "src":
+			if !safeURL(a.Val, a.Key == "href") {
+				continue
+			}
+		case "class":
+			if !languageClass.MatchString(a.Val) {
+				continue
+			}
+		case "type":
+			if a.Val != "checkbox" {
+				continue
+			}
+		case "align":
+			if a.Val != "left" && a.Val != "center" && a.Val != "right" {
+				continue
+			}
+		case "start":
+			if !number.MatchString(a.Val) {
+				continue
+			}
+		}
+
+		safe = append(safe, a)
+	}
+
+	switch tag {
+	case "a":
+		safe = append(safe, html.Attribute{Key: "rel", Val: "nofollow ugc"})
+	case "input":
+		if len(safe) == 0 || safe[0].Key != "type" {
+			safe = append([]html.Attribute{{Key: "type", Val: "checkbox"}}, safe...)
+		}
+
+		safe = append(safe, html.Attribute{Key: "disabled", Val: ""})
+	}
+
+	return dedupe(safe)
+}
+
+// dedupe removes the repeated attributes, keeping the first one.
+func dedupe(attrs []html.Attribute) []html.Attribute {
+	seen := make(map[string]bool, len(attrs))
+	unique := attrs[:0]
+	for _, a := range attrs {
+		if seen[a.Key] {
+			continue
+		}
+
+		seen[a.Key] = true
+		unique = append(unique, a)
+	}
+
+	return unique
+}
+
+// safeURL reports whether the URL is relative to the site or has a
+// safe scheme: http and https, and mailto for links. The
+// protocol-relative URLs, like //host/path, are not relative to the
+// site, and the browsers read the backslashes as slashes.
+func safeURL(raw string, link bool) bool {
+	raw = strings.TrimSpace(raw)
+	if strings.ContainsRune(raw, '\\') {
+		return false
+	}
+
+	u, err := url.Parse(raw)
+	if err != nil {
+		return false
+	}
+
+	switch strings.ToLower(u.Scheme) {
+	case "":
+		return u.Opaque == "" && u.Host == "" && !strings.HasPrefix(u.Path, "//")
+	case "http", "https":
+		return true
+	case "mailto":
+		return link
+	}
+
+	return false
+}
@@ -0,0 +1,246 @@
+package markdown
+
+import "testing"
+
+func TestSanitize(t *testing.T) {
+	tests := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{
+			name: "allowed elements",
+			in:   `<p><strong>a</strong> <em>b</em> <code>c</code></p>`,
+			want: `<p><strong>a</strong> <em>b</em> <code>c</code></p>`,
+		},
+		{
+			name: "http link",
+			in:   `<a href="https://example.com/a?b=1" title="t">x</a>`,
+			want: `<a href="https://example.com/a?b=1" title="t" rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "relative link",
+			in:   `<a href="/posts/1">x</a>`,
+			want: `<a href="/posts/1" rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "mailto link",
+			in:   `<a href="mailto:a@example.com">x</a>`,
+			want: `<a href="mailto:a@example.com" rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "rel of the user is replaced",
+			in:   `<a href="/a" rel="opener">x</a>`,
+			want: `<a href="/a" rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "javascript href",
+			in:   `<a href="javascript:alert(1)">x</a>`,
+			want: `<a rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "mixed case javascript href",
+			in:   `<a href="JaVaScRiPt:alert(1)">x</a>`,
+			want: `<a rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "javascript href with spaces",
+			in:   `<a href="  javascript:alert(1)">x</a>`,
+			want: `<a rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "entity encoded javascript href",
+			in:   `<a href="&#106;avascript&#58;alert(1)">x</a>`,
+			want: `<a rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "hex entity encoded javascript href",
+			in:   `<a href="&#x6A;&#x61;vascript:alert(1)">x</a>`,
+			want: `<a rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "javascript href with tab",
+			in:   "<a href=\"java\tscript:alert(1)\">x</a>",
+			want: `<a rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "javascript href with encoded newline",
+			in:   `<a href="java&#10;script:alert(1)">x</a>`,
+			want: `<a rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "vbscript href",
+			in:   `<a href="vbscript:msgbox(1)">x</a>`,
+			want: `<a rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "data href",
+			in:   `<a href="data:text/html;base64,PHNjcmlwdD4=">x</a>`,
+			want: `<a rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "data img src",
+			in:   `<img src="data:image/svg+xml;base64,PHN2Zz4=">`,
+			want: `<img/>`,
+		},
+		{
+			name: "mailto img src",
+			in:   `<img src="mailto:a@example.com">`,
+			want: `<img/>`,
+		},
+		{
+			name: "https img src",
+			in:   `<img src="https://example.com/a.png" alt="a">`,
+			want: `<img src="https://example.com/a.png" alt="a"/>`,
+		},
+		{
+			name: "protocol-relative img src",
+			in:   `<img src="//evil.example.com/a.png">`,
+			want: `<img/>`,
+		},
+		{
+			name: "protocol-relative href",
+			in:   `<a href="//evil.example.com">x</a>`,
+			want: `<a rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "backslash protocol-relative href",
+			in:   `<a href="/\evil.example.com">x</a>`,
+			want: `<a rel="nofollow ugc">x</a>`,
+		},
+		{
+			name: "event handler attributes",
+			in:   `<p onclick="alert(1)">a</p><img src="/a.png" onerror="alert(1)" ONLOAD="alert(1)">`,
+			want: `<p>a</p><img src="/a.png"/>`,
+		},
+		{
+			name: "style and id attributes",
+			in:   `<p style="color:red" id="x" class="y">a</p>`,
+			want: `<p>a</p>`,
+		},
+		{
+			name: "script is dropped with its content",
+			in:   `a<script>alert(1)</script>b`,
+			want: `ab`,
+		},
+		{
+			name: "style is dropped with its content",
+			in:   `a<style>body{display:none}</style>b`,
+			want: `ab`,
+		},
+		{
+			name: "svg is dropped with its content",
+			in:   `a<svg onload="alert(1)"><script>alert(1)</script><a href="/x">c</a></svg>b`,
+			want: `ab`,
+		},
+		{
+			name: "math is dropped with its content",
+			in:   `a<math><mtext><img src="/x"></mtext></math>b`,
+			want: `ab`,
+		},
+		{
+			name: "iframe is dropped with its content",
+			in:   `a<iframe src="https://example.com">c</iframe>b`,
+			want: `ab`,
+		},
+		{
+			name: "self-closing iframe",
+			in:   `a<iframe src="https://example.com"/>b`,
+			want: `ab`,
+		},
+		{
+			name: "unknown elements keep their text",
+			in:   `<div><span>a</span></div>`,
+			want: `a`,
+		},
+		{
+			name: "unclosed elements are closed",
+			in:   `<p><strong>a<em>b`,
+			want: `<p><strong>a<em>b</em></strong></p>`,
+		},
+		{
+			name: "misnested elements",
+			in:   `<strong><em>a</strong>b</em>`,
+			want: `<strong><em>a</em></strong>b`,
+		},
+		{
+			name: "end tag without start tag",
+			in:   `a</p></strong>b`,
+			want: `ab`,
+		},
+		{
+			name: "malformed start tag",
+			in:   `<a href="/x"<script>alert(1)</script>`,
+			want: `<a href="/x" rel="nofollow ugc">alert(1)</a>`,
+		},
+		{
+			name: "tag not finished",
+			in:   `a<img src="/x" onerror="alert(1)"`,
+			want: `a`,
+		},
+		{
+			name: "comments are removed",
+			in:   `a<!-- <script>alert(1)</script> -->b`,
+			want: `ab`,
+		},
+		{
+			name: "text is escaped",
+			in:   `a &lt;script&gt; &amp; "b"`,
+			want: `a &lt;script&gt; &amp; &#34;b&#34;`,
+		},
+		{
+			name: "attribute values are escaped",
+			in:   `<img alt="&quot;><script>alert(1)</script>">`,
+			want: `<img alt="&#34;&gt;&lt;script&gt;alert(1)&lt;/script&gt;"/>`,
+		},
+		{
+			name: "language class of code",
+			in:   `<pre><code class="language-go">x</code></pre>`,
+			want: `<pre><code class="language-go">x</code></pre>`,
+		},
+		{
+			name: "language class with symbols",
+			in:   `<code class="language-c++">x</code><code class="language-c#">y</code>`,
+			want: `<code class="language-c++">x</code><code class="language-c#">y</code>`,
+		},
+		{
+			name: "other classes of code",
+			in:   `<code class="hljs">x</code><code class="language-go x">y</code><code class="language-&quot;x">z</code>`,
+			want: `<code>x</code><code>y</code><code>z</code>`,
+		},
+		{
+			name: "task list checkbox",
+			in:   `<input checked="" type="checkbox" disabled="">`,
+			want: `<input type="checkbox" checked="" disabled=""/>`,
+		},
+		{
+			name: "other inputs become disabled checkboxes",
+			in:   `<input type="text" value="x">`,
+			want: `<input type="checkbox" disabled=""/>`,
+		},
+		{
+			name: "table alignment",
+			in:   `<td align="center">a</td><td align="justify">b</td>`,
+			want: `<td align="center">a</td><td>b</td>`,
+		},
+		{
+			name: "list start",
+			in:   `<ol start="3"><li>a</li></ol><ol start="x"></ol>`,
+			want: `<ol start="3"><li>a</li></ol><ol></ol>`,
+		},
+		{
+			name: "repeated attributes",
+			in:   `<a href="/a" href="javascript:alert(1)">x</a>`,
+			want: `<a href="/a" rel="nofollow ugc">x</a>`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Sanitize(tt.in)
+			if got != tt.want {
+				t.Errorf("Sanitize(%q)\n got: %s\nwant: %s", tt.in, got, tt.want)
+			}
+		})
+	}
+}
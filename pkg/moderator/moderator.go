@@ -0,0 +1,12 @@
+// Package moderator handles the moderators of the subjects, the users
+// that can edit, delete, pin and lock the content of a subject.
+package moderator
+
+import "time"
+
+// Moderator of a subject.
+type Moderator struct {
+	SubjectID uint      `json:"subject_id,omitempty"`
+	UserID    uint      `json:"user_id,omitempty"`
+	CreatedAt time.Time `json:"created_at,omitempty"`
+}
@@ -0,0 +1,11 @@
+package moderator
+
+import "context"
+
+// Repository handle the moderators of the subjects.
+type Repository interface {
+	GetBySubject(ctx context.Context, subjectID uint) ([]Moderator, error)
+	IsModerator(ctx context.Context, subjectID, userID uint) (bool, error)
+	Add(ctx context.Context, m *Moderator) error
+	Remove(ctx context.Context, subjectID, userID uint) error
+}
@@ -0,0 +1,77 @@
+package notification
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+// Types of notifications.
+const (
+	// TypeReply is sent to the author of a post when someone replies.
+	TypeReply = "reply"
+	// TypeMention is sent to the users mentioned with @username.
+	TypeMention = "mention"
+	// TypePost is sent to the users enrolled in the subject of a new post.
+	TypePost = "post"
+)
+
+// mention matches @username. The usernames are not only ASCII, so the
+// letters, marks and digits of any script are part of them.
+var mention = regexp.MustCompile(`(?:^|[^\p{L}\p{M}\p{N}_@])@([\p{L}\p{M}\p{N}_.-]{3,150})`)
+
+// Notification of an event to a user.
+type Notification struct {
+	ID        uint       `json:"id,omitempty"`
+	UserID    uint       `json:"user_id,omitempty"`
+	Type      string     `json:"type,omitempty"`
+	ActorID   uint       `json:"actor_id,omitempty"`
+	SubjectID uint       `json:"subject_id,omitempty"`
+	PostID    uint       `json:"post_id,omitempty"`
+	ReplyID   uint       `json:"reply_id,omitempty"`
+	ReadAt    *time.Time `json:"read_at"`
+	CreatedAt time.Time  `json:"created_at,omitempty"`
+}
+
+// Preferences of the events that generate notifications for a user.
+// Every event is enabled by default.
+type Preferences struct {
+	Reply   bool `json:"reply"`
+	Mention bool `json:"mention"`
+	Post    bool `json:"post"`
+}
+
+// PreferencesRequest is the body of the request to update the preferences.
+type PreferencesRequest struct {
+	Reply   *bool `json:"reply" validate:"required"`
+	Mention *bool `json:"mention" validate:"required"`
+	Post    *bool `json:"post" validate:"required"`
+}
+
+// Preferences returns the preferences of the request.
+func (req PreferencesRequest) Preferences() Preferences {
+	return Preferences{
+		Reply:   *req.Reply,
+		Mention: *req.Mention,
+		Post:    *req.Post,
+	}
+}
+
+// Mentions returns the usernames mentioned with @username in the text,
+// without duplicates.
+func Mentions(text string) []string {
+	seen := make(map[string]bool)
+	var usernames []string
+	for _, match := range mention.FindAllStringSubmatch(text, -1) {
+		// a mention at the end of a sentence, like @juan., ends before the dot.
+		username := strings.TrimRight(match[1], ".-")
+		if username == "" || seen[username] {
+			continue
+		}
+
+		seen[username] = true
+		usernames = append(usernames, username)
+	}
+
+	return usernames
+}
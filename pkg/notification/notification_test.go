@@ -0,0 +1,38 @@
+package notification
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMentions(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{name: "ascii", text: "hola @juan", want: []string{"juan"}},
+		{name: "start of the text", text: "@juan hola", want: []string{"juan"}},
+		{name: "accents", text: "gracias @josé y @núñez", want: []string{"josé", "núñez"}},
+		{name: "combining marks", text: "hola @jose\u0301", want: []string{"jose\u0301"}},
+		{name: "other scripts", text: "привет @иван, 你好 @李小龙", want: []string{"иван", "李小龙"}},
+		{name: "digits and symbols", text: "@ana_1.b-c", want: []string{"ana_1.b-c"}},
+		{name: "end of a sentence", text: "lo dijo @juan.", want: []string{"juan"}},
+		{name: "duplicates", text: "@juan @juan", want: []string{"juan"}},
+		{name: "too short", text: "@jo", want: nil},
+		{name: "email", text: "juan@example.com", want: nil},
+		{name: "email with accents", text: "josé@example.com", want: nil},
+		{name: "double at", text: "@@juan", want: nil},
+		{name: "punctuation before", text: "(@juan)", want: []string{"juan"}},
+		{name: "none", text: "hola", want: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Mentions(tt.text)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Mentions(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
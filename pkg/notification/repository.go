@@ -0,0 +1,21 @@
+package notification
+
+import (
+	"context"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/post"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/reply"
+)
+
+// Repository handle the notifications of the users and their preferences.
+type Repository interface {
+	GetByUser(ctx context.Context, userID uint, unread bool, pg page.Request) ([]Notification, string, error)
+	CountUnread(ctx context.Context, userID uint) (int, error)
+	MarkRead(ctx context.Context, userID, id uint) error
+	MarkAllRead(ctx context.Context, userID uint) error
+	GetPreferences(ctx context.Context, userID uint) (Preferences, error)
+	UpdatePreferences(ctx context.Context, userID uint, p Preferences) error
+	NotifyPost(ctx context.Context, p post.Post) error
+	NotifyReply(ctx context.Context, r reply.Reply) error
+}
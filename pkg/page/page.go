@@ -0,0 +1,101 @@
+package page
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Page sizes of the list endpoints.
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+// Request is the page requested by the client with the limit and
+// after query parameters.
+type Request struct {
+	Limit int
+	After Cursor
+}
+
+// Cursor identifies the last item of a page. It is opaque to the
+// clients, which only send back the encoded value.
+type Cursor struct {
+	ID    uint      `json:"id"`
+	Time  time.Time `json:"t,omitempty"`
+	Rank  float32   `json:"r,omitempty"`
+	Score float64   `json:"s,omitempty"`
+	// Path is the path of the last reply of a branch, which sorts
+	// the branch even if that reply is purged.
+	Path []int64 `json:"p,omitempty"`
+}
+
+// FromRequest returns the page requested in the query string.
+func FromRequest(r *http.Request) (Request, error) {
+	q := r.URL.Query()
+	pg := Request{Limit: DefaultLimit}
+
+	if s := q.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 {
+			return Request{}, errors.New("invalid limit")
+		}
+
+		if limit > MaxLimit {
+			limit = MaxLimit
+		}
+
+		pg.Limit = limit
+	}
+
+	if s := q.Get("after"); s != "" {
+		c, err := Decode(s)
+		if err != nil {
+			return Request{}, err
+		}
+
+		pg.After = c
+	}
+
+	return pg, nil
+}
+
+// Encode returns the opaque representation of the cursor.
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// Decode returns the cursor of an opaque representation.
+func Decode(s string) (Cursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+
+	var c Cursor
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == 0 {
+		return Cursor{}, errors.New("invalid cursor")
+	}
+
+	return c, nil
+}
+
+// SetLink adds the Link header pointing to the next page, if there is one.
+func SetLink(w http.ResponseWriter, r *http.Request, next string) {
+	if next == "" {
+		return
+	}
+
+	u := *r.URL
+	q := u.Query()
+	q.Set("after", next)
+	u.RawQuery = q.Encode()
+
+	w.Header().Add("Link", fmt.Sprintf(`<%s>; rel="next"`, u.String()))
+}
@@ -0,0 +1,121 @@
+package page
+
+import (
+	"encoding/base64"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestCursor(t *testing.T) {
+	tests := []struct {
+		name string
+		c    Cursor
+	}{
+		{name: "id", c: Cursor{ID: 1}},
+		{name: "time", c: Cursor{ID: 2, Time: time.Date(2021, time.March, 4, 5, 6, 7, 8, time.UTC)}},
+		{name: "rank", c: Cursor{ID: 3, Rank: 0.25}},
+		{name: "score", c: Cursor{ID: 4, Score: -12.5}},
+		{name: "path", c: Cursor{ID: 5, Path: []int64{1, 3, 5}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Decode(tt.c.Encode())
+			if err != nil {
+				t.Fatalf("Decode(Encode(%+v)): %v", tt.c, err)
+			}
+
+			if !got.Time.Equal(tt.c.Time) {
+				t.Errorf("Time = %v, want %v", got.Time, tt.c.Time)
+			}
+
+			got.Time = tt.c.Time
+			if !reflect.DeepEqual(got, tt.c) {
+				t.Errorf("Decode(Encode(%+v)) = %+v", tt.c, got)
+			}
+		})
+	}
+}
+
+func TestDecodeInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		s    string
+	}{
+		{name: "empty", s: ""},
+		{name: "not base64", s: "!!!"},
+		{name: "padded base64", s: base64.URLEncoding.EncodeToString([]byte(`{"id":1}`))},
+		{name: "not JSON", s: base64.RawURLEncoding.EncodeToString([]byte("id=1"))},
+		{name: "without id", s: base64.RawURLEncoding.EncodeToString([]byte(`{"t":"2021-03-04T05:06:07Z"}`))},
+		{name: "negative id", s: base64.RawURLEncoding.EncodeToString([]byte(`{"id":-1}`))},
+		{name: "invalid path", s: base64.RawURLEncoding.EncodeToString([]byte(`{"id":1,"p":"1.2"}`))},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Decode(tt.s)
+			if err == nil {
+				t.Errorf("Decode(%q): want an error", tt.s)
+			}
+		})
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	after := Cursor{ID: 7}.Encode()
+
+	tests := []struct {
+		name    string
+		query   string
+		want    Request
+		wantErr bool
+	}{
+		{name: "default", query: "", want: Request{Limit: DefaultLimit}},
+		{name: "limit", query: "limit=5", want: Request{Limit: 5}},
+		{name: "max limit", query: "limit=1000", want: Request{Limit: MaxLimit}},
+		{name: "after", query: "after=" + after, want: Request{Limit: DefaultLimit, After: Cursor{ID: 7}}},
+		{name: "zero limit", query: "limit=0", wantErr: true},
+		{name: "invalid limit", query: "limit=x", wantErr: true},
+		{name: "invalid after", query: "after=x", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/posts?"+tt.query, nil)
+			got, err := FromRequest(r)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromRequest(%q) error = %v, want error %v", tt.query, err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FromRequest(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetLink(t *testing.T) {
+	tests := []struct {
+		name string
+		url  string
+		next string
+		want string
+	}{
+		{name: "last page", url: "/posts", next: "", want: ""},
+		{name: "next page", url: "/posts?limit=5", next: "abc", want: `</posts?after=abc&limit=5>; rel="next"`},
+		{name: "replaces after", url: "/posts?after=old", next: "new", want: `</posts?after=new>; rel="next"`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			SetLink(w, httptest.NewRequest("GET", tt.url, nil), tt.next)
+
+			if got := w.Header().Get("Link"); got != tt.want {
+				t.Errorf("Link = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -1,6 +1,29 @@
 package post
 
-import "time"
+import (
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+)
+
+// ErrReplyNotInPost is returned when the accepted answer of a post is
+// a reply of another post.
+var ErrReplyNotInPost = apperror.New(apperror.ErrValidation, "validation_failed",
+	"the request has invalid fields",
+	apperror.FieldError{Field: "reply_id", Code: "not_in_post", Message: "must be a reply of the post"})
+
+// ErrSubjectDeleted is returned when a post of a deleted subject is
+// restored.
+var ErrSubjectDeleted = apperror.New(apperror.ErrConflict, "subject_deleted",
+	"the subject of the post is deleted, it must be restored first")
+
+// Orders of the posts of a subject.
+const (
+	OrderCreated = "created"
+	OrderUpdated = "updated"
+	OrderTop     = "top"
+	OrderHot     = "hot"
+)
 
 // Post created by a user.
 type Post struct {
@@ -8,8 +31,30 @@ type Post struct {
 	Title 	  string	`json:"title,omitempty"`
 	Category  string	`json:"category,omitempty"`
 	Body      string    `json:"body,omitempty"`
+	BodyHTML  string    `json:"body_html,omitempty"`
 	UserID    uint      `json:"user_id,omitempty"`
 	SubjectId uint 		`json:"subject_id,omitempty"`
 	CreatedAt time.Time `json:"created_at,omitempty"`
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
+
+	// Upvotes, Downvotes and Score are the totals of the votes and
+	// MyVote is the vote of the authenticated user: 1, -1 or 0.
+	Upvotes   int     `json:"upvotes"`
+	Downvotes int     `json:"downvotes"`
+	Score     int     `json:"score"`
+	MyVote    int     `json:"my_vote"`
+	Hot       float64 `json:"-"`
+
+	// Solved is true when the post has an accepted answer.
+	Solved          bool `json:"solved"`
+	AcceptedReplyID uint `json:"accepted_reply_id,omitempty"`
+
+	// Pinned posts go first in their subject and Locked posts don't
+	// accept new replies.
+	Pinned bool `json:"pinned"`
+	Locked bool `json:"locked"`
+
+	// ActivityAt is the time of the last update or reply, only
+	// returned in the feed.
+	ActivityAt *time.Time `json:"activity_at,omitempty"`
 }
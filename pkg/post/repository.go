@@ -1,16 +1,28 @@
 package post
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+)
 
 // Repository handle the CRUD operations with Posts.
 type Repository interface {
-	GetAll(ctx context.Context) ([]Post, error)
+	GetAll(ctx context.Context, pg page.Request) ([]Post, string, error)
 	GetOne(ctx context.Context, id uint) (Post, error)
-	GetBySubject(ctx context.Context, subjectID uint, order string) ([]Post, error)
-	GetByUser(ctx context.Context, userID uint) ([]Post, error)
-	GetByCategory(ctx context.Context, subjectID uint, category string) ([]Post, error)
-	GetByTitle(ctx context.Context, subjectID uint, title string) ([]Post, error)
+	GetBySubject(ctx context.Context, subjectID uint, order string, solved *bool, pg page.Request) ([]Post, string, error)
+	GetByUser(ctx context.Context, userID uint, pg page.Request) ([]Post, string, error)
+	GetByCategory(ctx context.Context, subjectID uint, category string, pg page.Request) ([]Post, string, error)
+	GetByTitle(ctx context.Context, subjectID uint, title string, pg page.Request) ([]Post, string, error)
+	GetFeed(ctx context.Context, userID uint, pg page.Request) ([]Post, string, error)
+	Accept(ctx context.Context, id, replyID uint) error
+	Unaccept(ctx context.Context, id uint) error
+	Pin(ctx context.Context, id uint, pinned bool) error
+	Lock(ctx context.Context, id uint, locked bool) error
 	Create(ctx context.Context, post *Post) error
-	Update(ctx context.Context, id uint, post Post) error
+	Update(ctx context.Context, id, editorID uint, post Post) error
 	Delete(ctx context.Context, id uint) error
+	Restore(ctx context.Context, id uint) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
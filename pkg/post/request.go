@@ -0,0 +1,41 @@
+package post
+
+// CreateRequest is the body of the request to create a post.
+type CreateRequest struct {
+	SubjectID uint   `json:"subject_id" validate:"required"`
+	Title     string `json:"title" validate:"required,max=150"`
+	Category  string `json:"category" validate:"required,max=150"`
+	Body      string `json:"body" validate:"required,max=20000"`
+}
+
+// Post returns the post of the request.
+func (req CreateRequest) Post() Post {
+	return Post{
+		SubjectId: req.SubjectID,
+		Title:     req.Title,
+		Category:  req.Category,
+		Body:      req.Body,
+	}
+}
+
+// UpdateRequest is the body of the request to update a post.
+type UpdateRequest struct {
+	Title    string `json:"title" validate:"required,max=150"`
+	Category string `json:"category" validate:"required,max=150"`
+	Body     string `json:"body" validate:"required,max=20000"`
+}
+
+// Post returns the post of the request.
+func (req UpdateRequest) Post() Post {
+	return Post{
+		Title:    req.Title,
+		Category: req.Category,
+		Body:     req.Body,
+	}
+}
+
+// AcceptRequest is the body of the request to accept a reply as the
+// answer of a post.
+type AcceptRequest struct {
+	ReplyID uint `json:"reply_id" validate:"required"`
+}
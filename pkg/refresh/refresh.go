@@ -0,0 +1,63 @@
+package refresh
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// TTL is the lifetime of a refresh token.
+const TTL = 30 * 24 * time.Hour
+
+// Token used to obtain new access tokens.
+// Tokens obtained from the same login share the FamilyID.
+type Token struct {
+	ID        uint       `json:"id,omitempty"`
+	UserID    uint       `json:"user_id,omitempty"`
+	FamilyID  string     `json:"family_id,omitempty"`
+	Hash      string     `json:"-"`
+	ExpiresAt time.Time  `json:"expires_at,omitempty"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+	CreatedAt time.Time  `json:"created_at,omitempty"`
+}
+
+// New returns a new token for the user and its plain value, which is
+// only known by the client. An empty familyID starts a new family.
+func New(userID uint, familyID string) (Token, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return Token{}, "", err
+	}
+
+	if familyID == "" {
+		f := make([]byte, 16)
+		if _, err := rand.Read(f); err != nil {
+			return Token{}, "", err
+		}
+
+		familyID = hex.EncodeToString(f)
+	}
+
+	plain := base64.RawURLEncoding.EncodeToString(b)
+	t := Token{
+		UserID:    userID,
+		FamilyID:  familyID,
+		Hash:      Hash(plain),
+		ExpiresAt: time.Now().Add(TTL),
+	}
+
+	return t, plain, nil
+}
+
+// Hash returns the value stored in the database for a plain token.
+func Hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
+
+// Expired reports whether the token can no longer be used.
+func (t Token) Expired() bool {
+	return time.Now().After(t.ExpiresAt)
+}
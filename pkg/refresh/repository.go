@@ -0,0 +1,17 @@
+package refresh
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrAlreadyRevoked is returned when a token is rotated twice.
+var ErrAlreadyRevoked = errors.New("refresh token already revoked")
+
+// Repository handle the operations with refresh Tokens.
+type Repository interface {
+	GetByHash(ctx context.Context, hash string) (Token, error)
+	Create(ctx context.Context, token *Token) error
+	Rotate(ctx context.Context, id uint, token *Token) error
+	RevokeFamily(ctx context.Context, familyID string) error
+}
@@ -1,13 +1,90 @@
 package reply
 
-import "time"
+import (
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+)
+
+// MaxDepth is the maximum depth of the replies, the top-level replies
+// have depth 0.
+const MaxDepth = 5
+
+// Errors of the parent of a new reply.
+var (
+	ErrParentNotFound  = parentError("not_found", "must be an existing reply")
+	ErrParentNotInPost = parentError("not_in_post", "must be a reply of the same post")
+	ErrParentDeleted   = parentError("deleted", "can't be a deleted reply")
+	ErrTooDeep         = parentError("too_deep", "the thread can't be nested deeper")
+)
+
+// ErrPostDeleted is returned when a reply of a deleted post is
+// restored.
+var ErrPostDeleted = apperror.New(apperror.ErrConflict, "post_deleted",
+	"the post of the reply is deleted, it must be restored first")
+
+// ErrPostLocked is returned when a reply is added to a locked post.
+var ErrPostLocked = apperror.New(apperror.ErrConflict, "post_locked",
+	"the post is locked, it doesn't accept new replies")
+
+// Orders of the replies of a post. The default is OrderCreated, the
+// oldest first.
+const (
+	OrderCreated = "created"
+	OrderTop     = "top"
+	OrderHot     = "hot"
+)
 
 // Reply created by a user.
 type Reply struct {
 	ID        uint      `json:"id,omitempty"`
 	Body      string    `json:"body,omitempty"`
+	BodyHTML  string    `json:"body_html,omitempty"`
 	UserID    uint      `json:"user_id,omitempty"`
 	PostId    uint 		`json:"post_id,omitempty"`
+	// SubjectID is the subject of the post, only returned by GetOne to
+	// check the moderators.
+	SubjectID uint `json:"-"`
 	CreatedAt time.Time `json:"created_at,omitempty"`
 	UpdatedAt time.Time `json:"updated_at,omitempty"`
+
+	// Upvotes, Downvotes and Score are the totals of the votes and
+	// MyVote is the vote of the authenticated user: 1, -1 or 0.
+	Upvotes   int     `json:"upvotes"`
+	Downvotes int     `json:"downvotes"`
+	Score     int     `json:"score"`
+	MyVote    int     `json:"my_vote"`
+	Hot       float64 `json:"-"`
+
+	// Accepted is true for the accepted answer of the post.
+	Accepted bool `json:"accepted,omitempty"`
+
+	// ParentID is the reply answered, 0 for the top-level replies.
+	// Path has the ids from the top-level reply to this one.
+	ParentID      uint    `json:"parent_id,omitempty"`
+	Depth         int     `json:"depth"`
+	Path          []int64 `json:"path,omitempty"`
+	ChildrenCount int     `json:"children_count"`
+
+	// Deleted is true for the deleted replies kept as tombstones, so
+	// their answers are not lost. They have no body nor author.
+	Deleted bool `json:"deleted,omitempty"`
+
+	// Hidden is true for the replies hidden by the moderators, they are
+	// kept as tombstones too.
+	Hidden bool `json:"hidden,omitempty"`
+}
+
+// Tombstone clears the content of a deleted or hidden reply.
+func (r *Reply) Tombstone() {
+	r.Body = ""
+	r.BodyHTML = ""
+	r.UserID = 0
+}
+
+// parentError returns a validation error of the parent_id field.
+func parentError(code, message string) error {
+	return apperror.New(apperror.ErrValidation, "validation_failed",
+		"the request has invalid fields",
+		apperror.FieldError{Field: "parent_id", Code: code, Message: message})
 }
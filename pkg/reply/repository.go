@@ -1,11 +1,20 @@
 package reply
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+)
 
 // Repository handle the CRUD operations with Replies.
 type Repository interface {
-	GetByPost(ctx context.Context, postID uint) ([]Reply, error)
+	GetOne(ctx context.Context, id uint) (Reply, error)
+	GetByPost(ctx context.Context, postID uint, order string, pg page.Request) ([]Reply, string, error)
+	GetBranch(ctx context.Context, id uint, pg page.Request) ([]Reply, string, error)
 	Create(ctx context.Context, reply *Reply) error
-	Update(ctx context.Context, id uint, reply Reply) error
+	Update(ctx context.Context, id, editorID uint, reply Reply) error
 	Delete(ctx context.Context, id uint) error
+	Restore(ctx context.Context, id uint) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
@@ -0,0 +1,28 @@
+package reply
+
+// CreateRequest is the body of the request to create a reply.
+// ParentID is the reply answered, if any.
+type CreateRequest struct {
+	PostID   uint   `json:"post_id" validate:"required"`
+	ParentID uint   `json:"parent_id"`
+	Body     string `json:"body" validate:"required,max=10000"`
+}
+
+// Reply returns the reply of the request.
+func (req CreateRequest) Reply() Reply {
+	return Reply{
+		PostId:   req.PostID,
+		ParentID: req.ParentID,
+		Body:     req.Body,
+	}
+}
+
+// UpdateRequest is the body of the request to update a reply.
+type UpdateRequest struct {
+	Body string `json:"body" validate:"required,max=10000"`
+}
+
+// Reply returns the reply of the request.
+func (req UpdateRequest) Reply() Reply {
+	return Reply{Body: req.Body}
+}
@@ -0,0 +1,155 @@
+// Package report handles the reports of the users about posts, replies
+// and other users, and the cases the moderators review with them.
+package report
+
+import (
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+)
+
+// DefaultThreshold is the number of distinct reports that hide a post
+// or reply until a moderator reviews it.
+const DefaultThreshold = 5
+
+// Targets of the reports.
+const (
+	TargetPost  = "post"
+	TargetReply = "reply"
+	TargetUser  = "user"
+)
+
+// Reasons of the reports.
+const (
+	ReasonSpam       = "spam"
+	ReasonHarassment = "harassment"
+	ReasonHate       = "hate"
+	ReasonSexual     = "sexual"
+	ReasonViolence   = "violence"
+	ReasonOffTopic   = "off_topic"
+	ReasonOther      = "other"
+)
+
+// Statuses of the cases. A case is open until a moderator acts on it.
+const (
+	StatusOpen      = "open"
+	StatusResolved  = "resolved"
+	StatusDismissed = "dismissed"
+)
+
+// Actions of the moderators. Dismiss closes the case without sanction
+// and shows the content again if it was hidden automatically, the rest
+// resolve it.
+const (
+	ActionHide    = "hide"
+	ActionWarn    = "warn"
+	ActionDelete  = "delete"
+	ActionDismiss = "dismiss"
+)
+
+// Errors of the reports and cases.
+var (
+	ErrAlreadyReported = apperror.New(apperror.ErrConflict, "already_reported",
+		"you have already reported it")
+	ErrOwnContent = apperror.New(apperror.ErrValidation, "own_content",
+		"you can't report yourself nor your own content")
+	ErrClosed = apperror.New(apperror.ErrConflict, "case_closed",
+		"the case is already closed")
+	ErrNotHideable = apperror.New(apperror.ErrValidation, "validation_failed",
+		"the request has invalid fields",
+		apperror.FieldError{Field: "action", Code: "invalid_option", Message: "only posts and replies can be hidden"})
+	ErrAssignee = apperror.New(apperror.ErrValidation, "validation_failed",
+		"the request has invalid fields",
+		apperror.FieldError{Field: "assignee_id", Code: "not_admin", Message: "must be an administrator"})
+)
+
+// Report of a user about a post, reply or user.
+type Report struct {
+	ID         uint      `json:"id,omitempty"`
+	CaseID     uint      `json:"case_id,omitempty"`
+	ReporterID uint      `json:"reporter_id,omitempty"`
+	Target     string    `json:"target,omitempty"`
+	TargetID   uint      `json:"target_id,omitempty"`
+	Reason     string    `json:"reason,omitempty"`
+	Details    string    `json:"details,omitempty"`
+	CreatedAt  time.Time `json:"created_at,omitempty"`
+}
+
+// Case groups the reports of a post, reply or user. UserID is the user
+// reported or the author of the content. Reports and Actions are only
+// returned with a single case.
+type Case struct {
+	ID           uint       `json:"id,omitempty"`
+	Target       string     `json:"target,omitempty"`
+	TargetID     uint       `json:"target_id,omitempty"`
+	UserID       uint       `json:"user_id,omitempty"`
+	Status       string     `json:"status,omitempty"`
+	AssigneeID   uint       `json:"assignee_id,omitempty"`
+	ReportsCount int        `json:"reports_count"`
+	Hidden       bool       `json:"hidden"`
+	CreatedAt    time.Time  `json:"created_at,omitempty"`
+	UpdatedAt    time.Time  `json:"updated_at,omitempty"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+	Reports      []Report   `json:"reports,omitempty"`
+	Actions      []Action   `json:"actions,omitempty"`
+}
+
+// Action of a moderator in a case. ModeratorID is 0 for the automatic
+// actions.
+type Action struct {
+	ID          uint      `json:"id,omitempty"`
+	CaseID      uint      `json:"case_id,omitempty"`
+	ModeratorID uint      `json:"moderator_id,omitempty"`
+	Action      string    `json:"action,omitempty"`
+	Note        string    `json:"note,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+}
+
+// Warning sent by a moderator to a user.
+type Warning struct {
+	ID          uint      `json:"id,omitempty"`
+	UserID      uint      `json:"user_id,omitempty"`
+	CaseID      uint      `json:"case_id,omitempty"`
+	ModeratorID uint      `json:"moderator_id,omitempty"`
+	Note        string    `json:"note,omitempty"`
+	CreatedAt   time.Time `json:"created_at,omitempty"`
+}
+
+// Filter of the cases of the moderation queue, the empty fields match
+// every case.
+type Filter struct {
+	Status     string
+	Target     string
+	AssigneeID uint
+}
+
+// Request is the body of the request to report a post, reply or user.
+type Request struct {
+	Target  string `json:"target" validate:"required,oneof=post reply user"`
+	ID      uint   `json:"id" validate:"required"`
+	Reason  string `json:"reason" validate:"required,oneof=spam harassment hate sexual violence off_topic other"`
+	Details string `json:"details" validate:"max=1000"`
+}
+
+// Report returns the report of the request sent by the user.
+func (req Request) Report(reporterID uint) Report {
+	return Report{
+		ReporterID: reporterID,
+		Target:     req.Target,
+		TargetID:   req.ID,
+		Reason:     req.Reason,
+		Details:    req.Details,
+	}
+}
+
+// AssignRequest is the body of the request to assign a case to an
+// administrator, 0 leaves it unassigned.
+type AssignRequest struct {
+	AssigneeID *uint `json:"assignee_id" validate:"required"`
+}
+
+// ActionRequest is the body of the request to act on a case.
+type ActionRequest struct {
+	Action string `json:"action" validate:"required,oneof=hide warn delete dismiss"`
+	Note   string `json:"note" validate:"max=1000"`
+}
@@ -0,0 +1,21 @@
+package report
+
+import (
+	"context"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+)
+
+// Repository handle the reports and the moderation cases.
+type Repository interface {
+	// Create adds the report to the open case of its target, opening
+	// one if there is none, and hides the post or reply once the case
+	// has threshold reports. A threshold of 0 never hides it.
+	Create(ctx context.Context, r *Report, threshold int) error
+	GetCases(ctx context.Context, f Filter, pg page.Request) ([]Case, string, error)
+	GetCase(ctx context.Context, id uint) (Case, error)
+	Assign(ctx context.Context, id, assigneeID uint) error
+	// Act records the action of the moderator and closes the case.
+	Act(ctx context.Context, id, moderatorID uint, action, note string) error
+	Warnings(ctx context.Context, userID uint, pg page.Request) ([]Warning, string, error)
+}
@@ -0,0 +1,71 @@
+package request
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/validate"
+)
+
+// MaxBodySize is the maximum size in bytes of a JSON request body.
+const MaxBodySize = 1 << 20
+
+// Decode reads the JSON body of the request into dst, which must be a
+// pointer to a request struct, and validates it. Unknown fields, bodies
+// bigger than MaxBodySize and trailing data are rejected.
+func Decode(w http.ResponseWriter, r *http.Request, dst interface{}) error {
+	r.Body = http.MaxBytesReader(w, r.Body, MaxBodySize)
+	defer r.Body.Close()
+
+	dec := json.NewDecoder(r.Body)
+	dec.DisallowUnknownFields()
+
+	if err := dec.Decode(dst); err != nil {
+		return decodeError(err)
+	}
+
+	if err := dec.Decode(&struct{}{}); err != io.EOF {
+		return apperror.New(apperror.ErrValidation, "invalid_json",
+			"the body must contain a single JSON object")
+	}
+
+	return validate.Struct(dst)
+}
+
+// decodeError converts the errors of the JSON decoder into domain errors.
+func decodeError(err error) error {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+
+	switch {
+	case errors.As(err, &typeErr):
+		return apperror.New(apperror.ErrValidation, "validation_failed",
+			"the request has invalid fields", apperror.FieldError{
+				Field:   typeErr.Field,
+				Code:    "invalid_type",
+				Message: "must be of type " + typeErr.Type.String(),
+			})
+	case strings.HasPrefix(err.Error(), "json: unknown field "):
+		field := strings.Trim(strings.TrimPrefix(err.Error(), "json: unknown field "), `"`)
+		return apperror.New(apperror.ErrValidation, "validation_failed",
+			"the request has invalid fields", apperror.FieldError{
+				Field:   field,
+				Code:    "unknown_field",
+				Message: "is not allowed",
+			})
+	case err.Error() == "http: request body too large":
+		return apperror.New(apperror.ErrTooLarge, "body_too_large",
+			fmt.Sprintf("the body must not be larger than %d bytes", MaxBodySize))
+	case errors.Is(err, io.EOF):
+		return apperror.New(apperror.ErrValidation, "invalid_json", "the body must not be empty")
+	case errors.As(err, &syntaxErr), errors.Is(err, io.ErrUnexpectedEOF):
+		return apperror.New(apperror.ErrValidation, "invalid_json", "the body is not valid JSON")
+	}
+
+	return apperror.New(apperror.ErrValidation, "invalid_json", "the body is not valid JSON")
+}
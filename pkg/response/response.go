@@ -2,14 +2,33 @@ package response
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
 	"net/http"
+	"strings"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
 )
 
 // ErrorMessage standarized error response.
+//
+// Deprecated: errors are sent as a Problem, ErrorMessage is only sent
+// to the clients that don't accept application/problem+json.
 type ErrorMessage struct {
 	Message string `json:"message"`
 }
 
+// Problem standarized error response in the format of RFC 7807.
+type Problem struct {
+	Type     string                `json:"type"`
+	Title    string                `json:"title"`
+	Status   int                   `json:"status"`
+	Detail   string                `json:"detail,omitempty"`
+	Instance string                `json:"instance,omitempty"`
+	Code     string                `json:"code"`
+	Errors   []apperror.FieldError `json:"errors,omitempty"`
+}
+
 // Map is a convenient way to create objects of unknown types.
 type Map map[string]interface{}
 
@@ -34,9 +53,105 @@ func JSON(w http.ResponseWriter, r *http.Request, statusCode int, data interface
 
 // HTTPError standarized error response in JSON format.
 func HTTPError(w http.ResponseWriter, r *http.Request, statusCode int, message string) error {
-	msg := ErrorMessage{
-		Message: message,
+	p := Problem{
+		Status: statusCode,
+		Detail: message,
+		Code:   codeOfStatus(statusCode),
+	}
+
+	return WriteProblem(w, r, p)
+}
+
+// Error writes the error with the status of its kind. Errors that are
+// not domain errors are logged and reported without their message.
+func Error(w http.ResponseWriter, r *http.Request, err error) error {
+	var appErr *apperror.Error
+	if !errors.As(err, &appErr) {
+		log.Printf("%s %s: %v", r.Method, r.URL.Path, err)
+		return HTTPError(w, r, http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError))
+	}
+
+	p := Problem{
+		Status: Status(err),
+		Detail: appErr.Message,
+		Code:   appErr.ErrorCode(),
+		Errors: appErr.Fields,
+	}
+
+	return WriteProblem(w, r, p)
+}
+
+// WriteProblem writes the problem as application/problem+json, or with
+// the legacy ErrorMessage format if the client only accepts
+// application/json.
+func WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) error {
+	w.Header().Add("Vary", "Accept")
+	if !acceptsProblem(r) {
+		return JSON(w, r, p.Status, ErrorMessage{Message: p.Detail})
+	}
+
+	if p.Type == "" {
+		p.Type = "about:blank"
+	}
+
+	if p.Title == "" {
+		p.Title = http.StatusText(p.Status)
+	}
+
+	if p.Instance == "" {
+		p.Instance = r.URL.Path
+	}
+
+	j, err := json.Marshal(p)
+	if err != nil {
+		return err
+	}
+
+	w.Header().Set("Content-Type", "application/problem+json; charset=utf-8")
+	w.WriteHeader(p.Status)
+	w.Write(j)
+	return nil
+}
+
+// Status returns the HTTP status of the kind of the error.
+func Status(err error) int {
+	switch {
+	case errors.Is(err, apperror.ErrNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, apperror.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, apperror.ErrValidation):
+		return http.StatusBadRequest
+	case errors.Is(err, apperror.ErrForbidden):
+		return http.StatusForbidden
+	case errors.Is(err, apperror.ErrTooLarge):
+		return http.StatusRequestEntityTooLarge
+	}
+
+	return http.StatusInternalServerError
+}
+
+// acceptsProblem reports whether the client accepts problem documents.
+// Clients asking for application/json without mentioning
+// application/problem+json get the legacy format.
+func acceptsProblem(r *http.Request) bool {
+	accept := r.Header.Get("Accept")
+	if strings.Contains(accept, "application/problem+json") {
+		return true
+	}
+
+	return !strings.Contains(accept, "application/json")
+}
+
+// codeOfStatus returns the error code of a status, like not_found.
+func codeOfStatus(statusCode int) string {
+	text := http.StatusText(statusCode)
+	if text == "" {
+		return "error"
 	}
 
-	return JSON(w, r, statusCode, msg)
+	text = strings.ToLower(text)
+	text = strings.ReplaceAll(text, "'", "")
+	text = strings.ReplaceAll(text, "-", "_")
+	return strings.ReplaceAll(text, " ", "_")
 }
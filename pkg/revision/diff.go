@@ -0,0 +1,156 @@
+package revision
+
+import "strings"
+
+// Operations of the lines of a diff.
+const (
+	OpEqual  = "equal"
+	OpInsert = "insert"
+	OpDelete = "delete"
+)
+
+// maxEdits is the maximum number of lines inserted and deleted that
+// Diff looks for, above it the whole text is replaced.
+const maxEdits = 1000
+
+// Line is a line of a diff: kept, inserted or deleted.
+type Line struct {
+	Op   string `json:"op"`
+	Text string `json:"text"`
+}
+
+// Diff returns the shortest list of lines inserted and deleted to
+// change the text a into b.
+func Diff(a, b string) []Line {
+	la, lb := lines(a), lines(b)
+
+	var prefix []Line
+	for len(la) > 0 && len(lb) > 0 && la[0] == lb[0] {
+		prefix = append(prefix, Line{Op: OpEqual, Text: la[0]})
+		la, lb = la[1:], lb[1:]
+	}
+
+	var suffix []Line
+	for len(la) > 0 && len(lb) > 0 && la[len(la)-1] == lb[len(lb)-1] {
+		suffix = append(suffix, Line{Op: OpEqual, Text: la[len(la)-1]})
+		la, lb = la[:len(la)-1], lb[:len(lb)-1]
+	}
+
+	middle, ok := myers(la, lb)
+	if !ok {
+		middle = middle[:0]
+		for _, l := range la {
+			middle = append(middle, Line{Op: OpDelete, Text: l})
+		}
+
+		for _, l := range lb {
+			middle = append(middle, Line{Op: OpInsert, Text: l})
+		}
+	}
+
+	diff := append(prefix, middle...)
+	for i := len(suffix) - 1; i >= 0; i-- {
+		diff = append(diff, suffix[i])
+	}
+
+	if diff == nil {
+		return []Line{}
+	}
+
+	return diff
+}
+
+// lines returns the lines of the text, none if it is empty.
+func lines(s string) []string {
+	if s == "" {
+		return nil
+	}
+
+	return strings.Split(strings.ReplaceAll(s, "\r\n", "\n"), "\n")
+}
+
+// myers returns the diff of the lines with the algorithm of Eugene W.
+// Myers, "An O(ND) Difference Algorithm and Its Variations". ok is
+// false if the lines need more than maxEdits changes.
+func myers(a, b []string) (diff []Line, ok bool) {
+	n, m := len(a), len(b)
+	limit := n + m
+	if limit > maxEdits {
+		limit = maxEdits
+	}
+
+	// v[offset+k] is the furthest x reached in the diagonal k = x - y.
+	// trace[d] keeps the diagonals -d-1 to d+1 of v before the step d,
+	// to walk the path back.
+	offset := limit + 1
+	v := make([]int, 2*limit+3)
+	var trace [][]int
+	for d := 0; d <= limit; d++ {
+		trace = append(trace, append([]int(nil), v[offset-d-1:offset+d+2]...))
+		for k := -d; k <= d; k += 2 {
+			var x int
+			if k == -d || (k != d && v[offset+k-1] < v[offset+k+1]) {
+				x = v[offset+k+1]
+			} else {
+				x = v[offset+k-1] + 1
+			}
+
+			y := x - k
+			for x < n && y < m && a[x] == b[y] {
+				x++
+				y++
+			}
+
+			v[offset+k] = x
+			if x >= n && y >= m {
+				return backtrack(a, b, trace, d), true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// backtrack returns the diff of the path found by myers in d steps.
+func backtrack(a, b []string, trace [][]int, d int) []Line {
+	var diff []Line
+	x, y := len(a), len(b)
+	for ; d > 0; d-- {
+		v := trace[d]
+		k := x - y
+
+		// v[k+d+1] is the diagonal k.
+		prevK := k - 1
+		if k == -d || (k != d && v[k+d] < v[k+d+2]) {
+			prevK = k + 1
+		}
+
+		prevX := v[prevK+d+1]
+		prevY := prevX - prevK
+		for x > prevX && y > prevY {
+			diff = append(diff, Line{Op: OpEqual, Text: a[x-1]})
+			x--
+			y--
+		}
+
+		if x == prevX {
+			diff = append(diff, Line{Op: OpInsert, Text: b[y-1]})
+		} else {
+			diff = append(diff, Line{Op: OpDelete, Text: a[x-1]})
+		}
+
+		x, y = prevX, prevY
+	}
+
+	for x > 0 && y > 0 {
+		diff = append(diff, Line{Op: OpEqual, Text: a[x-1]})
+		x--
+		y--
+	}
+
+	for i, j := 0, len(diff)-1; i < j; i, j = i+1, j-1 {
+		diff[i], diff[j] = diff[j], diff[i]
+	}
+
+	return diff
+}
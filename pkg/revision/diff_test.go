@@ -0,0 +1,158 @@
+package revision
+
+import (
+	"math/rand"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func eq(s string) Line  { return Line{Op: OpEqual, Text: s} }
+func ins(s string) Line { return Line{Op: OpInsert, Text: s} }
+func del(s string) Line { return Line{Op: OpDelete, Text: s} }
+
+func TestDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want []Line
+	}{
+		{name: "both empty", a: "", b: "", want: []Line{}},
+		{name: "equal", a: "a\nb", b: "a\nb", want: []Line{eq("a"), eq("b")}},
+		{name: "from empty", a: "", b: "a\nb", want: []Line{ins("a"), ins("b")}},
+		{name: "to empty", a: "a\nb", b: "", want: []Line{del("a"), del("b")}},
+		{name: "insert in the middle", a: "a\nc", b: "a\nb\nc", want: []Line{eq("a"), ins("b"), eq("c")}},
+		{name: "delete in the middle", a: "a\nb\nc", b: "a\nc", want: []Line{eq("a"), del("b"), eq("c")}},
+		{name: "replace", a: "a\nb\nc", b: "a\nx\nc", want: []Line{eq("a"), del("b"), ins("x"), eq("c")}},
+		{name: "insert at the start", a: "b\nc", b: "a\nb\nc", want: []Line{ins("a"), eq("b"), eq("c")}},
+		{name: "delete at the end", a: "a\nb\nc", b: "a\nb", want: []Line{eq("a"), eq("b"), del("c")}},
+		{name: "windows line endings", a: "a\r\nb", b: "a\nb", want: []Line{eq("a"), eq("b")}},
+		{name: "trailing newline", a: "a", b: "a\n", want: []Line{eq("a"), ins("")}},
+		{
+			name: "myers example",
+			a:    "A\nB\nC\nA\nB\nB\nA",
+			b:    "C\nB\nA\nB\nA\nC",
+			want: []Line{del("A"), del("B"), eq("C"), ins("B"), eq("A"), eq("B"), del("B"), eq("A"), ins("C")},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := Diff(tt.a, tt.b)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Diff(%q, %q)\n got: %v\nwant: %v", tt.a, tt.b, got, tt.want)
+			}
+		})
+	}
+}
+
+// apply returns the texts a and b of the diff.
+func apply(diff []Line) (string, string) {
+	var a, b []string
+	for _, l := range diff {
+		if l.Op != OpInsert {
+			a = append(a, l.Text)
+		}
+
+		if l.Op != OpDelete {
+			b = append(b, l.Text)
+		}
+	}
+
+	return strings.Join(a, "\n"), strings.Join(b, "\n")
+}
+
+// edits returns the number of lines inserted and deleted.
+func edits(diff []Line) int {
+	n := 0
+	for _, l := range diff {
+		if l.Op != OpEqual {
+			n++
+		}
+	}
+
+	return n
+}
+
+// minEdits returns the minimum number of lines inserted and deleted to
+// change a into b, with the longest common subsequence.
+func minEdits(a, b []string) int {
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			switch {
+			case a[i] == b[j]:
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			case lcs[i+1][j] > lcs[i][j+1]:
+				lcs[i][j] = lcs[i+1][j]
+			default:
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	return len(a) + len(b) - 2*lcs[0][0]
+}
+
+func TestDiffRandom(t *testing.T) {
+	rnd := rand.New(rand.NewSource(1))
+	text := func() []string {
+		l := make([]string, rnd.Intn(12))
+		for i := range l {
+			l[i] = string(rune('a' + rnd.Intn(4)))
+		}
+
+		return l
+	}
+
+	for i := 0; i < 1000; i++ {
+		la, lb := text(), text()
+		a, b := strings.Join(la, "\n"), strings.Join(lb, "\n")
+
+		diff := Diff(a, b)
+		gotA, gotB := apply(diff)
+		if gotA != a || gotB != b {
+			t.Fatalf("Diff(%q, %q) = %v applies to %q, %q", a, b, diff, gotA, gotB)
+		}
+
+		if got, want := edits(diff), minEdits(lines(a), lines(b)); got != want {
+			t.Fatalf("Diff(%q, %q) = %v has %d edits, want %d", a, b, diff, got, want)
+		}
+	}
+}
+
+func TestDiffMaxEdits(t *testing.T) {
+	var la, lb []string
+	for i := 0; i < maxEdits; i++ {
+		la = append(la, "a")
+		lb = append(lb, "b")
+	}
+
+	a := "same\n" + strings.Join(la, "\n")
+	b := "same\n" + strings.Join(lb, "\n")
+
+	diff := Diff(a, b)
+	if len(diff) != 1+2*maxEdits || diff[0] != eq("same") {
+		t.Fatalf("Diff() has %d lines, want %d", len(diff), 1+2*maxEdits)
+	}
+
+	for i, l := range diff[1:] {
+		want := OpDelete
+		if i >= maxEdits {
+			want = OpInsert
+		}
+
+		if l.Op != want {
+			t.Fatalf("line %d: op = %s, want %s", i+1, l.Op, want)
+		}
+	}
+
+	gotA, gotB := apply(diff)
+	if gotA != a || gotB != b {
+		t.Error("the diff doesn't apply to the texts")
+	}
+}
@@ -0,0 +1,15 @@
+package revision
+
+import (
+	"context"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+)
+
+// Repository handle the revisions of the posts and replies. They are
+// written by the repositories of the posts and replies when the
+// content changes.
+type Repository interface {
+	GetByTarget(ctx context.Context, target string, id uint, pg page.Request) ([]Revision, string, error)
+	GetOne(ctx context.Context, target string, id uint, number int) (Revision, error)
+}
@@ -0,0 +1,52 @@
+// Package revision keeps the history of the edits of the posts and
+// replies.
+package revision
+
+import "time"
+
+// Targets of the revisions.
+const (
+	TargetPost  = "post"
+	TargetReply = "reply"
+)
+
+// Revision is a version of the content of a post or reply. The
+// revisions are numbered from 1, the original content, and the last
+// one is the current content. UserID is the user who wrote it, the
+// author or an admin.
+type Revision struct {
+	Number    int       `json:"number"`
+	PostID    uint      `json:"post_id,omitempty"`
+	ReplyID   uint      `json:"reply_id,omitempty"`
+	UserID    uint      `json:"user_id,omitempty"`
+	Title     string    `json:"title,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	Body      string    `json:"body"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Changes are the differences between two revisions, field by field.
+// The title and the category are only set for the posts.
+type Changes struct {
+	From     int    `json:"from"`
+	To       int    `json:"to"`
+	Title    []Line `json:"title,omitempty"`
+	Category []Line `json:"category,omitempty"`
+	Body     []Line `json:"body"`
+}
+
+// Compare returns the changes from the revision a to b.
+func Compare(a, b Revision) Changes {
+	c := Changes{
+		From: a.Number,
+		To:   b.Number,
+		Body: Diff(a.Body, b.Body),
+	}
+
+	if a.PostID != 0 {
+		c.Title = Diff(a.Title, b.Title)
+		c.Category = Diff(a.Category, b.Category)
+	}
+
+	return c
+}
@@ -0,0 +1,20 @@
+package revocation
+
+import (
+	"context"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/sanction"
+)
+
+// Repository handle the revocation of access tokens.
+//
+// A single token is revoked by its jti until it expires, while every
+// token of a user is revoked at once by increasing its token version.
+type Repository interface {
+	Revoke(ctx context.Context, jti string, userID uint, expiresAt time.Time) error
+	RevokeAll(ctx context.Context, userID uint) error
+	// Check reports whether the token is revoked and returns the
+	// active sanction of the user, nil if there is none.
+	Check(ctx context.Context, jti string, userID uint, version int) (bool, *sanction.Sanction, error)
+}
@@ -0,0 +1,19 @@
+package sanction
+
+import (
+	"context"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+)
+
+// Repository handle the sanctions of the users.
+type Repository interface {
+	// Active returns the most restrictive active sanction of the user,
+	// or nil if the user has none.
+	Active(ctx context.Context, userID uint) (*Sanction, error)
+	GetActive(ctx context.Context, pg page.Request) ([]Sanction, string, error)
+	Create(ctx context.Context, s *Sanction) error
+	Lift(ctx context.Context, id, adminID uint) error
+	// Appeal adds the note of the user to the active sanction.
+	Appeal(ctx context.Context, userID uint, appeal string) (Sanction, error)
+}
@@ -0,0 +1,90 @@
+// Package sanction handles the suspensions and bans of the users.
+package sanction
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+)
+
+// Errors of the sanctions.
+var (
+	ErrExpiresAt = apperror.New(apperror.ErrValidation, "validation_failed",
+		"the request has invalid fields",
+		apperror.FieldError{Field: "expires_at", Code: "in_past", Message: "must be in the future"})
+	ErrSelf = apperror.New(apperror.ErrValidation, "self_sanction",
+		"you can't sanction yourself")
+	ErrAlreadyAppealed = apperror.New(apperror.ErrConflict, "already_appealed",
+		"the sanction has already been appealed")
+)
+
+// Sanction of a user. ExpiresAt is nil for the permanent ones, the
+// bans. The read-only sanctions let the user log in and read, but not
+// write.
+type Sanction struct {
+	ID         uint       `json:"id,omitempty"`
+	UserID     uint       `json:"user_id,omitempty"`
+	ReadOnly   bool       `json:"read_only"`
+	Reason     string     `json:"reason,omitempty"`
+	ExpiresAt  *time.Time `json:"expires_at"`
+	CreatedBy  uint       `json:"created_by,omitempty"`
+	CreatedAt  time.Time  `json:"created_at,omitempty"`
+	LiftedAt   *time.Time `json:"lifted_at,omitempty"`
+	LiftedBy   uint       `json:"lifted_by,omitempty"`
+	Appeal     string     `json:"appeal,omitempty"`
+	AppealedAt *time.Time `json:"appealed_at,omitempty"`
+}
+
+// Err returns the error of the requests the sanction doesn't allow,
+// every request or, for the read-only ones, the writes.
+func (s Sanction) Err() error {
+	code, state := "suspended", "suspended"
+	switch {
+	case s.ReadOnly:
+		code, state = "read_only", "in read-only mode"
+	case s.ExpiresAt == nil:
+		code, state = "banned", "banned"
+	}
+
+	until := ""
+	if s.ExpiresAt != nil {
+		until = " until " + s.ExpiresAt.UTC().Format(time.RFC3339)
+	}
+
+	return apperror.New(apperror.ErrForbidden, code,
+		fmt.Sprintf("your account is %s%s: %s", state, until, s.Reason))
+}
+
+// Request is the body of the request to sanction a user. Without
+// expires_at the sanction is permanent.
+type Request struct {
+	Reason    string     `json:"reason" validate:"required,max=1000"`
+	ExpiresAt *time.Time `json:"expires_at"`
+	ReadOnly  bool       `json:"read_only"`
+}
+
+// Sanction returns the sanction of the user of the request, created by
+// the admin.
+func (req Request) Sanction(userID, adminID uint) (Sanction, error) {
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		return Sanction{}, ErrExpiresAt
+	}
+
+	return Sanction{
+		UserID:    userID,
+		ReadOnly:  req.ReadOnly,
+		Reason:    req.Reason,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: adminID,
+	}, nil
+}
+
+// AppealRequest is the body of the request to appeal the active
+// sanction. The suspended users can't log in, so they send their
+// credentials.
+type AppealRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+	Appeal   string `json:"appeal" validate:"required,max=2000"`
+}
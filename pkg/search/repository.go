@@ -0,0 +1,12 @@
+package search
+
+import (
+	"context"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+)
+
+// Repository handle the full-text search over posts and replies.
+type Repository interface {
+	Search(ctx context.Context, q Query, pg page.Request) ([]Result, string, error)
+}
@@ -0,0 +1,36 @@
+package search
+
+import "time"
+
+// Types of the search results.
+const (
+	TypePost  = "post"
+	TypeReply = "reply"
+)
+
+// Query with the text to search and the filters of the results.
+// Zero values don't filter.
+type Query struct {
+	Text      string
+	Type      string
+	SubjectID uint
+	Year      int
+	UserID    uint
+	Category  string
+	From      time.Time
+	To        time.Time
+}
+
+// Result is a post or a reply matching a search.
+type Result struct {
+	Type      string    `json:"type"`
+	ID        uint      `json:"id"`
+	PostID    uint      `json:"post_id"`
+	SubjectID uint      `json:"subject_id"`
+	UserID    uint      `json:"user_id"`
+	Title     string    `json:"title,omitempty"`
+	Category  string    `json:"category,omitempty"`
+	Snippet   string    `json:"snippet"`
+	Rank      float32   `json:"rank"`
+	CreatedAt time.Time `json:"created_at"`
+}
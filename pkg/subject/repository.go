@@ -1,13 +1,20 @@
 package subject
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+)
 
 // Repository handle the CRUD operations with Subjects.
 type Repository interface {
-	GetAll(ctx context.Context) ([]Subject, error)
+	GetAll(ctx context.Context, pg page.Request) ([]Subject, string, error)
 	GetOne(ctx context.Context, id uint) (Subject, error)
-	GetByYear(ctx context.Context, year int) ([]Subject, error)
+	GetByYear(ctx context.Context, year int, pg page.Request) ([]Subject, string, error)
 	Create(ctx context.Context, subject *Subject) error
 	Update(ctx context.Context, id uint, subject Subject) error
 	Delete(ctx context.Context, id uint) error
+	Restore(ctx context.Context, id uint) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
@@ -0,0 +1,16 @@
+package subject
+
+// Request is the body of the request to create or update a subject.
+// Year is the year of the degree, from 1 to 4.
+type Request struct {
+	Name string `json:"name" validate:"required,max=150"`
+	Year int    `json:"year" validate:"required,min=1,max=4"`
+}
+
+// Subject returns the subject of the request.
+func (req Request) Subject() Subject {
+	return Subject{
+		Name: req.Name,
+		Year: req.Year,
+	}
+}
@@ -0,0 +1,11 @@
+package ticket
+
+import "context"
+
+// Repository handle the operations with the tickets of the streams.
+type Repository interface {
+	Create(ctx context.Context, t *Ticket) error
+	// Consume removes the ticket by hash and returns its access token.
+	// A ticket that doesn't exist or has expired is not found.
+	Consume(ctx context.Context, hash string) (string, error)
+}
@@ -0,0 +1,49 @@
+// Package ticket handles the single-use tickets of the streams.
+package ticket
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"time"
+)
+
+// TTL is the lifetime of a ticket, enough to open the stream.
+const TTL = 30 * time.Second
+
+// Ticket opens one WebSocket or Server-Sent Events stream with the
+// access token it was issued with. Browsers can't set the Authorization
+// header in these requests, and the ticket is sent in the query
+// instead of the token.
+type Ticket struct {
+	Hash      string    `json:"-"`
+	UserID    uint      `json:"-"`
+	Token     string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// New returns a new ticket of the access token of the user and its
+// plain value, which is only known by the client.
+func New(userID uint, token string) (Ticket, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return Ticket{}, "", err
+	}
+
+	plain := base64.RawURLEncoding.EncodeToString(b)
+	t := Ticket{
+		Hash:      Hash(plain),
+		UserID:    userID,
+		Token:     token,
+		ExpiresAt: time.Now().Add(TTL),
+	}
+
+	return t, plain, nil
+}
+
+// Hash returns the value stored in the database for a plain ticket.
+func Hash(plain string) string {
+	sum := sha256.Sum256([]byte(plain))
+	return hex.EncodeToString(sum[:])
+}
@@ -1,14 +1,23 @@
 package user
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/page"
+)
 
 // Repository handle the CRUD operations with Users.
 type Repository interface {
-	GetAll(ctx context.Context) ([]User, error)
+	GetAll(ctx context.Context, pg page.Request) ([]User, string, error)
 	GetOne(ctx context.Context, id uint) (User, error)
 	GetByUsername(ctx context.Context, username string) (User, error)
 	GetByYear(ctx context.Context, year int) (User, error)
 	Create(ctx context.Context, user *User) error
 	Update(ctx context.Context, id uint, user User) error
+	SetAvatar(ctx context.Context, id uint, avatar string) error
+	SetAdmin(ctx context.Context, id uint, admin bool) error
 	Delete(ctx context.Context, id uint) error
+	Restore(ctx context.Context, id uint) error
+	Purge(ctx context.Context, before time.Time) (int64, error)
 }
@@ -0,0 +1,51 @@
+package user
+
+// CreateRequest is the body of the request to sign up.
+// Year is the year of the degree, from 1 to 4.
+type CreateRequest struct {
+	Username string `json:"username" validate:"required,min=3,max=150"`
+	Email    string `json:"email" validate:"required,email,max=150"`
+	Password string `json:"password" validate:"required,password"`
+	Year     int    `json:"year" validate:"required,min=1,max=4"`
+
+	// Deprecated: Picture is ignored, the avatar is uploaded to
+	// /users/{id}/avatar.
+	Picture string `json:"picture" validate:"max=256"`
+
+	// Admin is ignored, admins are only promoted through the admin routes.
+	Admin bool `json:"admin"`
+}
+
+// User returns the user of the request.
+func (req CreateRequest) User() User {
+	return User{
+		Username: req.Username,
+		Email:    req.Email,
+		Password: req.Password,
+		Year:     req.Year,
+	}
+}
+
+// UpdateRequest is the body of the request to update a user.
+type UpdateRequest struct {
+	Email string `json:"email" validate:"required,email,max=150"`
+	Year  int    `json:"year" validate:"required,min=1,max=4"`
+
+	// Deprecated: Picture is ignored, the avatar is uploaded to
+	// /users/{id}/avatar.
+	Picture string `json:"picture" validate:"max=256"`
+}
+
+// User returns the user of the request.
+func (req UpdateRequest) User() User {
+	return User{
+		Email: req.Email,
+		Year:  req.Year,
+	}
+}
+
+// LoginRequest is the body of the request to log in.
+type LoginRequest struct {
+	Username string `json:"username" validate:"required"`
+	Password string `json:"password" validate:"required"`
+}
@@ -1,6 +1,8 @@
 package user
 
 import (
+	"fmt"
+	"path"
 	"time"
 
 	"golang.org/x/crypto/bcrypt"
@@ -16,8 +18,26 @@ type User struct {
 	Admin		 bool      `json:"admin,omitempty"`
 	Password     string    `json:"password,omitempty"`
 	PasswordHash string    `json:"-"`
+	TokenVersion int       `json:"-"`
 	CreatedAt    time.Time `json:"created_at,omitempty"`
 	UpdatedAt    time.Time `json:"updated_at,omitempty"`
+
+	// Avatar is the prefix of the files of the picture uploaded by the
+	// user in the storage, empty if the user has none.
+	Avatar string `json:"-"`
+}
+
+// pictureURL is the URL of the pictures, served by the API from the
+// storage or, for the users without avatar, as an identicon.
+const pictureURL = "/api/v1/users/%d/avatar"
+
+// SetPicture sets the URL of the picture of the user. The URL changes
+// with the avatar, so the clients can cache the pictures.
+func (u *User) SetPicture() {
+	u.Picture = fmt.Sprintf(pictureURL, u.ID)
+	if u.Avatar != "" {
+		u.Picture += "?v=" + path.Base(u.Avatar)
+	}
 }
 
 // HashPassword generates a hash of the password and places the result in PasswordHash.
@@ -0,0 +1,207 @@
+// Package validate checks the fields of a struct with the rules
+// declared in their validate tags, like:
+//
+//	Title string `json:"title" validate:"required,max=150"`
+//
+// The available rules are required, min=n and max=n (length of the
+// strings or value of the numbers), email, password and oneof=a b c.
+package validate
+
+import (
+	"fmt"
+	"net/mail"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+)
+
+// Password length limits. bcrypt ignores the bytes after the 72th.
+const (
+	MinPasswordLength = 8
+	MaxPasswordLength = 72
+)
+
+// Struct validates the fields of the struct pointed by v and returns
+// an error of kind apperror.ErrValidation with every violation.
+func Struct(v interface{}) error {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []apperror.FieldError
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		sf := rt.Field(i)
+		tag, ok := sf.Tag.Lookup("validate")
+		if !ok || sf.PkgPath != "" {
+			continue
+		}
+
+		name := fieldName(sf)
+		for _, rule := range strings.Split(tag, ",") {
+			if fe, ok := check(name, rv.Field(i), rule); !ok {
+				fields = append(fields, fe)
+				break
+			}
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	return apperror.New(apperror.ErrValidation, "validation_failed",
+		"the request has invalid fields", fields...)
+}
+
+// check returns the violation of the rule, if the value breaks it.
+func check(name string, v reflect.Value, rule string) (apperror.FieldError, bool) {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return violation(name, "required", "is required"), rule != "required"
+		}
+
+		// a pointer is only required to be set, false and 0 are valid.
+		if rule == "required" {
+			return apperror.FieldError{}, true
+		}
+
+		v = v.Elem()
+	}
+
+	key, arg := rule, ""
+	if i := strings.Index(rule, "="); i >= 0 {
+		key, arg = rule[:i], rule[i+1:]
+	}
+
+	switch key {
+	case "required":
+		if isZero(v) {
+			return violation(name, "required", "is required"), false
+		}
+	case "min", "max":
+		n, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			panic(fmt.Sprintf("validate: invalid rule %q of %s", rule, name))
+		}
+
+		size, isString := measure(v)
+		if key == "min" && size < n {
+			if isString {
+				return violation(name, "too_short", fmt.Sprintf("must have at least %s characters", arg)), false
+			}
+
+			return violation(name, "too_small", fmt.Sprintf("must be at least %s", arg)), false
+		}
+
+		if key == "max" && size > n {
+			if isString {
+				return violation(name, "too_long", fmt.Sprintf("must have at most %s characters", arg)), false
+			}
+
+			return violation(name, "too_large", fmt.Sprintf("must be at most %s", arg)), false
+		}
+	case "email":
+		s := v.String()
+		if s == "" {
+			break
+		}
+
+		addr, err := mail.ParseAddress(s)
+		if err != nil || addr.Address != s {
+			return violation(name, "invalid_email", "must be a valid email address"), false
+		}
+	case "password":
+		if msg, ok := Password(v.String()); !ok {
+			return violation(name, "weak_password", msg), false
+		}
+	case "oneof":
+		s := fmt.Sprint(v.Interface())
+		if s == "" {
+			break
+		}
+
+		for _, option := range strings.Fields(arg) {
+			if s == option {
+				return apperror.FieldError{}, true
+			}
+		}
+
+		return violation(name, "invalid_option", "must be one of: "+strings.Join(strings.Fields(arg), ", ")), false
+	default:
+		panic(fmt.Sprintf("validate: unknown rule %q of %s", rule, name))
+	}
+
+	return apperror.FieldError{}, true
+}
+
+// Password reports whether the password is strong enough and, if it
+// isn't, why.
+func Password(password string) (string, bool) {
+	if utf8.RuneCountInString(password) < MinPasswordLength {
+		return fmt.Sprintf("must have at least %d characters", MinPasswordLength), false
+	}
+
+	if len(password) > MaxPasswordLength {
+		return fmt.Sprintf("must have at most %d bytes", MaxPasswordLength), false
+	}
+
+	var letter, digit bool
+	for _, r := range password {
+		letter = letter || unicode.IsLetter(r)
+		digit = digit || unicode.IsDigit(r)
+	}
+
+	if !letter || !digit {
+		return "must contain letters and digits", false
+	}
+
+	return "", true
+}
+
+// measure returns the length of the strings and the value of the numbers.
+func measure(v reflect.Value) (float64, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false
+	case reflect.Slice, reflect.Map:
+		return float64(v.Len()), false
+	}
+
+	return 0, false
+}
+
+// isZero reports whether the value is empty. Strings with only spaces
+// are empty too.
+func isZero(v reflect.Value) bool {
+	if v.Kind() == reflect.String {
+		return strings.TrimSpace(v.String()) == ""
+	}
+
+	return v.IsZero()
+}
+
+// fieldName returns the name of the field in the JSON document.
+func fieldName(sf reflect.StructField) string {
+	name := strings.Split(sf.Tag.Get("json"), ",")[0]
+	if name == "" || name == "-" {
+		return sf.Name
+	}
+
+	return name
+}
+
+func violation(field, code, message string) apperror.FieldError {
+	return apperror.FieldError{Field: field, Code: code, Message: message}
+}
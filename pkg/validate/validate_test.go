@@ -0,0 +1,212 @@
+package validate
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/orlmonteverde/go-postgres-microblog/pkg/apperror"
+)
+
+type testRequest struct {
+	Title    string  `json:"title" validate:"required,max=10"`
+	Body     string  `json:"body,omitempty" validate:"min=3"`
+	Email    string  `json:"email" validate:"email"`
+	Password string  `json:"password" validate:"password"`
+	Order    string  `json:"order" validate:"oneof=new top"`
+	Year     int     `json:"year" validate:"min=1,max=5"`
+	Solved   *bool   `json:"solved" validate:"required"`
+	Rank     *int    `json:"rank" validate:"max=3"`
+	Tags     []int   `validate:"max=2"`
+	Ignored  string  `json:"-" validate:"required"`
+	Score    float64 `json:"score"`
+	private  string  `validate:"required"`
+}
+
+func validRequest() testRequest {
+	solved := false
+	return testRequest{
+		Title:    "título",
+		Body:     "abc",
+		Email:    "ana@example.com",
+		Password: "secret123",
+		Order:    "top",
+		Year:     1,
+		Solved:   &solved,
+		Ignored:  "x",
+	}
+}
+
+func TestStruct(t *testing.T) {
+	three, four := 3, 4
+
+	tests := []struct {
+		name   string
+		modify func(r *testRequest)
+		want   []apperror.FieldError
+	}{
+		{name: "valid", modify: func(r *testRequest) {}},
+		{
+			name:   "required",
+			modify: func(r *testRequest) { r.Title = "" },
+			want:   []apperror.FieldError{{Field: "title", Code: "required", Message: "is required"}},
+		},
+		{
+			name:   "required with only spaces",
+			modify: func(r *testRequest) { r.Title = "  \t" },
+			want:   []apperror.FieldError{{Field: "title", Code: "required", Message: "is required"}},
+		},
+		{
+			name:   "max length counts characters",
+			modify: func(r *testRequest) { r.Title = strings.Repeat("ñ", 10) },
+		},
+		{
+			name:   "too long",
+			modify: func(r *testRequest) { r.Title = strings.Repeat("a", 11) },
+			want:   []apperror.FieldError{{Field: "title", Code: "too_long", Message: "must have at most 10 characters"}},
+		},
+		{
+			name:   "too short",
+			modify: func(r *testRequest) { r.Body = "ab" },
+			want:   []apperror.FieldError{{Field: "body", Code: "too_short", Message: "must have at least 3 characters"}},
+		},
+		{
+			name:   "invalid email",
+			modify: func(r *testRequest) { r.Email = "Ana <ana@example.com>" },
+			want:   []apperror.FieldError{{Field: "email", Code: "invalid_email", Message: "must be a valid email address"}},
+		},
+		{
+			name:   "empty email",
+			modify: func(r *testRequest) { r.Email = "" },
+		},
+		{
+			name:   "weak password",
+			modify: func(r *testRequest) { r.Password = "password" },
+			want:   []apperror.FieldError{{Field: "password", Code: "weak_password", Message: "must contain letters and digits"}},
+		},
+		{
+			name:   "invalid option",
+			modify: func(r *testRequest) { r.Order = "old" },
+			want:   []apperror.FieldError{{Field: "order", Code: "invalid_option", Message: "must be one of: new, top"}},
+		},
+		{
+			name:   "empty option",
+			modify: func(r *testRequest) { r.Order = "" },
+		},
+		{
+			name:   "too small",
+			modify: func(r *testRequest) { r.Year = 0 },
+			want:   []apperror.FieldError{{Field: "year", Code: "too_small", Message: "must be at least 1"}},
+		},
+		{
+			name:   "too large",
+			modify: func(r *testRequest) { r.Year = 6 },
+			want:   []apperror.FieldError{{Field: "year", Code: "too_large", Message: "must be at most 5"}},
+		},
+		{
+			name:   "required pointer",
+			modify: func(r *testRequest) { r.Solved = nil },
+			want:   []apperror.FieldError{{Field: "solved", Code: "required", Message: "is required"}},
+		},
+		{
+			name:   "optional pointer",
+			modify: func(r *testRequest) { r.Rank = &three },
+		},
+		{
+			name:   "optional pointer too large",
+			modify: func(r *testRequest) { r.Rank = &four },
+			want:   []apperror.FieldError{{Field: "rank", Code: "too_large", Message: "must be at most 3"}},
+		},
+		{
+			name:   "slice length",
+			modify: func(r *testRequest) { r.Tags = []int{1, 2, 3} },
+			want:   []apperror.FieldError{{Field: "Tags", Code: "too_large", Message: "must be at most 2"}},
+		},
+		{
+			name:   "field without JSON name",
+			modify: func(r *testRequest) { r.Ignored = "" },
+			want:   []apperror.FieldError{{Field: "Ignored", Code: "required", Message: "is required"}},
+		},
+		{
+			name: "every violation",
+			modify: func(r *testRequest) {
+				r.Title = ""
+				r.Year = 0
+			},
+			want: []apperror.FieldError{
+				{Field: "title", Code: "required", Message: "is required"},
+				{Field: "year", Code: "too_small", Message: "must be at least 1"},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := validRequest()
+			tt.modify(&r)
+
+			err := Struct(&r)
+			if tt.want == nil {
+				if err != nil {
+					t.Fatalf("Struct() = %v, want nil", err)
+				}
+
+				return
+			}
+
+			var appErr *apperror.Error
+			if !errors.As(err, &appErr) || !errors.Is(err, apperror.ErrValidation) {
+				t.Fatalf("Struct() = %v, want a validation error", err)
+			}
+
+			if !reflect.DeepEqual(appErr.Fields, tt.want) {
+				t.Errorf("Fields = %+v, want %+v", appErr.Fields, tt.want)
+			}
+		})
+	}
+}
+
+func TestStructNotStruct(t *testing.T) {
+	if err := Struct("x"); err != nil {
+		t.Errorf("Struct(string) = %v, want nil", err)
+	}
+}
+
+func TestPassword(t *testing.T) {
+	tests := []struct {
+		password string
+		want     string
+	}{
+		{password: "secret123", want: ""},
+		{password: "contraseña1", want: ""},
+		{password: "abc123", want: "must have at least 8 characters"},
+		{password: "ñññññññ1", want: ""},
+		{password: "password", want: "must contain letters and digits"},
+		{password: "12345678", want: "must contain letters and digits"},
+		{password: strings.Repeat("a1", 36), want: ""},
+		{password: strings.Repeat("a1", 36) + "b", want: "must have at most 72 bytes"},
+		{password: strings.Repeat("ñ", 36) + "1", want: "must have at most 72 bytes"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.password, func(t *testing.T) {
+			msg, ok := Password(tt.password)
+			if msg != tt.want || ok != (tt.want == "") {
+				t.Errorf("Password(%q) = %q, %v, want %q", tt.password, msg, ok, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnknownRule(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Error("Struct() with an unknown rule: want a panic")
+		}
+	}()
+
+	Struct(&struct {
+		Name string `validate:"unknown"`
+	}{})
+}
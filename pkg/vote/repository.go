@@ -0,0 +1,10 @@
+package vote
+
+import "context"
+
+// Repository handle the votes of the users to posts and replies.
+type Repository interface {
+	Vote(ctx context.Context, target string, id, userID uint, value int) (Totals, error)
+	Unvote(ctx context.Context, target string, id, userID uint) (Totals, error)
+	Mine(ctx context.Context, target string, userID uint, ids []uint) (map[uint]int, error)
+}
@@ -0,0 +1,22 @@
+package vote
+
+// Targets of the votes.
+const (
+	TargetPost  = "post"
+	TargetReply = "reply"
+)
+
+// Totals of the votes of a post or reply. MyVote is the vote of the
+// authenticated user: 1, -1 or 0 if the user hasn't voted.
+type Totals struct {
+	Upvotes   int `json:"upvotes"`
+	Downvotes int `json:"downvotes"`
+	Score     int `json:"score"`
+	MyVote    int `json:"my_vote"`
+}
+
+// Request is the body of the request to vote, 1 to upvote and -1 to
+// downvote.
+type Request struct {
+	Value int `json:"value" validate:"required,oneof=1 -1"`
+}